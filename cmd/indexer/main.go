@@ -0,0 +1,66 @@
+// Command indexer rebuilds lottery state from the contract's ordered log lines.
+//
+// Usage:
+//
+//	indexer [-listen addr] [logfile]
+//
+// Lines are read from logfile, or from stdin when no file is given. Without -listen the
+// reconstructed state is printed as JSON and the exit status is 1 if any accounting
+// violation was found. With -listen the state is served over a read-only HTTP API
+// while lines keep streaming in, e.g. `tail -f contract.log | indexer -listen :8080`.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"okinoko_lottery/indexer"
+)
+
+func main() {
+	listen := flag.String("listen", "", "serve the HTTP read API on this address instead of printing JSON")
+	flag.Parse()
+
+	in := io.Reader(os.Stdin)
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ix := indexer.New()
+
+	if *listen != "" {
+		go func() {
+			if err := ix.Consume(in); err != nil {
+				log.Printf("reading log: %v", err)
+			}
+		}()
+		log.Printf("serving lottery index on %s", *listen)
+		log.Fatal(http.ListenAndServe(*listen, ix.Handler()))
+	}
+
+	if err := ix.Consume(in); err != nil {
+		log.Fatal(err)
+	}
+	snapshot := ix.Snapshot()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snapshot); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(snapshot.Issues) > 0 {
+		fmt.Fprintf(os.Stderr, "%d issue(s) found\n", len(snapshot.Issues))
+		os.Exit(1)
+	}
+}
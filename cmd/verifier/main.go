@@ -0,0 +1,229 @@
+// Command verifier replays a lottery draw offline from public data.
+//
+// From the contract's event log (lc, lj and, once executed, lp/ld/le events):
+//
+//	verifier -log contract.log -lottery 1 [-seed 12345]
+//
+// The seed defaults to the one in the lottery's le event. Executed lotteries are compared
+// with the on-chain payouts and the exit status is 1 on any mismatch.
+//
+// From a participant list, one "address tickets" pair per line in first-join order:
+//
+//	verifier -participants list.txt -seed 12345 -shares 50,30,20 [-ticket 1.000 -burn 10 -donation 0 -fee 0]
+//
+// The payout split is printed when -ticket is given.
+//
+// A ticket proof returned by get_ticket_proof, against the tickets_root of the lottery's le event:
+//
+//	verifier -proof 'index:2|address:hive:bob|tickets:3-7|...' -root <tickets_root>
+//
+// The exit status is 1 if the proof does not lead to the root.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/indexer"
+	"okinoko_lottery/verifier"
+)
+
+func main() {
+	logFile := flag.String("log", "", "contract event log, - for stdin")
+	lotteryID := flag.Uint64("lottery", 0, "lottery ID to replay from the event log")
+	listFile := flag.String("participants", "", "participant list with one \"address tickets\" pair per line, - for stdin")
+	seedFlag := flag.String("seed", "", "draw seed (defaults to the le event's seed)")
+	sharesFlag := flag.String("shares", "100", "winner shares in percent, comma separated (participant list only)")
+	ticketFlag := flag.String("ticket", "", "ticket price (participant list only)")
+	burnFlag := flag.String("burn", "0", "burn percent (participant list only)")
+	donationFlag := flag.String("donation", "0", "donation percent, comma separated per recipient (participant list only)")
+	feeFlag := flag.String("fee", "0", "protocol fee percent from the lc event (participant list only)")
+	creatorFeeFlag := flag.String("creator-fee", "0", "creator fee percent from the lc event (participant list only)")
+	proofFlag := flag.String("proof", "", "get_ticket_proof result to check against -root")
+	rootFlag := flag.String("root", "", "tickets_root from the le event (proof only)")
+	flag.Parse()
+
+	if *proofFlag != "" {
+		if !checkProof(*proofFlag, *rootFlag) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var res *verifier.Result
+	switch {
+	case *logFile != "":
+		res = replayLog(*logFile, *lotteryID, *seedFlag)
+	case *listFile != "":
+		res = replayList(*listFile, *seedFlag, *sharesFlag, *ticketFlag, *burnFlag, *donationFlag, *feeFlag, *creatorFeeFlag)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	printResult(res)
+	if len(res.Mismatches) > 0 {
+		os.Exit(1)
+	}
+}
+
+// replayLog rebuilds the lottery with the indexer and replays it
+func replayLog(path string, id uint64, seedStr string) *verifier.Result {
+	ix := indexer.New()
+	in := open(path)
+	defer in.Close()
+	if err := ix.Consume(in); err != nil {
+		log.Fatal(err)
+	}
+
+	l := ix.Lottery(id)
+	if l == nil {
+		log.Fatalf("lottery %d not found in log", id)
+	}
+
+	seed := uint64(0)
+	switch {
+	case seedStr != "":
+		seed = parseSeed(seedStr)
+	case l.Execution != nil:
+		seed = l.Execution.Seed
+	default:
+		log.Fatal("lottery is not executed yet, pass -seed")
+	}
+	return verifier.Replay(l, seed)
+}
+
+// replayList draws from a plain participant list
+func replayList(path, seedStr, sharesStr, ticketStr, burnStr, donationStr, feeStr, creatorFeeStr string) *verifier.Result {
+	if seedStr == "" {
+		log.Fatal("-seed is required with -participants")
+	}
+	seed := parseSeed(seedStr)
+
+	var shares []events.Percent
+	for _, s := range strings.Split(sharesStr, ",") {
+		shares = append(shares, parsePercent(s))
+	}
+
+	in := open(path)
+	defer in.Close()
+	var participants []verifier.Participant
+	tickets := uint64(0)
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			log.Fatalf("line %d: expected \"address tickets\"", line)
+		}
+		count, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			log.Fatalf("line %d: invalid ticket count: %v", line, err)
+		}
+		participants = append(participants, verifier.Participant{Address: fields[0], Tickets: count})
+		tickets += count
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	winners := verifier.SelectWinners(participants, len(shares), seed)
+	res := &verifier.Result{Seed: seed, Tickets: tickets, Winners: winners}
+	if ticketStr != "" {
+		price, err := events.ParseAmount(ticketStr)
+		if err != nil {
+			log.Fatalf("invalid ticket price: %v", err)
+		}
+		pool := events.Amount(tickets) * price
+		var donations []events.Percent
+		for _, s := range strings.Split(donationStr, ",") {
+			donations = append(donations, parsePercent(s))
+		}
+		res.Split = verifier.ComputeSplit(pool, parsePercent(burnStr), donations, parsePercent(feeStr), parsePercent(creatorFeeStr), shares, len(winners))
+	}
+	return res
+}
+
+// checkProof verifies a ticket proof against the root the lottery emitted
+func checkProof(result, root string) bool {
+	if root == "" {
+		log.Fatal("-root is required with -proof, take it from the le event rather than the proof")
+	}
+	proof, err := verifier.ParseTicketProof(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if proof.Root != root {
+		fmt.Printf("proof root %s differs from %s\n", proof.Root, root)
+	}
+	if !proof.Verify(root) {
+		fmt.Println("proof invalid")
+		return false
+	}
+	fmt.Printf("proof valid: %s held tickets %d-%d as participant %d of %d\n", proof.Address, proof.Start, proof.End, proof.Index, proof.Participants)
+	return true
+}
+
+func printResult(res *verifier.Result) {
+	if res.LotteryID != 0 {
+		fmt.Printf("lottery: %d\n", res.LotteryID)
+	}
+	fmt.Printf("seed: %d\n", res.Seed)
+	fmt.Printf("tickets: %d\n", res.Tickets)
+
+	hasSplit := res.Split.Pool > 0
+	for i, w := range res.Winners {
+		fmt.Printf("winner %d: %s ticket:%d", w.Position, w.Address, w.Ticket)
+		if hasSplit && i < len(res.Split.Payouts) {
+			fmt.Printf(" amount:%s", res.Split.Payouts[i])
+		}
+		fmt.Println()
+	}
+	if hasSplit {
+		fmt.Printf("pool: %s burned: %s donated: %s fee: %s creator fee: %s undistributed: %s\n",
+			res.Split.Pool, res.Split.Burned, res.Split.Donated, res.Split.Fee, res.Split.Creator, res.Split.Undistributed)
+	}
+
+	if len(res.Mismatches) > 0 {
+		fmt.Println("verification failed:")
+		for _, m := range res.Mismatches {
+			fmt.Println("  " + m)
+		}
+	}
+}
+
+func open(path string) io.ReadCloser {
+	if path == "-" {
+		return io.NopCloser(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
+
+func parseSeed(s string) uint64 {
+	seed, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		log.Fatalf("invalid seed: %v", err)
+	}
+	return seed
+}
+
+func parsePercent(s string) events.Percent {
+	p, err := events.ParsePercent(strings.TrimSpace(s))
+	if err != nil {
+		log.Fatalf("invalid percent %q: %v", s, err)
+	}
+	return p
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"okinoko_lottery/sdk"
+)
+
+// archiveGracePeriod is how long the participant entries of an executed lottery stay in state
+// before anyone may archive them, so indexers and verify_lottery callers can still read them
+const archiveGracePeriod = 30 * 24 * 60 * 60
+
+// archiveBatchSize caps how many participants a single archive_lottery call prunes so it stays within the gas limit
+const archiveBatchSize = 250
+
+// participantsHashStep extends the participant hash chain by one entry:
+// SHA-256(previous hash || address || " " || tickets || "\n"), starting from an empty previous hash.
+// A chain instead of one hash over the whole list lets archiving resume across calls.
+func participantsHashStep(prev string, p *ParticipantEntry) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write([]byte(p.Address + " " + strconv.FormatUint(p.Tickets, 10) + "\n"))
+	return string(h.Sum(nil))
+}
+
+//export archive_lottery
+func archive_lottery(payload *string) *string {
+	requireCurrentSchema()
+	requireNotPaused("archive_lottery")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "archive_lottery payload missing")
+	args := parseArchiveLottery(payloadStr, format)
+
+	meta := loadLotteryMetadata(args.LotteryID)
+	if meta == nil {
+		sdk.Abort("lottery not found")
+	}
+	if meta.State != LotteryStateExecuted {
+		sdk.Abort("lottery not executed yet")
+	}
+	if nowUnix() < meta.ExecutedAt+archiveGracePeriod {
+		sdk.Abort("archive grace period has not passed")
+	}
+	stats := loadLotteryPoolStats(args.LotteryID)
+	if meta.ArchivedCount >= stats.ParticipantCount {
+		sdk.Abort("lottery already archived")
+	}
+
+	// Prune in participant index order, folding every entry into the hash before it is deleted.
+	// Pool stats stay, so the participant and ticket counts remain readable.
+	end := min(meta.ArchivedCount+archiveBatchSize, stats.ParticipantCount)
+	for i := meta.ArchivedCount + 1; i <= end; i++ {
+		entry := loadParticipantEntry(args.LotteryID, i)
+		if entry == nil {
+			sdk.Abort("participant entry missing")
+		}
+		meta.ParticipantsHash = participantsHashStep(meta.ParticipantsHash, entry)
+		host.StateDelete(getParticipantIndexKey(args.LotteryID, i))
+		host.StateDelete(getParticipantLookupKey(args.LotteryID, entry.Address))
+		host.StateDelete(getLotteryPayoutPreferenceKey(args.LotteryID, entry.Address))
+	}
+	meta.ArchivedCount = end
+	saveLotteryMetadata(meta)
+
+	if end < stats.ParticipantCount {
+		ret := "archived " + strconv.FormatUint(end, 10) + " of " + strconv.FormatUint(stats.ParticipantCount, 10) + " participant(s)"
+		return &ret
+	}
+	hash := hex.EncodeToString([]byte(meta.ParticipantsHash))
+	emitLotteryArchived(args.LotteryID, stats.ParticipantCount, hash)
+	ret := "lottery archived with participants hash " + hash
+	return &ret
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/verifier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveTime is the first timestamp at which a lottery executed at fakeFuture may be archived
+const archiveTime = "2025-10-05T00:00:00"
+
+// TestArchiveLottery tests pruning in batches, the hash commitment and what stays readable
+func TestArchiveLottery(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Crowd|24|10|60,40|0.001", "hive:creator")
+	participants := make([]verifier.Participant, 0, archiveBatchSize+10)
+	for i := range archiveBatchSize + 10 {
+		who := "hive:user" + strconv.Itoa(i)
+		tickets := uint64(i%3 + 1)
+		a.f.fund(who, 10)
+		a.mustCall(t, join_lottery, "1", who, transferAllow("0.00"+strconv.FormatUint(tickets, 10)))
+		participants = append(participants, verifier.Participant{Address: who, Tickets: tickets})
+	}
+	a.f.at(fakeFuture)
+	assert.Equal(t, "lottery not executed yet", a.call(t, archive_lottery, "1", "hive:anyone").Err)
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+
+	a.f.at("2025-10-04T23:59:59")
+	assert.Equal(t, "archive grace period has not passed", a.call(t, archive_lottery, "1", "hive:anyone").Err)
+
+	a.f.at(archiveTime)
+	res := a.mustCall(t, archive_lottery, "1", "hive:anyone")
+	assert.Equal(t, "archived 250 of 260 participant(s)", res.Ret)
+	assert.Empty(t, res.Logs)
+	assert.NotContains(t, a.f.state, "lpi:1:250")
+	assert.NotContains(t, a.f.state, "lpu:1:hive:user249")
+	assert.Contains(t, a.f.state, "lpi:1:251")
+
+	res = a.mustCall(t, archive_lottery, `{"lottery_id":1}`, "hive:someone")
+	hash := verifier.ParticipantsHash(participants)
+	assert.Equal(t, "lottery archived with participants hash "+hash, res.Ret)
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 1)
+	assert.Equal(t, &events.Archived{ID: 1, Participants: 260, Hash: hash}, evs[0])
+
+	for key := range a.f.state {
+		assert.False(t, strings.HasPrefix(key, "lpi:") || strings.HasPrefix(key, "lpu:"), key)
+	}
+	meta := loadLotteryMetadata(1)
+	assert.Equal(t, uint64(260), meta.ArchivedCount)
+	assert.Equal(t, uint64(260), loadLotteryPoolStats(1).ParticipantCount)
+	assert.Len(t, meta.Winners, 2)
+
+	assert.Equal(t, "lottery already archived", a.call(t, archive_lottery, "1", "hive:anyone").Err)
+	assert.Equal(t, "lottery archived - verify off-chain against the participants hash", a.call(t, verify_lottery, "1|"+strconv.FormatUint(meta.RandomSeed, 10), "hive:anyone").Err)
+}
+
+// TestArchiveLotteryValidation tests payload and lottery checks
+func TestArchiveLotteryValidation(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Open|24|10|100|1.000", "hive:creator")
+
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"", "archive_lottery payload missing"},
+		{"0", "lottery ID must be greater than 0"},
+		{"x", "invalid lottery ID"},
+		{"2", "lottery not found"},
+		{"1", "lottery not executed yet"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.at(archiveTime).call(t, archive_lottery, tt.payload, "hive:anyone").Err, tt.payload)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/sdk"
+)
+
+// The charity registry lists donation accounts the contract owner has verified, under ch:<id> with
+// a cha:<address> lookup. A lottery records at creation which of its donation recipients are
+// registered; it counts as a verified charity lottery if all of them are. Recipients that are not
+// registered have to accept the donation with accept_donation before the lottery sells tickets,
+// so nobody can be named as a "charity" without agreeing to it.
+
+// resolveDonationRecipients looks up the registry status of a new lottery's recipients. Registered
+// charities and recipients without a share need no acceptance, a creator naming itself accepts by
+// creating the lottery.
+func resolveDonationRecipients(donations []DonationRecipient, creator sdk.Address) {
+	for i := range donations {
+		d := &donations[i]
+		d.CharityID = loadCharityID(d.Account.String())
+		d.Accepted = d.CharityID != 0 || d.Account == creator || d.Percent == 0
+	}
+}
+
+//export register_charity
+func register_charity(payload *string) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "register_charity payload missing")
+	args := parseRegisterCharity(payloadStr, format)
+
+	if id := loadCharityID(args.Address.String()); id != 0 {
+		sdk.Abort("charity already registered with ID: " + strconv.FormatUint(id, 10))
+	}
+	charity := &Charity{
+		ID:      getNextCharityID(),
+		Address: args.Address,
+		Name:    args.Name,
+		URL:     args.URL,
+	}
+	saveCharity(charity)
+	emitCharityRegistered(charity)
+
+	ret := "charity registered with ID: " + strconv.FormatUint(charity.ID, 10)
+	return &ret
+}
+
+//export remove_charity
+func remove_charity(payload *string) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "remove_charity payload missing")
+	id := parseRemoveCharity(payloadStr, format)
+
+	charity := loadCharity(id)
+	if charity == nil {
+		sdk.Abort("charity not found")
+	}
+	// Lotteries already created keep the charity ID they announced
+	deleteCharity(charity)
+	emitCharityRemoved(charity)
+
+	ret := "charity removed: " + charity.Address.String()
+	return &ret
+}
+
+//export get_charity
+func get_charity(payload *string) *string {
+	requireCurrentSchema()
+	payloadStr, _ := unwrapPayload(payload, "get_charity payload missing")
+
+	// Format: charityID or address
+	ref := strings.TrimSpace(payloadStr)
+	id, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		id = loadCharityID(ref)
+	}
+	charity := loadCharity(id)
+	if charity == nil {
+		sdk.Abort("charity not found")
+	}
+
+	ret := "id:" + strconv.FormatUint(charity.ID, 10) + "|address:" + charity.Address.String() + "|name:" + charity.Name + "|url:" + charity.URL
+	return &ret
+}
+
+//export accept_donation
+func accept_donation(payload *string) *string {
+	requireCurrentSchema()
+	requireNotPaused("accept_donation")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "accept_donation payload missing")
+	args := parseAcceptDonation(payloadStr, format)
+
+	meta := loadLotteryMetadata(args.LotteryID)
+	if meta == nil {
+		sdk.Abort("lottery not found")
+	}
+	if meta.State != LotteryStateActive {
+		sdk.Abort("lottery is not active")
+	}
+
+	sender := getSenderAddress()
+	var recipient *DonationRecipient
+	for i := range meta.Donations {
+		if meta.Donations[i].Account == sender {
+			recipient = &meta.Donations[i]
+		}
+	}
+	if recipient == nil {
+		sdk.Abort("sender is not a donation account of this lottery")
+	}
+	if recipient.Accepted {
+		sdk.Abort("donation already accepted")
+	}
+
+	recipient.Accepted = true
+	saveLotteryMetadata(meta)
+	emitDonationAccepted(meta.ID, sender)
+
+	ret := "donation accepted"
+	if pending := pendingDonation(meta.Donations); pending != nil {
+		ret += ", waiting for " + pending.Account.String()
+	}
+	return &ret
+}
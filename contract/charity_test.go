@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"okinoko_lottery/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCharityRegistry tests registering, looking up and removing charities
+func TestCharityRegistry(t *testing.T) {
+	f := newFakeHost(t)
+	assert.Equal(t, "only the contract owner can do this", f.call(t, register_charity, "hive:ocean|Ocean DAO|https://ocean.org", "hive:creator").Err)
+
+	res := f.mustCall(t, register_charity, "hive:ocean|Ocean DAO|https://ocean.org", fakeOwner)
+	assert.Equal(t, "charity registered with ID: 1", res.Ret)
+	registered := v2Events(t, res.Logs)[0].(*events.CharityRegistered)
+	assert.Equal(t, &events.CharityRegistered{ID: 1, Address: "hive:ocean", Name: "Ocean DAO", URL: "https://ocean.org", At: registered.At}, registered)
+
+	res = f.mustCall(t, register_charity, `{"address":"did:key:z6Mk","name":"Shelter","url":"https://shelter.example/about"}`, fakeOwner)
+	assert.Equal(t, "charity registered with ID: 2", res.Ret)
+
+	want := "id:1|address:hive:ocean|name:Ocean DAO|url:https://ocean.org"
+	assert.Equal(t, want, f.mustCall(t, get_charity, "1", "hive:anyone").Ret)
+	assert.Equal(t, want, f.mustCall(t, get_charity, "hive:ocean", "hive:anyone").Ret)
+	assert.Equal(t, "charity not found", f.call(t, get_charity, "hive:nobody", "hive:anyone").Err)
+
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"hive:ocean|Ocean DAO|https://ocean.org", "charity already registered with ID: 1"},
+		{"hive:ocean|Ocean DAO", "invalid register_charity payload format: expected address|name|url"},
+		{"ocean|Ocean DAO|https://ocean.org", "invalid charity address"},
+		{"hive:forest| |https://forest.org", "charity name must be between 1 and 100 characters"},
+		{"hive:forest|Forest|http://forest.org", "charity URL must be an https:// link"},
+		{"hive:forest|Forest|https://", "charity URL must be an https:// link"},
+		{`{"address":"hive:forest","name":"A|B","url":"https://forest.org"}`, "charity name cannot contain pipe character"},
+		{`{"address":"hive:forest","name":"Forest"}`, "charity URL must be an https:// link"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, register_charity, tt.payload, fakeOwner).Err, tt.payload)
+	}
+
+	// A lottery keeps the ID it was created with after the charity is removed
+	f.mustCall(t, create_lottery, "Ocean|24|10|100|1.000|hive:ocean|10", "hive:creator")
+	res = f.mustCall(t, remove_charity, `{"charity_id":1}`, fakeOwner)
+	assert.Equal(t, "charity removed: hive:ocean", res.Ret)
+	assert.Equal(t, &events.CharityRemoved{ID: 1, Address: "hive:ocean", At: registered.At}, v2Events(t, res.Logs)[0])
+	assert.Equal(t, "charity not found", f.call(t, get_charity, "hive:ocean", "hive:anyone").Err)
+	assert.Equal(t, "charity not found", f.call(t, remove_charity, "1", fakeOwner).Err)
+	assert.Equal(t, "charity ID must be greater than 0", f.call(t, remove_charity, "0", fakeOwner).Err)
+	assert.Equal(t, uint64(1), loadLotteryMetadata(1).Donations[0].CharityID)
+
+	// IDs are not reused
+	res = f.mustCall(t, register_charity, "hive:ocean|Ocean DAO|https://ocean.org", fakeOwner)
+	assert.Equal(t, "charity registered with ID: 3", res.Ret)
+}
+
+// TestDonationOptIn tests that an unregistered donation account has to accept before tickets are sold
+func TestDonationOptIn(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.f.fund("hive:alice", 10_000)
+	a.mustCall(t, register_charity, "hive:ocean|Ocean DAO|https://ocean.org", fakeOwner)
+
+	res := a.mustCall(t, create_lottery, "Mixed|24|10|100|1.000|donations=hive:ocean=10,hive:friend=5", "hive:creator")
+	created := v2Events(t, res.Logs)[0].(*events.Created)
+	assert.Equal(t, []events.DonationShare{{Account: "hive:ocean", Percent: 1000, CharityID: 1}, {Account: "hive:friend", Percent: 500}}, created.Donations)
+	assert.False(t, created.Verified)
+	assert.Contains(t, res.Logs[1], "|charity_id:1,0")
+
+	assert.Equal(t, "donation account hive:friend has not accepted the donation yet", a.call(t, join_lottery, "1", "hive:alice", transferAllow("1.000")).Err)
+	assert.Equal(t, "sender is not a donation account of this lottery", a.call(t, accept_donation, "1", "hive:alice").Err)
+	assert.Equal(t, "donation already accepted", a.call(t, accept_donation, "1", "hive:ocean").Err)
+	assert.Equal(t, "lottery not found", a.call(t, accept_donation, "2", "hive:friend").Err)
+
+	res = a.mustCall(t, accept_donation, `{"lottery_id":1}`, "hive:friend")
+	assert.Equal(t, "donation accepted", res.Ret)
+	assert.Equal(t, []events.Event{&events.DonationAccepted{ID: 1, Account: "hive:friend"}}, v2Events(t, res.Logs))
+	assert.Equal(t, "donation already accepted", a.call(t, accept_donation, "1", "hive:friend").Err)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+
+	// Registered charities only make a verified charity lottery that sells tickets right away
+	res = a.mustCall(t, create_lottery, "Ocean|24|10|100|1.000|hive:ocean|10", "hive:creator")
+	assert.True(t, v2Events(t, res.Logs)[0].(*events.Created).Verified)
+	assert.Contains(t, res.Logs[1], "|charity_id:1|verified:true")
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+
+	// A creator naming itself accepts by creating the lottery
+	res = a.mustCall(t, create_lottery, "Self|24|10|100|1.000|hive:creator|10", "hive:creator")
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 2)
+	assert.Equal(t, &events.DonationAccepted{ID: 3, Account: "hive:creator"}, evs[1])
+	a.mustCall(t, join_lottery, "3", "hive:alice", transferAllow("1.000"))
+
+	// Once the lottery is over there is nothing left to accept
+	a.f.at(fakeFuture)
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Equal(t, "lottery is not active", a.call(t, accept_donation, "1", "hive:friend").Err)
+}
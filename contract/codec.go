@@ -6,27 +6,65 @@ import (
 	"okinoko_lottery/sdk"
 )
 
+// Every record starts with its schema version, followed by varint fields in a fixed order:
+// unsigned values as uvarint, signed values (timestamps, amounts) zigzag-encoded as varint,
+// strings and slices with a uvarint length prefix, percentages as basis points.
+//
+// Version 0 is the original layout without a version byte: fixed 8-byte little endian fields
+// and percentages as float64. Its first byte can take any value, so a record is taken for
+// versioned only if it parses completely with the version named by its first byte; everything
+// else is decoded as version 0, which must consume the record exactly as well. Decoders always
+// return the current in-memory form, and encoders always write codecVersion, so old records are
+// upgraded the next time they are written.
+//
+// Later versions only append fields: the compact decoders read every version from
+// codecVersionCompact up to codecVersion and leave the fields a record predates at zero.
+const (
+	codecVersionLegacy    = 0 // unversioned fixed-width layout
+	codecVersionCompact   = 1 // version byte + varints
+	codecVersionArchive   = 2 // lm: participant archive progress and hash
+	codecVersionTickets   = 3 // lm: ticket ownership Merkle root
+	codecVersionFees      = 4 // lm: protocol fee rate and amount, config: protocol fee rate
+	codecVersionCreator   = 5 // lm: creator fee rate and amount, config: creator fee cap
+	codecVersionDonations = 6 // lm: donation recipients after the first
+	codecVersionCharities = 7 // lm: charity ID and acceptance of every donation recipient
+	codecVersion          = codecVersionCharities
+)
+
 // LotteryMetadata contains the static/rarely-changing lottery data
 type LotteryMetadata struct {
-	ID              uint64
-	Creator         sdk.Address
-	Name            string
-	CreatedAt       int64
-	DeadlineHours   uint64
-	DeadlineUnix    int64
-	MaxTickets      uint64
-	BurnPercent     float64
-	TicketPrice     Amount
-	Asset           sdk.Asset
-	WinnerShares    []float64
-	State           LotteryState
-	Winners         []Winner
-	ExecutedAt      int64
-	RandomSeed      uint64
-	BurnedAmount    Amount
-	DonationAccount sdk.Address
-	DonationPercent float64
-	DonatedAmount   Amount
+	ID            uint64
+	Creator       sdk.Address
+	Name          string
+	CreatedAt     int64
+	DeadlineHours uint64
+	DeadlineUnix  int64
+	MaxTickets    uint64
+	BurnPercent   BasisPoints
+	TicketPrice   Amount
+	Asset         sdk.Asset
+	WinnerShares  []BasisPoints
+	State         LotteryState
+	Winners       []Winner
+	ExecutedAt    int64
+	RandomSeed    uint64
+	BurnedAmount  Amount
+	Donations     []DonationRecipient // The first one is kept in the original donation fields
+	DonatedAmount Amount              // Sum over all recipients
+
+	// Participant archive, see archive_lottery
+	ArchivedCount    uint64 // Participant entries pruned so far
+	ParticipantsHash string // Raw SHA-256 chain over the pruned entries, empty before archiving
+
+	TicketsRoot string // Raw Merkle root over the participants' ticket ranges, set at execution
+
+	// Protocol fee, the rate is taken from the config at creation
+	ProtocolFeePercent BasisPoints
+	ProtocolFeeAmount  Amount // Moved to the treasury at execution
+
+	// Creator fee, chosen by the creator within the config cap
+	CreatorFeePercent BasisPoints
+	CreatorFeeAmount  Amount // Transferred to the creator at execution
 }
 
 // LotteryPoolStats contains pool and ticket totals (frequently updated)
@@ -42,52 +80,204 @@ type ParticipantEntry struct {
 	Tickets uint64
 }
 
+// CreatorStats contains lifetime aggregates across all lotteries of one creator
+type CreatorStats struct {
+	LotteryCount uint64 // Lotteries ever created
+	ActiveCount  uint64 // Lotteries not yet executed
+	TotalVolume  Amount // Ticket sales across all lotteries
+	TotalDonated Amount // Donations routed to the lotteries' donation accounts
+	CappedCount  uint64 // Lotteries created with MaxTickets > 0
+	FillRateSum  uint64 // Sum of per-lottery fill rates in basis points (capped lotteries only)
+}
+
+// Config holds the protocol limits create_lottery enforces, see set_config
+type Config struct {
+	MinBurnPercent     BasisPoints
+	MaxBurnPercent     BasisPoints
+	MinDeadlineHours   uint64
+	MaxDeadlineHours   uint64
+	MaxDonationPercent BasisPoints
+	MaxBurnAndDonation BasisPoints // Cap on burn + donation so the winners keep the rest
+	MinTicketPrice     Amount
+	MaxNameLength      uint64
+	MaxMetadataLength  uint64
+
+	ProtocolFee BasisPoints // Share of every pool kept in the treasury, 0 disables the fee
+
+	MaxCreatorFeePercent BasisPoints // Cap on the creator fee, counted in MaxBurnAndDonation as well
+}
+
 // encodeLotteryMetadata encodes the static lottery metadata
 func encodeLotteryMetadata(m *LotteryMetadata) string {
-	buf := make([]byte, 0, 256)
-
-	buf = appendUint64(buf, m.ID)
-	buf = appendString(buf, m.Creator.String())
-	buf = appendString(buf, m.Name)
-	buf = appendInt64(buf, m.CreatedAt)
-	buf = appendUint64(buf, m.DeadlineHours)
-	buf = appendInt64(buf, m.DeadlineUnix)
-	buf = appendUint64(buf, m.MaxTickets)
-	buf = appendFloat64(buf, m.BurnPercent)
-	buf = appendInt64(buf, int64(m.TicketPrice))
-	buf = appendString(buf, m.Asset.String())
+	buf := make([]byte, 0, 96)
+	buf = append(buf, codecVersion)
+
+	buf = binary.AppendUvarint(buf, m.ID)
+	buf = appendVarString(buf, m.Creator.String())
+	buf = appendVarString(buf, m.Name)
+	buf = binary.AppendVarint(buf, m.CreatedAt)
+	buf = binary.AppendUvarint(buf, m.DeadlineHours)
+	buf = binary.AppendVarint(buf, m.DeadlineUnix)
+	buf = binary.AppendUvarint(buf, m.MaxTickets)
+	buf = binary.AppendUvarint(buf, uint64(m.BurnPercent))
+	buf = binary.AppendVarint(buf, int64(m.TicketPrice))
+	buf = appendVarString(buf, m.Asset.String())
 
 	// WinnerShares slice
-	buf = appendUint64(buf, uint64(len(m.WinnerShares)))
+	buf = binary.AppendUvarint(buf, uint64(len(m.WinnerShares)))
 	for _, share := range m.WinnerShares {
-		buf = appendFloat64(buf, share)
+		buf = binary.AppendUvarint(buf, uint64(share))
 	}
 
 	buf = append(buf, byte(m.State))
 
 	// Winners slice
-	buf = appendUint64(buf, uint64(len(m.Winners)))
+	buf = binary.AppendUvarint(buf, uint64(len(m.Winners)))
 	for _, w := range m.Winners {
-		buf = appendString(buf, w.Address.String())
-		buf = appendInt64(buf, int64(w.Amount))
-		buf = appendFloat64(buf, w.Share)
+		buf = appendVarString(buf, w.Address.String())
+		buf = binary.AppendVarint(buf, int64(w.Amount))
+		buf = binary.AppendUvarint(buf, uint64(w.Share))
 	}
 
-	buf = appendInt64(buf, m.ExecutedAt)
-	buf = appendUint64(buf, m.RandomSeed)
-	buf = appendInt64(buf, int64(m.BurnedAmount))
+	buf = binary.AppendVarint(buf, m.ExecutedAt)
+	buf = binary.AppendUvarint(buf, m.RandomSeed)
+	buf = binary.AppendVarint(buf, int64(m.BurnedAmount))
 
-	// Donation fields
-	buf = appendString(buf, m.DonationAccount.String())
-	buf = appendFloat64(buf, m.DonationPercent)
-	buf = appendInt64(buf, int64(m.DonatedAmount))
+	// Donation fields, the first recipient keeps its original place
+	first := DonationRecipient{}
+	if len(m.Donations) > 0 {
+		first = m.Donations[0]
+	}
+	buf = appendVarString(buf, first.Account.String())
+	buf = binary.AppendUvarint(buf, uint64(first.Percent))
+	buf = binary.AppendVarint(buf, int64(m.DonatedAmount))
+
+	// Archive fields
+	buf = binary.AppendUvarint(buf, m.ArchivedCount)
+	buf = appendVarString(buf, m.ParticipantsHash)
+
+	buf = appendVarString(buf, m.TicketsRoot)
+
+	// Protocol fee
+	buf = binary.AppendUvarint(buf, uint64(m.ProtocolFeePercent))
+	buf = binary.AppendVarint(buf, int64(m.ProtocolFeeAmount))
+
+	// Creator fee
+	buf = binary.AppendUvarint(buf, uint64(m.CreatorFeePercent))
+	buf = binary.AppendVarint(buf, int64(m.CreatorFeeAmount))
+
+	// Further donation recipients
+	more := []DonationRecipient{}
+	if len(m.Donations) > 1 {
+		more = m.Donations[1:]
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(more)))
+	for _, d := range more {
+		buf = appendVarString(buf, d.Account.String())
+		buf = binary.AppendUvarint(buf, uint64(d.Percent))
+	}
+
+	// Registry status of every donation recipient, in order
+	for _, d := range m.Donations {
+		buf = binary.AppendUvarint(buf, d.CharityID)
+		buf = append(buf, boolByte(d.Accepted))
+	}
 
 	return string(buf)
 }
 
-// decodeLotteryMetadata decodes the static lottery metadata
+// decodeLotteryMetadata decodes the static lottery metadata of any schema version
 func decodeLotteryMetadata(data string) *LotteryMetadata {
 	buf := []byte(data)
+	if m, ok := decodeLotteryMetadataCompact(buf); ok {
+		return m
+	}
+	return decodeLotteryMetadataV0(buf)
+}
+
+// decodeLotteryMetadataCompact decodes the compact layout, ok is false if buf is not a valid
+// record of version 1 or later
+func decodeLotteryMetadataCompact(buf []byte) (*LotteryMetadata, bool) {
+	r := newVarReader(buf)
+	m := &LotteryMetadata{}
+
+	m.ID = r.uvarint()
+	m.Creator = AddressFromString(r.string())
+	m.Name = r.string()
+	m.CreatedAt = r.varint()
+	m.DeadlineHours = r.uvarint()
+	m.DeadlineUnix = r.varint()
+	m.MaxTickets = r.uvarint()
+	m.BurnPercent = r.percent()
+	m.TicketPrice = Amount(r.varint())
+	m.Asset = AssetFromString(r.string())
+
+	// WinnerShares slice
+	m.WinnerShares = make([]BasisPoints, r.count(1))
+	for i := range m.WinnerShares {
+		m.WinnerShares[i] = r.percent()
+	}
+
+	m.State = LotteryState(r.byte())
+
+	// Winners slice, each at least an empty address, an amount and a share
+	m.Winners = make([]Winner, r.count(3))
+	for i := range m.Winners {
+		m.Winners[i] = Winner{
+			Address: AddressFromString(r.string()),
+			Amount:  Amount(r.varint()),
+			Share:   r.percent(),
+		}
+	}
+
+	m.ExecutedAt = r.varint()
+	m.RandomSeed = r.uvarint()
+	m.BurnedAmount = Amount(r.varint())
+
+	// Donation fields
+	m.Donations = donationRecipients(r.string(), r.percent())
+	m.DonatedAmount = Amount(r.varint())
+
+	// Archive fields
+	if r.version >= codecVersionArchive {
+		m.ArchivedCount = r.uvarint()
+		m.ParticipantsHash = r.string()
+	}
+	if r.version >= codecVersionTickets {
+		m.TicketsRoot = r.string()
+	}
+	if r.version >= codecVersionFees {
+		m.ProtocolFeePercent = r.percent()
+		m.ProtocolFeeAmount = Amount(r.varint())
+	}
+	if r.version >= codecVersionCreator {
+		m.CreatorFeePercent = r.percent()
+		m.CreatorFeeAmount = Amount(r.varint())
+	}
+	if r.version >= codecVersionDonations {
+		// Further recipients, each at least an empty account and a percent
+		more := r.count(2)
+		for i := uint64(0); i < more; i++ {
+			m.Donations = append(m.Donations, DonationRecipient{
+				Account:  AddressFromString(r.string()),
+				Percent:  r.percent(),
+				Accepted: true,
+			})
+		}
+	}
+	if r.version >= codecVersionCharities {
+		for i := range m.Donations {
+			m.Donations[i].CharityID = r.uvarint()
+			m.Donations[i].Accepted = r.bool()
+		}
+	}
+
+	return m, r.done()
+}
+
+// decodeLotteryMetadataV0 decodes the original fixed-width layout, with or without the
+// donation fields that were appended later
+func decodeLotteryMetadataV0(buf []byte) *LotteryMetadata {
 	offset := 0
 
 	m := &LotteryMetadata{}
@@ -101,7 +291,7 @@ func decodeLotteryMetadata(data string) *LotteryMetadata {
 	m.DeadlineHours, offset = readUint64(buf, offset)
 	m.DeadlineUnix, offset = readInt64(buf, offset)
 	m.MaxTickets, offset = readUint64(buf, offset)
-	m.BurnPercent, offset = readFloat64(buf, offset)
+	m.BurnPercent, offset = readPercent(buf, offset)
 	ticketPrice, off := readInt64(buf, offset)
 	m.TicketPrice = Amount(ticketPrice)
 	offset = off
@@ -110,11 +300,11 @@ func decodeLotteryMetadata(data string) *LotteryMetadata {
 	offset = off
 
 	// WinnerShares slice
-	sharesLen, off := readUint64(buf, offset)
+	sharesLen, off := readCount(buf, offset, 8)
 	offset = off
-	m.WinnerShares = make([]float64, sharesLen)
+	m.WinnerShares = make([]BasisPoints, sharesLen)
 	for i := uint64(0); i < sharesLen; i++ {
-		m.WinnerShares[i], offset = readFloat64(buf, offset)
+		m.WinnerShares[i], offset = readPercent(buf, offset)
 	}
 
 	if offset >= len(buf) {
@@ -124,7 +314,7 @@ func decodeLotteryMetadata(data string) *LotteryMetadata {
 	offset++
 
 	// Winners slice
-	winnersLen, off := readUint64(buf, offset)
+	winnersLen, off := readCount(buf, offset, 24)
 	offset = off
 	m.Winners = make([]Winner, winnersLen)
 	for i := uint64(0); i < winnersLen; i++ {
@@ -132,7 +322,7 @@ func decodeLotteryMetadata(data string) *LotteryMetadata {
 		offset = off
 		amount, off := readInt64(buf, offset)
 		offset = off
-		share, off := readFloat64(buf, offset)
+		share, off := readPercent(buf, offset)
 		offset = off
 		m.Winners[i] = Winner{
 			Address: AddressFromString(addrStr),
@@ -147,87 +337,340 @@ func decodeLotteryMetadata(data string) *LotteryMetadata {
 	m.BurnedAmount = Amount(burnedAmount)
 	offset = off
 
+	// Records written before donations existed end here
+	if offset == len(buf) {
+		return m
+	}
+
 	// Donation fields
 	donationAccountStr, off := readString(buf, offset)
-	m.DonationAccount = AddressFromString(donationAccountStr)
 	offset = off
-	m.DonationPercent, offset = readFloat64(buf, offset)
+	donationPercent, off := readPercent(buf, offset)
+	offset = off
+	m.Donations = donationRecipients(donationAccountStr, donationPercent)
 	donatedAmount, off := readInt64(buf, offset)
 	m.DonatedAmount = Amount(donatedAmount)
+	offset = off
 
+	expectEnd(buf, offset)
 	return m
 }
 
+// donationRecipients returns the single recipient stored in the original donation fields,
+// or none if the account is empty. Recipients count as accepted until a version 7 record
+// says otherwise, lotteries from before the charity registry never had to ask.
+func donationRecipients(account string, percent BasisPoints) []DonationRecipient {
+	if account == "" {
+		return nil
+	}
+	return []DonationRecipient{{Account: AddressFromString(account), Percent: percent, Accepted: true}}
+}
+
 // encodeLotteryPoolStats encodes pool statistics
 func encodeLotteryPoolStats(s *LotteryPoolStats) string {
-	buf := make([]byte, 0, 24)
-	buf = appendInt64(buf, int64(s.Pool))
-	buf = appendUint64(buf, s.TotalTickets)
-	buf = appendUint64(buf, s.ParticipantCount)
+	buf := make([]byte, 0, 16)
+	buf = append(buf, codecVersion)
+	buf = binary.AppendVarint(buf, int64(s.Pool))
+	buf = binary.AppendUvarint(buf, s.TotalTickets)
+	buf = binary.AppendUvarint(buf, s.ParticipantCount)
 	return string(buf)
 }
 
-// decodeLotteryPoolStats decodes pool statistics
+// decodeLotteryPoolStats decodes pool statistics of any schema version
 func decodeLotteryPoolStats(data string) *LotteryPoolStats {
 	buf := []byte(data)
-	offset := 0
 
+	r := newVarReader(buf)
 	s := &LotteryPoolStats{}
+	s.Pool = Amount(r.varint())
+	s.TotalTickets = r.uvarint()
+	s.ParticipantCount = r.uvarint()
+	if r.done() {
+		return s
+	}
+
+	// Version 0: three fixed 8-byte fields
+	s = &LotteryPoolStats{}
+	offset := 0
 	pool, off := readInt64(buf, offset)
 	s.Pool = Amount(pool)
 	offset = off
 	s.TotalTickets, offset = readUint64(buf, offset)
 	s.ParticipantCount, offset = readUint64(buf, offset)
+	expectEnd(buf, offset)
 
 	return s
 }
 
 // encodeParticipantEntry encodes a participant entry (address and tickets)
 func encodeParticipantEntry(p *ParticipantEntry) string {
-	buf := make([]byte, 0, 32)
-	buf = appendString(buf, p.Address)
-	buf = appendUint64(buf, p.Tickets)
+	buf := make([]byte, 0, 2+len(p.Address)+4)
+	buf = append(buf, codecVersion)
+	buf = appendVarString(buf, p.Address)
+	buf = binary.AppendUvarint(buf, p.Tickets)
 	return string(buf)
 }
 
-// decodeParticipantEntry decodes a participant entry
+// decodeParticipantEntry decodes a participant entry of any schema version
 func decodeParticipantEntry(data string) *ParticipantEntry {
 	buf := []byte(data)
-	offset := 0
 
+	r := newVarReader(buf)
 	p := &ParticipantEntry{}
+	p.Address = r.string()
+	p.Tickets = r.uvarint()
+	if r.done() {
+		return p
+	}
+
+	// Version 0: length-prefixed address and a fixed 8-byte ticket count
+	p = &ParticipantEntry{}
+	offset := 0
 	p.Address, offset = readString(buf, offset)
 	p.Tickets, offset = readUint64(buf, offset)
+	expectEnd(buf, offset)
 
 	return p
 }
 
-// Binary encoding helpers
+// encodeCreatorStats encodes creator aggregates
+func encodeCreatorStats(s *CreatorStats) string {
+	buf := make([]byte, 0, 24)
+	buf = append(buf, codecVersion)
+	buf = binary.AppendUvarint(buf, s.LotteryCount)
+	buf = binary.AppendUvarint(buf, s.ActiveCount)
+	buf = binary.AppendVarint(buf, int64(s.TotalVolume))
+	buf = binary.AppendVarint(buf, int64(s.TotalDonated))
+	buf = binary.AppendUvarint(buf, s.CappedCount)
+	buf = binary.AppendUvarint(buf, s.FillRateSum)
+	return string(buf)
+}
 
-func appendUint64(buf []byte, v uint64) []byte {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, v)
-	return append(buf, b...)
+// decodeCreatorStats decodes creator aggregates of any schema version
+func decodeCreatorStats(data string) *CreatorStats {
+	buf := []byte(data)
+
+	r := newVarReader(buf)
+	s := &CreatorStats{}
+	s.LotteryCount = r.uvarint()
+	s.ActiveCount = r.uvarint()
+	s.TotalVolume = Amount(r.varint())
+	s.TotalDonated = Amount(r.varint())
+	s.CappedCount = r.uvarint()
+	s.FillRateSum = r.uvarint()
+	if r.done() {
+		return s
+	}
+
+	// Version 0: six fixed 8-byte fields
+	s = &CreatorStats{}
+	offset := 0
+	s.LotteryCount, offset = readUint64(buf, offset)
+	s.ActiveCount, offset = readUint64(buf, offset)
+	volume, off := readInt64(buf, offset)
+	s.TotalVolume = Amount(volume)
+	offset = off
+	donated, off := readInt64(buf, offset)
+	s.TotalDonated = Amount(donated)
+	offset = off
+	s.CappedCount, offset = readUint64(buf, offset)
+	s.FillRateSum, offset = readUint64(buf, offset)
+	expectEnd(buf, offset)
+
+	return s
+}
+
+// encodeConfig encodes the protocol limits
+func encodeConfig(c *Config) string {
+	buf := make([]byte, 0, 24)
+	buf = append(buf, codecVersion)
+	buf = binary.AppendUvarint(buf, uint64(c.MinBurnPercent))
+	buf = binary.AppendUvarint(buf, uint64(c.MaxBurnPercent))
+	buf = binary.AppendUvarint(buf, c.MinDeadlineHours)
+	buf = binary.AppendUvarint(buf, c.MaxDeadlineHours)
+	buf = binary.AppendUvarint(buf, uint64(c.MaxDonationPercent))
+	buf = binary.AppendUvarint(buf, uint64(c.MaxBurnAndDonation))
+	buf = binary.AppendVarint(buf, int64(c.MinTicketPrice))
+	buf = binary.AppendUvarint(buf, c.MaxNameLength)
+	buf = binary.AppendUvarint(buf, c.MaxMetadataLength)
+	buf = binary.AppendUvarint(buf, uint64(c.ProtocolFee))
+	buf = binary.AppendUvarint(buf, uint64(c.MaxCreatorFeePercent))
+	return string(buf)
+}
+
+// decodeConfig decodes the protocol limits. The record was introduced after versioning,
+// so there is no version 0 layout to fall back to.
+func decodeConfig(data string) *Config {
+	r := newVarReader([]byte(data))
+	c := &Config{}
+	c.MinBurnPercent = r.percent()
+	c.MaxBurnPercent = r.percent()
+	c.MinDeadlineHours = r.uvarint()
+	c.MaxDeadlineHours = r.uvarint()
+	c.MaxDonationPercent = r.percent()
+	c.MaxBurnAndDonation = r.percent()
+	c.MinTicketPrice = Amount(r.varint())
+	c.MaxNameLength = r.uvarint()
+	c.MaxMetadataLength = r.uvarint()
+	if r.version >= codecVersionFees {
+		c.ProtocolFee = r.percent()
+	}
+	if r.version >= codecVersionCreator {
+		c.MaxCreatorFeePercent = r.percent()
+	}
+	if !r.done() {
+		sdk.Abort("decode error: invalid config record")
+	}
+	return c
+}
+
+// encodeCharity encodes a charity registry entry
+func encodeCharity(c *Charity) string {
+	buf := make([]byte, 0, 16+len(c.Address)+len(c.Name)+len(c.URL))
+	buf = append(buf, codecVersion)
+	buf = binary.AppendUvarint(buf, c.ID)
+	buf = appendVarString(buf, c.Address.String())
+	buf = appendVarString(buf, c.Name)
+	buf = appendVarString(buf, c.URL)
+	return string(buf)
+}
+
+// decodeCharity decodes a charity registry entry. The record was introduced after versioning,
+// so there is no version 0 layout to fall back to.
+func decodeCharity(data string) *Charity {
+	r := newVarReader([]byte(data))
+	c := &Charity{}
+	c.ID = r.uvarint()
+	c.Address = AddressFromString(r.string())
+	c.Name = r.string()
+	c.URL = r.string()
+	if !r.done() {
+		sdk.Abort("decode error: invalid charity record")
+	}
+	return c
+}
+
+// Compact (version 1 and later) encoding helpers
+
+// appendVarString appends a uvarint length-prefixed string
+func appendVarString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// boolByte encodes a flag as a single 0 or 1 byte
+func boolByte(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// varReader reads a versioned varint record. Instead of aborting it remembers the first
+// failure, so callers can fall back to the version 0 decoder.
+type varReader struct {
+	buf     []byte
+	offset  int
+	failed  bool
+	version byte
+}
+
+// newVarReader starts reading buf after checking that its version byte is a compact version
+func newVarReader(buf []byte) *varReader {
+	r := &varReader{buf: buf}
+	r.version = r.byte()
+	if r.version < codecVersionCompact || r.version > codecVersion {
+		r.failed = true
+	}
+	return r
+}
+
+func (r *varReader) byte() byte {
+	if r.failed || r.offset >= len(r.buf) {
+		r.failed = true
+		return 0
+	}
+	b := r.buf[r.offset]
+	r.offset++
+	return b
+}
+
+func (r *varReader) uvarint() uint64 {
+	if r.failed {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf[r.offset:])
+	if n <= 0 {
+		r.failed = true
+		return 0
+	}
+	r.offset += n
+	return v
+}
+
+func (r *varReader) varint() int64 {
+	if r.failed {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.offset:])
+	if n <= 0 {
+		r.failed = true
+		return 0
+	}
+	r.offset += n
+	return v
+}
+
+func (r *varReader) string() string {
+	length := r.uvarint()
+	if r.failed || length > uint64(len(r.buf)-r.offset) {
+		r.failed = true
+		return ""
+	}
+	s := string(r.buf[r.offset : r.offset+int(length)])
+	r.offset += int(length)
+	return s
+}
+
+// percent reads basis points, values above 100% fail like corrupt data
+func (r *varReader) percent() BasisPoints {
+	v := r.uvarint()
+	if v > BasisPointsScale {
+		r.failed = true
+		return 0
+	}
+	return BasisPoints(v)
 }
 
-func appendInt64(buf []byte, v int64) []byte {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, uint64(v))
-	return append(buf, b...)
+// bool reads a flag written by boolByte, other values fail like corrupt data
+func (r *varReader) bool() bool {
+	switch r.byte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	r.failed = true
+	return false
 }
 
-func appendFloat64(buf []byte, v float64) []byte {
-	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
-	return append(buf, b...)
+// count reads a slice length that the remaining data can hold at minSize bytes per element
+func (r *varReader) count(minSize uint64) uint64 {
+	n := r.uvarint()
+	if r.failed || n > uint64(len(r.buf)-r.offset)/minSize {
+		r.failed = true
+		return 0
+	}
+	return n
 }
 
-func appendString(buf []byte, s string) []byte {
-	// Length-prefixed string
-	buf = appendUint64(buf, uint64(len(s)))
-	return append(buf, []byte(s)...)
+// done reports whether the whole record was read without a failure
+func (r *varReader) done() bool {
+	return !r.failed && r.offset == len(r.buf)
 }
 
+// Fixed-width (version 0) decoding helpers
+
 func readUint64(buf []byte, offset int) (uint64, int) {
 	if offset+8 > len(buf) {
 		sdk.Abort("decode error: insufficient data for uint64")
@@ -246,11 +689,39 @@ func readFloat64(buf []byte, offset int) (float64, int) {
 	return math.Float64frombits(v), off
 }
 
+// readPercent reads a float64 percentage and rounds it to basis points. Values outside
+// 0-100% cannot come from the parsers and are rejected as corrupt data.
+func readPercent(buf []byte, offset int) (BasisPoints, int) {
+	v, off := readFloat64(buf, offset)
+	if !(v >= 0 && v <= 100) {
+		sdk.Abort("decode error: invalid percent")
+	}
+	return BasisPoints(math.Round(v * 100)), off
+}
+
 func readString(buf []byte, offset int) (string, int) {
 	length, off := readUint64(buf, offset)
-	if off+int(length) > len(buf) {
+	// Compare against the remaining bytes in uint64, a huge prefix would overflow int
+	if length > uint64(len(buf)-off) {
 		sdk.Abort("decode error: insufficient data for string")
 	}
 	s := string(buf[off : off+int(length)])
 	return s, off + int(length)
 }
+
+// readCount reads a slice length and checks the remaining data can hold that many elements
+// of at least minSize bytes each, so a corrupt prefix cannot trigger a huge allocation
+func readCount(buf []byte, offset int, minSize uint64) (uint64, int) {
+	count, off := readUint64(buf, offset)
+	if count > uint64(len(buf)-off)/minSize {
+		sdk.Abort("decode error: invalid slice length")
+	}
+	return count, off
+}
+
+// expectEnd aborts if a record has bytes left after its last field
+func expectEnd(buf []byte, offset int) {
+	if offset != len(buf) {
+		sdk.Abort("decode error: trailing data")
+	}
+}
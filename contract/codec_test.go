@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Version 0 encoders, as the contract wrote records before the codec was versioned. Production
+// code only decodes this layout, the tests need to produce it.
+
+// encodeLotteryMetadataV0 encodes lottery metadata in the unversioned fixed-width layout
+func encodeLotteryMetadataV0(m *LotteryMetadata) string {
+	buf := make([]byte, 0, 256)
+
+	buf = appendUint64(buf, m.ID)
+	buf = appendString(buf, m.Creator.String())
+	buf = appendString(buf, m.Name)
+	buf = appendInt64(buf, m.CreatedAt)
+	buf = appendUint64(buf, m.DeadlineHours)
+	buf = appendInt64(buf, m.DeadlineUnix)
+	buf = appendUint64(buf, m.MaxTickets)
+	buf = appendPercent(buf, m.BurnPercent)
+	buf = appendInt64(buf, int64(m.TicketPrice))
+	buf = appendString(buf, m.Asset.String())
+
+	// WinnerShares slice
+	buf = appendUint64(buf, uint64(len(m.WinnerShares)))
+	for _, share := range m.WinnerShares {
+		buf = appendPercent(buf, share)
+	}
+
+	buf = append(buf, byte(m.State))
+
+	// Winners slice
+	buf = appendUint64(buf, uint64(len(m.Winners)))
+	for _, w := range m.Winners {
+		buf = appendString(buf, w.Address.String())
+		buf = appendInt64(buf, int64(w.Amount))
+		buf = appendPercent(buf, w.Share)
+	}
+
+	buf = appendInt64(buf, m.ExecutedAt)
+	buf = appendUint64(buf, m.RandomSeed)
+	buf = appendInt64(buf, int64(m.BurnedAmount))
+
+	// Donation fields, version 0 has room for one recipient
+	first := DonationRecipient{}
+	if len(m.Donations) > 0 {
+		first = m.Donations[0]
+	}
+	buf = appendString(buf, first.Account.String())
+	buf = appendPercent(buf, first.Percent)
+	buf = appendInt64(buf, int64(m.DonatedAmount))
+
+	return string(buf)
+}
+
+// encodeLotteryPoolStatsV0 encodes pool statistics in the unversioned fixed-width layout
+func encodeLotteryPoolStatsV0(s *LotteryPoolStats) string {
+	buf := make([]byte, 0, 24)
+	buf = appendInt64(buf, int64(s.Pool))
+	buf = appendUint64(buf, s.TotalTickets)
+	buf = appendUint64(buf, s.ParticipantCount)
+	return string(buf)
+}
+
+// encodeParticipantEntryV0 encodes a participant entry in the unversioned fixed-width layout
+func encodeParticipantEntryV0(p *ParticipantEntry) string {
+	buf := make([]byte, 0, 32)
+	buf = appendString(buf, p.Address)
+	buf = appendUint64(buf, p.Tickets)
+	return string(buf)
+}
+
+// encodeCreatorStatsV0 encodes creator aggregates in the unversioned fixed-width layout
+func encodeCreatorStatsV0(s *CreatorStats) string {
+	buf := make([]byte, 0, 48)
+	buf = appendUint64(buf, s.LotteryCount)
+	buf = appendUint64(buf, s.ActiveCount)
+	buf = appendInt64(buf, int64(s.TotalVolume))
+	buf = appendInt64(buf, int64(s.TotalDonated))
+	buf = appendUint64(buf, s.CappedCount)
+	buf = appendUint64(buf, s.FillRateSum)
+	return string(buf)
+}
+
+// encodeLotteryMetadataV6 encodes lottery metadata as version 6, which ends before the charity
+// ID and acceptance of the donation recipients. The IDs must be below 128 to take one byte.
+func encodeLotteryMetadataV6(m *LotteryMetadata) string {
+	buf := []byte(encodeLotteryMetadata(m))
+	buf[0] = codecVersionDonations
+	return string(buf[:len(buf)-2*len(m.Donations)])
+}
+
+// encodeLotteryMetadataV5 encodes lottery metadata as version 5, which keeps only the first donation recipient
+func encodeLotteryMetadataV5(m *LotteryMetadata) string {
+	current := *m
+	if len(current.Donations) > 1 {
+		current.Donations = current.Donations[:1]
+	}
+	buf := []byte(encodeLotteryMetadataV6(&current))
+	buf[0] = codecVersionCreator
+	// Drop the zero count of further recipients
+	return string(buf[:len(buf)-1])
+}
+
+// encodeLotteryMetadataV4 encodes lottery metadata as version 4, which ends before the creator fee
+func encodeLotteryMetadataV4(m *LotteryMetadata) string {
+	current := *m
+	current.CreatorFeePercent, current.CreatorFeeAmount = 0, 0
+	buf := []byte(encodeLotteryMetadataV5(&current))
+	buf[0] = codecVersionFees
+	// Drop the zero fee rate and amount
+	return string(buf[:len(buf)-2])
+}
+
+// encodeLotteryMetadataV3 encodes lottery metadata as version 3, which ends before the protocol fee
+func encodeLotteryMetadataV3(m *LotteryMetadata) string {
+	current := *m
+	current.ProtocolFeePercent, current.ProtocolFeeAmount = 0, 0
+	buf := []byte(encodeLotteryMetadataV4(&current))
+	buf[0] = codecVersionTickets
+	// Drop the zero fee rate and amount
+	return string(buf[:len(buf)-2])
+}
+
+// encodeLotteryMetadataV2 encodes lottery metadata as version 2, which ends before the tickets root
+func encodeLotteryMetadataV2(m *LotteryMetadata) string {
+	current := *m
+	current.TicketsRoot = ""
+	buf := []byte(encodeLotteryMetadataV3(&current))
+	buf[0] = codecVersionArchive
+	// Drop the empty root
+	return string(buf[:len(buf)-1])
+}
+
+// encodeLotteryMetadataV1 encodes lottery metadata as version 1, which ends before the archive fields
+func encodeLotteryMetadataV1(m *LotteryMetadata) string {
+	current := *m
+	current.ArchivedCount, current.ParticipantsHash = 0, ""
+	buf := []byte(encodeLotteryMetadataV2(&current))
+	buf[0] = codecVersionCompact
+	// Drop the zero archived count and the empty hash
+	return string(buf[:len(buf)-2])
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return append(buf, b...)
+}
+
+func appendInt64(buf []byte, v int64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(v))
+	return append(buf, b...)
+}
+
+func appendFloat64(buf []byte, v float64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	return append(buf, b...)
+}
+
+// appendPercent writes basis points as the float64 percentage version 0 stored
+func appendPercent(buf []byte, bps BasisPoints) []byte {
+	return appendFloat64(buf, float64(bps)/100)
+}
+
+func appendString(buf []byte, s string) []byte {
+	// Length-prefixed string
+	buf = appendUint64(buf, uint64(len(s)))
+	return append(buf, []byte(s)...)
+}
+
+// TestCodecWritesCurrentVersion tests that every encoder starts its record with the schema version
+func TestCodecWritesCurrentVersion(t *testing.T) {
+	for _, data := range []string{
+		encodeLotteryMetadata(sampleMetadata()),
+		encodeLotteryPoolStats(&LotteryPoolStats{Pool: 5000, TotalTickets: 5, ParticipantCount: 2}),
+		encodeParticipantEntry(&ParticipantEntry{Address: "hive:alice", Tickets: 3}),
+		encodeCreatorStats(&CreatorStats{LotteryCount: 2, TotalVolume: 10_000}),
+		encodeCharity(&Charity{ID: 1, Address: "hive:ocean", Name: "Ocean DAO", URL: "https://ocean.org"}),
+	} {
+		assert.Equal(t, byte(codecVersion), data[0])
+	}
+}
+
+// TestLegacyRecordsDecode tests that version 0 records decode into the current form
+func TestLegacyRecordsDecode(t *testing.T) {
+	meta := sampleMetadata()
+	assert.Equal(t, meta, decodeLotteryMetadata(encodeLotteryMetadataV0(meta)))
+
+	stats := &LotteryPoolStats{Pool: 257, TotalTickets: 5, ParticipantCount: 2} // first byte 0x01 like a version byte
+	assert.Equal(t, stats, decodeLotteryPoolStats(encodeLotteryPoolStatsV0(stats)))
+
+	entry := &ParticipantEntry{Address: "h", Tickets: 1} // one-byte address, length prefix starts with 0x01
+	assert.Equal(t, entry, decodeParticipantEntry(encodeParticipantEntryV0(entry)))
+
+	creator := &CreatorStats{LotteryCount: 1, ActiveCount: 1, TotalVolume: 12_000, TotalDonated: 500, CappedCount: 1, FillRateSum: 2500}
+	assert.Equal(t, creator, decodeCreatorStats(encodeCreatorStatsV0(creator)))
+}
+
+// TestLegacyMetadataWithoutDonation tests records written before the donation fields were appended
+func TestLegacyMetadataWithoutDonation(t *testing.T) {
+	meta := sampleMetadata()
+	meta.Donations, meta.DonatedAmount = nil, 0
+	full := encodeLotteryMetadataV0(meta)
+	// Empty account (8 byte length), percent and amount
+	preDonation := full[:len(full)-24]
+
+	assert.Equal(t, meta, decodeLotteryMetadata(preDonation))
+	assert.Equal(t, "decode error: trailing data", catchAbort(func() { decodeLotteryMetadata(full + "x") }))
+}
+
+// TestEarlierCompactMetadataDecodes tests that metadata written before the archive fields, the
+// tickets root, the protocol and creator fees, further donation recipients and their registry status decodes with those left
+// empty, or accepted for the recipients, and is rewritten in the current version
+func TestEarlierCompactMetadataDecodes(t *testing.T) {
+	meta := sampleMetadata()
+	v1 := encodeLotteryMetadataV1(meta)
+	assert.Equal(t, byte(codecVersionCompact), v1[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v1))
+	assert.Equal(t, byte(codecVersion), encodeLotteryMetadata(decodeLotteryMetadata(v1))[0])
+
+	meta.ArchivedCount, meta.ParticipantsHash = 3, strings.Repeat("h", 32)
+	v2 := encodeLotteryMetadataV2(meta)
+	assert.Equal(t, byte(codecVersionArchive), v2[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v2))
+
+	meta.TicketsRoot = strings.Repeat("r", 32)
+	v3 := encodeLotteryMetadataV3(meta)
+	assert.Equal(t, byte(codecVersionTickets), v3[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v3))
+
+	meta.ProtocolFeePercent, meta.ProtocolFeeAmount = 250, 1234
+	v4 := encodeLotteryMetadataV4(meta)
+	assert.Equal(t, byte(codecVersionFees), v4[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v4))
+
+	meta.CreatorFeePercent, meta.CreatorFeeAmount = 500, 2468
+	v5 := encodeLotteryMetadataV5(meta)
+	assert.Equal(t, byte(codecVersionCreator), v5[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v5))
+
+	meta.Donations = append(meta.Donations, DonationRecipient{Account: "hive:shelter", Percent: 250, Accepted: true})
+	v6 := encodeLotteryMetadataV6(meta)
+	assert.Equal(t, byte(codecVersionDonations), v6[0])
+	assert.Equal(t, meta, decodeLotteryMetadata(v6))
+
+	// A record carrying fields its version does not have is corrupt, not a newer record
+	current := encodeLotteryMetadata(meta)
+	assert.Contains(t, catchAbort(func() { decodeLotteryMetadata("\x01" + current[1:]) }), "decode error")
+	assert.Contains(t, catchAbort(func() { decodeLotteryMetadata("\x02" + current[1:] + "\x00") }), "decode error")
+}
+
+// TestCompactRoundTrip tests the current encoding with extreme values
+func TestCompactRoundTrip(t *testing.T) {
+	meta := sampleMetadata()
+	meta.CreatedAt, meta.ExecutedAt, meta.RandomSeed = -1, math.MaxInt64, math.MaxUint64
+	meta.Name = strings.Repeat("n", 100)
+	meta.ArchivedCount, meta.ParticipantsHash = math.MaxUint64, strings.Repeat("\xff", 32)
+	meta.TicketsRoot = strings.Repeat("\x01", 32)
+	meta.ProtocolFeePercent, meta.ProtocolFeeAmount = BasisPointsScale, math.MaxInt64
+	meta.CreatorFeePercent, meta.CreatorFeeAmount = BasisPointsScale, math.MaxInt64
+	meta.Donations = append(meta.Donations,
+		DonationRecipient{Account: "did:key:z6Mk", Percent: 1, CharityID: math.MaxUint64, Accepted: true},
+		DonationRecipient{Account: "hive:shelter", Percent: BasisPointsScale})
+	assert.Equal(t, meta, decodeLotteryMetadata(encodeLotteryMetadata(meta)))
+
+	// The first recipient stays where version 5 readers expect the only one
+	assert.True(t, strings.HasPrefix(encodeLotteryMetadata(meta)[1:], encodeLotteryMetadataV5(meta)[1:]))
+
+	empty := &LotteryMetadata{WinnerShares: []BasisPoints{}, Winners: []Winner{}}
+	assert.Equal(t, empty, decodeLotteryMetadata(encodeLotteryMetadata(empty)))
+
+	entry := &ParticipantEntry{Address: "did:pkh:eip155:1:0xabc", Tickets: math.MaxUint64}
+	assert.Equal(t, entry, decodeParticipantEntry(encodeParticipantEntry(entry)))
+
+	charity := &Charity{ID: math.MaxUint64, Address: "did:pkh:eip155:1:0xabc", Name: strings.Repeat("c", 100), URL: "https://example.org"}
+	assert.Equal(t, charity, decodeCharity(encodeCharity(charity)))
+	assert.Equal(t, "decode error: invalid charity record", catchAbort(func() { decodeCharity(encodeCharity(charity) + "x") }))
+
+	cfg := defaultConfig()
+	cfg.MinTicketPrice, cfg.MaxMetadataLength, cfg.ProtocolFee = math.MaxInt64, math.MaxUint64, 250
+	cfg.MaxCreatorFeePercent = BasisPointsScale
+	assert.Equal(t, cfg, decodeConfig(encodeConfig(cfg)))
+
+	// A config written before the fees has neither a protocol fee nor a creator fee cap
+	old := defaultConfig()
+	old.ProtocolFee, old.MaxCreatorFeePercent = 0, 0
+	v4 := []byte(encodeConfig(old))
+	v4[0] = codecVersionFees
+	assert.Equal(t, old, decodeConfig(string(v4[:len(v4)-1])))
+	v4[0] = codecVersionTickets
+	assert.Equal(t, old, decodeConfig(string(v4[:len(v4)-2])))
+}
+
+// TestCompactEncodingSize tests that the varint layout shrinks typical records
+func TestCompactEncodingSize(t *testing.T) {
+	meta := sampleMetadata()
+	assert.Less(t, len(encodeLotteryMetadata(meta))*2, len(encodeLotteryMetadataV0(meta)))
+
+	entry := &ParticipantEntry{Address: "hive:alice", Tickets: 42}
+	assert.Equal(t, 13, len(encodeParticipantEntry(entry)))
+	assert.Equal(t, 26, len(encodeParticipantEntryV0(entry)))
+
+	stats := &LotteryPoolStats{Pool: 1_000_000, TotalTickets: 1000, ParticipantCount: 50}
+	assert.Equal(t, 7, len(encodeLotteryPoolStats(stats)))
+}
+
+// downgradeState rewrites every record as an older contract version would have stored it
+func downgradeState(state map[string]string) {
+	for key, value := range state {
+		switch {
+		case strings.HasPrefix(key, "lm:"):
+			state[key] = encodeLotteryMetadataV0(decodeLotteryMetadata(value))
+		case strings.HasPrefix(key, "ls:"):
+			state[key] = encodeLotteryPoolStatsV0(decodeLotteryPoolStats(value))
+		case strings.HasPrefix(key, "lpi:"):
+			state[key] = encodeParticipantEntryV0(decodeParticipantEntry(value))
+		case strings.HasPrefix(key, "cs:"):
+			state[key] = encodeCreatorStatsV0(decodeCreatorStats(value))
+		}
+	}
+}
+
+// TestLegacyStateUpgradesOnWrite tests that a lottery stored entirely in version 0 keeps working
+// and that every record the contract touches is rewritten in the current version
+func TestLegacyStateUpgradesOnWrite(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Legacy|24|10|60,40|1.000|hive:charity|5", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	for _, who := range []string{"hive:alice", "hive:bob"} {
+		a.f.fund(who, 10_000)
+		a.mustCall(t, join_lottery, "1", who, transferAllow("2.000"))
+	}
+
+	downgradeState(a.f.state)
+
+	a.f.fund("hive:carol", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+	a.mustCall(t, join_lottery, "1", "hive:carol", transferAllow("3.000"))
+	a.f.at(fakeFuture)
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+
+	for _, key := range []string{"lm:1", "ls:1", "lpi:1:1", "lpi:1:3", "cs:hive:creator"} {
+		require.Contains(t, a.f.state, key)
+		assert.Equal(t, byte(codecVersion), a.f.state[key][0], key)
+	}
+	// Bob's entry was never written again and stays in version 0 until it is
+	assert.Equal(t, encodeParticipantEntryV0(&ParticipantEntry{Address: "hive:bob", Tickets: 2}), a.f.state["lpi:1:2"])
+	assert.Equal(t, uint64(8), loadLotteryPoolStats(1).TotalTickets)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+)
+
+// Sanity bounds for set_config, so a typo cannot configure lotteries that leave the winners
+// nothing or records that no longer fit into a transaction
+const (
+	maxConfigDeadlineHours   = 8760 // one year
+	maxConfigBurnAndDonation = 9500 // winners always keep at least 5% of the pool
+	maxConfigTicketPrice     = 1_000_000 * AmountScale
+	maxConfigNameLength      = 200
+	maxConfigMetadataLength  = 2000
+	maxConfigProtocolFee     = 1000 // 10%
+)
+
+// defaultConfig returns the limits in force until the contract owner calls set_config
+func defaultConfig() *Config {
+	return &Config{
+		MinBurnPercent:     500,
+		MaxBurnPercent:     7500,
+		MinDeadlineHours:   1,
+		MaxDeadlineHours:   2160,
+		MaxDonationPercent: 5000,
+		MaxBurnAndDonation: 9000,
+		MinTicketPrice:     1,
+		MaxNameLength:      100,
+		MaxMetadataLength:  500,
+
+		MaxCreatorFeePercent: 1000,
+	}
+}
+
+// validateConfig aborts if the limits contradict each other or leave the sanity bounds
+func validateConfig(c *Config) {
+	if c.MinBurnPercent > c.MaxBurnPercent {
+		sdk.Abort("min_burn_percent must not exceed max_burn_percent")
+	}
+	if c.MaxBurnAndDonation > maxConfigBurnAndDonation {
+		sdk.Abort("max_burn_donation_percent must be " + formatPercentLimit(maxConfigBurnAndDonation) + " or less")
+	}
+	if c.MaxBurnPercent > c.MaxBurnAndDonation {
+		sdk.Abort("max_burn_percent must not exceed max_burn_donation_percent")
+	}
+	if c.MaxDonationPercent > c.MaxBurnAndDonation {
+		sdk.Abort("max_donation_percent must not exceed max_burn_donation_percent")
+	}
+	if c.MaxCreatorFeePercent > c.MaxBurnAndDonation {
+		sdk.Abort("max_creator_fee_percent must not exceed max_burn_donation_percent")
+	}
+	if c.MinDeadlineHours < 1 {
+		sdk.Abort("min_deadline_hours must be at least 1")
+	}
+	if c.MinDeadlineHours > c.MaxDeadlineHours {
+		sdk.Abort("min_deadline_hours must not exceed max_deadline_hours")
+	}
+	if c.MaxDeadlineHours > maxConfigDeadlineHours {
+		sdk.Abort("max_deadline_hours must be " + strconv.Itoa(maxConfigDeadlineHours) + " or less")
+	}
+	if c.MinTicketPrice < 1 || c.MinTicketPrice > maxConfigTicketPrice {
+		sdk.Abort("min_ticket_price must be between 0.001 and " + events.Amount(maxConfigTicketPrice).String())
+	}
+	if c.MaxNameLength < 1 || c.MaxNameLength > maxConfigNameLength {
+		sdk.Abort("max_name_length must be between 1 and " + strconv.Itoa(maxConfigNameLength))
+	}
+	if c.MaxMetadataLength > maxConfigMetadataLength {
+		sdk.Abort("max_metadata_length must be " + strconv.Itoa(maxConfigMetadataLength) + " or less")
+	}
+	if c.ProtocolFee < 0 || c.ProtocolFee > maxConfigProtocolFee {
+		sdk.Abort("protocol_fee_percent must be " + formatPercentLimit(maxConfigProtocolFee) + " or less")
+	}
+	if c.MaxBurnAndDonation+c.ProtocolFee > maxConfigBurnAndDonation {
+		sdk.Abort("max_burn_donation_percent and protocol_fee_percent together must be " + formatPercentLimit(maxConfigBurnAndDonation) + " or less")
+	}
+}
+
+// configEvent builds the cc event for a configuration, the query result uses the same fields
+func configEvent(c *Config) *events.ConfigChanged {
+	return &events.ConfigChanged{
+		MinBurnPercent:         events.Percent(c.MinBurnPercent),
+		MaxBurnPercent:         events.Percent(c.MaxBurnPercent),
+		MinDeadlineHours:       c.MinDeadlineHours,
+		MaxDeadlineHours:       c.MaxDeadlineHours,
+		MaxDonationPercent:     events.Percent(c.MaxDonationPercent),
+		MaxBurnDonationPercent: events.Percent(c.MaxBurnAndDonation),
+		MinTicketPrice:         events.Amount(c.MinTicketPrice),
+		MaxNameLength:          c.MaxNameLength,
+		MaxMetadataLength:      c.MaxMetadataLength,
+		ProtocolFeePercent:     events.Percent(c.ProtocolFee),
+		MaxCreatorFeePercent:   events.Percent(c.MaxCreatorFeePercent),
+	}
+}
+
+// formatConfig renders the limits as key:value pairs in the order of the cc event, without its timestamp
+func formatConfig(c *Config) string {
+	fields := configEvent(c).Fields()
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.Key == "at" {
+			continue
+		}
+		parts = append(parts, f.Key+":"+f.Value)
+	}
+	return strings.Join(parts, "|")
+}
+
+// formatPercentLimit renders a limit for error messages without trailing zeros, e.g. 75 or 7.5
+func formatPercentLimit(bps BasisPoints) string {
+	s := events.Percent(bps).String()
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+//export set_config
+func set_config(payload *string) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "set_config payload missing")
+	cfg := parseSetConfig(payloadStr, format, loadConfig())
+	validateConfig(cfg)
+
+	saveConfig(cfg)
+	emitConfigChanged(cfg)
+
+	ret := formatConfig(cfg)
+	return &ret
+}
+
+//export get_config
+func get_config(payload *string) *string {
+	requireCurrentSchema()
+	ret := formatConfig(loadConfig())
+	return &ret
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultConfigResult is what get_config returns before the owner has set anything
+const defaultConfigResult = "min_burn_percent:5.00|max_burn_percent:75.00|min_deadline_hours:1|max_deadline_hours:2160|" +
+	"max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.001|max_name_length:100|max_metadata_length:500|protocol_fee_percent:0.00|max_creator_fee_percent:10.00"
+
+// TestSetConfigChangesLimits tests that new limits apply to the next lottery and are reported by the query and the cc event
+func TestSetConfigChangesLimits(t *testing.T) {
+	f := newFakeHost(t)
+	assert.Equal(t, defaultConfigResult, f.mustCall(t, get_config, "", "hive:anyone").Ret)
+	f.mustCall(t, create_lottery, "Long|2000|10|100|0.010", "hive:creator")
+
+	res := f.mustCall(t, set_config, "max_deadline_hours=720|min_ticket_price=0.100|max_burn_percent=50|max_name_length=10", fakeOwner)
+	want := "min_burn_percent:5.00|max_burn_percent:50.00|min_deadline_hours:1|max_deadline_hours:720|" +
+		"max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.100|max_name_length:10|max_metadata_length:500|protocol_fee_percent:0.00|max_creator_fee_percent:10.00"
+	assert.Equal(t, want, res.Ret)
+	assert.Equal(t, want, f.mustCall(t, get_config, "", "hive:anyone").Ret)
+
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 1)
+	cc := evs[0].(*events.ConfigChanged)
+	assert.Equal(t, uint64(720), cc.MaxDeadlineHours)
+	assert.Equal(t, events.Amount(100), cc.MinTicketPrice)
+	assert.Equal(t, nowUnix(), cc.At)
+
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"Long|2000|10|100|1.000", "deadline must be 720 hours or less"},
+		{"Cheap|24|10|100|0.010", "ticket price must be at least 0.100"},
+		{"Burn|24|60|100|1.000", "burn percent must be between 5 and 50"},
+		{"A long name|24|10|100|1.000", "lottery name must be 10 characters or less"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, create_lottery, tt.payload, "hive:creator").Err, tt.payload)
+	}
+	f.mustCall(t, create_lottery, "Short|720|50|100|0.100", "hive:creator")
+
+	// Lotteries created under the old limits keep running
+	f.fund("hive:alice", 10_000)
+	f.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("0.050"))
+
+	res = f.mustCall(t, set_config, `{"min_deadline_hours":2,"max_donation_percent":"7.5","max_metadata_length":0}`, fakeOwner)
+	assert.Contains(t, res.Ret, "min_deadline_hours:2|max_deadline_hours:720|max_donation_percent:7.50|")
+	assert.Equal(t, "deadline must be at least 2 hours", f.call(t, create_lottery, "Quick|1|10|100|1.000", "hive:creator").Err)
+	assert.Equal(t, "donation percent must be between 0 and 7.5", f.call(t, create_lottery, "Give|24|10|100|1.000|hive:charity|10", "hive:creator").Err)
+	assert.Equal(t, "metadata must be 0 characters or less", f.call(t, change_lottery_metadata, "1|x", "hive:creator").Err)
+}
+
+// TestSetConfigValidation tests owner, payload and sanity checks, none of which may touch the stored config
+func TestSetConfigValidation(t *testing.T) {
+	f := newFakeHost(t)
+
+	tests := []struct {
+		payload string
+		sender  string
+		err     string
+	}{
+		{"max_deadline_hours=720", "hive:creator", "only the contract owner can do this"},
+		{"", fakeOwner, "set_config payload missing"},
+		{"max_deadline_hours", fakeOwner, "invalid set_config payload format: expected key=value"},
+		{"max_tickets=5", fakeOwner, "unknown config key: max_tickets"},
+		{"max_name_length=5|max_name_length=6", fakeOwner, "duplicate config key: max_name_length"},
+		{"max_name_length=-1", fakeOwner, "invalid max_name_length"},
+		{"min_burn_percent=101", fakeOwner, "invalid min_burn_percent"},
+		{"min_burn_percent=1.234", fakeOwner, "invalid min_burn_percent"},
+		{"min_ticket_price=0.0001", fakeOwner, "invalid min_ticket_price"},
+		{"{}", fakeOwner, "at least one config value is required"},
+		{"min_burn_percent=80", fakeOwner, "min_burn_percent must not exceed max_burn_percent"},
+		{"max_burn_donation_percent=96", fakeOwner, "max_burn_donation_percent must be 95 or less"},
+		{"max_burn_donation_percent=70", fakeOwner, "max_burn_percent must not exceed max_burn_donation_percent"},
+		{"max_burn_donation_percent=80|max_burn_percent=80|max_donation_percent=81", fakeOwner, "max_donation_percent must not exceed max_burn_donation_percent"},
+		{"min_deadline_hours=0", fakeOwner, "min_deadline_hours must be at least 1"},
+		{"min_deadline_hours=3000", fakeOwner, "min_deadline_hours must not exceed max_deadline_hours"},
+		{"max_deadline_hours=9000", fakeOwner, "max_deadline_hours must be 8760 or less"},
+		{"min_ticket_price=0", fakeOwner, "min_ticket_price must be between 0.001 and 1000000.000"},
+		{"max_name_length=0", fakeOwner, "max_name_length must be between 1 and 200"},
+		{"max_metadata_length=2001", fakeOwner, "max_metadata_length must be 2000 or less"},
+		{"protocol_fee_percent=10.01", fakeOwner, "protocol_fee_percent must be 10 or less"},
+		{"protocol_fee_percent=250", fakeOwner, "invalid protocol_fee_percent"},
+		{"protocol_fee_percent=2.505", fakeOwner, "invalid protocol_fee_percent"},
+		{"protocol_fee_percent=6", fakeOwner, "max_burn_donation_percent and protocol_fee_percent together must be 95 or less"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, set_config, tt.payload, tt.sender).Err, tt.payload)
+	}
+	assert.True(t, strings.HasPrefix(f.call(t, set_config, `{"max_name_length":"5"}`, fakeOwner).Err, "invalid set_config JSON payload: "))
+	assert.NotContains(t, f.state, getConfigKey())
+}
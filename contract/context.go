@@ -6,25 +6,25 @@ import (
 	"time"
 )
 
-// cachedEnv/cachedTransfer are scoped to the currently executing transaction.
-// Whenever the tx.id changes we refresh sdk.GetEnv() and drop any memoized data to keep reads consistent.
+// cachedEnv/cachedTransfers are scoped to the currently executing transaction.
+// Whenever the tx.id changes we refresh host.Env() and drop any memoized data to keep reads consistent.
 var (
 	cachedEnv       sdk.Env
 	cachedEnvLoaded bool
-	cachedTransfer  *TransferAllow
+	cachedTransfers []TransferAllow // nil until parsed, empty if the tx carries no allowance
 )
 
 // currentEnv caches the env per tx.id so we dont poke the host api every few lines and ensures
 // subsequent helper calls (intents, sender, timestamps) always see the same snapshot.
 func currentEnv() *sdk.Env {
 	var currentTx string
-	if txPtr := sdk.GetEnvKey("tx.id"); txPtr != nil {
+	if txPtr := host.EnvKey("tx.id"); txPtr != nil {
 		currentTx = *txPtr
 	}
 	if !cachedEnvLoaded || cachedEnv.TxId != currentTx {
-		cachedEnv = sdk.GetEnv()
+		cachedEnv = host.Env()
 		cachedEnvLoaded = true
-		cachedTransfer = nil
+		cachedTransfers = nil
 	}
 	return &cachedEnv
 }
@@ -37,7 +37,7 @@ func currentIntents() []sdk.Intent {
 // TransferAllow represents arguments extracted from a transfer.allow intent.
 // It specifies the allowed transfer amount (`Limit`) and the asset (`Token`).
 type TransferAllow struct {
-	Limit float64
+	Limit Amount
 	Token sdk.Asset
 }
 
@@ -54,30 +54,49 @@ func isValidAsset(token string) bool {
 	return false
 }
 
-// getFirstTransferAllow scans the provided intents and returns the first valid
-// transfer.allow intent as a TransferAllow object. The cached result is cleared automatically
-// whenever currentEnv() detects a new transaction so tests do not leak state between calls.
-func getFirstTransferAllow() *TransferAllow {
-	if cachedTransfer != nil {
-		return cachedTransfer
+// getTransferAllows parses every transfer.allow intent of the transaction, one per asset.
+// Allowances for assets the contract does not handle are skipped, they may be meant for another
+// contract in the same transaction. A malformed limit or two allowances for the same asset abort.
+// The result is cached until currentEnv() detects a new transaction.
+func getTransferAllows() []TransferAllow {
+	// Read the intents first, currentEnv() drops the cached transfers of a previous transaction
+	intents := currentIntents()
+	if cachedTransfers != nil {
+		return cachedTransfers
 	}
-	for _, intent := range currentIntents() {
-		if intent.Type == "transfer.allow" {
-			token := intent.Args["token"]
-			if !isValidAsset(token) {
-				sdk.Abort("invalid intent asset")
-			}
-			limitStr := intent.Args["limit"]
-			limit, err := strconv.ParseFloat(limitStr, 32)
-			if err != nil {
-				sdk.Abort("invalid intent limit")
-			}
-			ta := &TransferAllow{
-				Limit: limit,
-				Token: sdk.Asset(token),
+	transfers := make([]TransferAllow, 0, len(intents))
+	for _, intent := range intents {
+		if intent.Type != "transfer.allow" {
+			continue
+		}
+		token := intent.Args["token"]
+		if !isValidAsset(token) {
+			continue
+		}
+		for _, t := range transfers {
+			if t.Token.String() == token {
+				sdk.Abort("multiple transfer.allow intents for " + token)
 			}
-			cachedTransfer = ta
-			return ta
+		}
+		limit, err := ParseAmount(intent.Args["limit"], sdk.Asset(token))
+		if err != nil {
+			sdk.Abort("invalid intent limit: " + err.Error())
+		}
+		transfers = append(transfers, TransferAllow{
+			Limit: limit,
+			Token: sdk.Asset(token),
+		})
+	}
+	cachedTransfers = transfers
+	return transfers
+}
+
+// getTransferAllow returns the transaction's allowance for the given asset, nil if there is none
+func getTransferAllow(asset sdk.Asset) *TransferAllow {
+	transfers := getTransferAllows()
+	for i := range transfers {
+		if transfers[i].Token.String() == asset.String() {
+			return &transfers[i]
 		}
 	}
 	return nil
@@ -88,6 +107,19 @@ func getSenderAddress() sdk.Address {
 	return currentEnv().Sender.Address
 }
 
+// requireContractOwner aborts unless the transaction is sent by the contract owner.
+func requireContractOwner() {
+	owner := currentEnv().ContractOwner
+	if owner == "" {
+		if ownerPtr := host.EnvKey("contract.owner"); ownerPtr != nil {
+			owner = *ownerPtr
+		}
+	}
+	if owner == "" || getSenderAddress().String() != owner {
+		sdk.Abort("only the contract owner can do this")
+	}
+}
+
 // nowUnix returns the current Unix timestamp.
 // It prefers the chain's block timestamp from the environment if available.
 func nowUnix() int64 {
@@ -96,7 +128,7 @@ func nowUnix() int64 {
 			return v
 		}
 	}
-	if tsPtr := sdk.GetEnvKey("block.timestamp"); tsPtr != nil && *tsPtr != "" {
+	if tsPtr := host.EnvKey("block.timestamp"); tsPtr != nil && *tsPtr != "" {
 		if v, ok := parseTimestamp(*tsPtr); ok {
 			return v
 		}
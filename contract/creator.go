@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/events"
+)
+
+// maxCreatorLotteriesPage caps how many IDs a single get_creator_lotteries call returns
+const maxCreatorLotteriesPage = 100
+
+// fillRateBps returns how full a capped lottery is in basis points (0-10000)
+func fillRateBps(sold uint64, maxTickets uint64) uint64 {
+	if maxTickets == 0 {
+		return 0
+	}
+	return sold * 10000 / maxTickets
+}
+
+// recordCreatorLottery registers a freshly created lottery in its creator's aggregates and ID list
+func recordCreatorLottery(l *Lottery) {
+	creator := l.Creator.String()
+	stats := loadCreatorStats(creator)
+
+	stats.LotteryCount++
+	stats.ActiveCount++
+	if l.MaxTickets > 0 {
+		stats.CappedCount++
+	}
+	saveCreatorLotteryID(creator, stats.LotteryCount, l.ID)
+	saveCreatorStats(creator, stats)
+}
+
+// recordCreatorJoin adds a ticket purchase to the creator's volume and fill rate.
+// The fill rate is tracked as the difference between the truncated rates before and after
+// the purchase so the running sum always equals the sum of the lotteries' current rates.
+func recordCreatorJoin(meta *LotteryMetadata, ticketsBefore uint64, ticketCount uint64, cost Amount) {
+	creator := meta.Creator.String()
+	stats := loadCreatorStats(creator)
+
+	stats.TotalVolume += cost
+	if meta.MaxTickets > 0 {
+		before := fillRateBps(ticketsBefore, meta.MaxTickets)
+		after := fillRateBps(ticketsBefore+ticketCount, meta.MaxTickets)
+		stats.FillRateSum += after - before
+	}
+	saveCreatorStats(creator, stats)
+}
+
+// recordCreatorExecution moves an executed lottery out of the creator's active count
+func recordCreatorExecution(l *Lottery) {
+	creator := l.Creator.String()
+	stats := loadCreatorStats(creator)
+
+	if stats.ActiveCount > 0 {
+		stats.ActiveCount--
+	}
+	stats.TotalDonated += l.DonatedAmount
+	saveCreatorStats(creator, stats)
+}
+
+// rebuildCreatorLottery adds a lottery, as it stands now, to its creator's aggregates and ID list.
+// migrate calls it for every lottery in ID order, which rebuilds the aggregates from the lottery
+// records, so lotteries created before the aggregates were kept are counted as well. The first
+// lottery migrate reaches for a creator resets their records: their list does not start with an
+// earlier lottery yet, as lotteries recorded at creation always have higher IDs than unrecorded ones.
+func rebuildCreatorLottery(meta *LotteryMetadata, pool *LotteryPoolStats) {
+	creator := meta.Creator.String()
+	stats := loadCreatorStats(creator)
+	if stats.LotteryCount == 0 || loadCreatorLotteryID(creator, 1) >= meta.ID {
+		stats = &CreatorStats{}
+	}
+
+	stats.LotteryCount++
+	stats.TotalVolume += pool.Pool
+	if meta.MaxTickets > 0 {
+		stats.CappedCount++
+		stats.FillRateSum += fillRateBps(pool.TotalTickets, meta.MaxTickets)
+	}
+	if meta.State == LotteryStateExecuted {
+		stats.TotalDonated += meta.DonatedAmount
+	} else {
+		stats.ActiveCount++
+	}
+	saveCreatorLotteryID(creator, stats.LotteryCount, meta.ID)
+	saveCreatorStats(creator, stats)
+}
+
+//export get_creator_stats
+func get_creator_stats(payload *string) *string {
+	requireCurrentSchema()
+	payloadStr, _ := unwrapPayload(payload, "get_creator_stats payload missing")
+	creator := parseGetCreatorStats(payloadStr)
+
+	stats := loadCreatorStats(creator.String())
+
+	// Average fill rate over capped lotteries, in percent with two decimals
+	avgFill := uint64(0)
+	if stats.CappedCount > 0 {
+		avgFill = stats.FillRateSum / stats.CappedCount
+	}
+
+	ret := "creator:" + creator.String() +
+		"|lotteries:" + strconv.FormatUint(stats.LotteryCount, 10) +
+		"|active:" + strconv.FormatUint(stats.ActiveCount, 10) +
+		"|volume:" + events.Amount(stats.TotalVolume).String() +
+		"|donated:" + events.Amount(stats.TotalDonated).String() +
+		"|capped:" + strconv.FormatUint(stats.CappedCount, 10) +
+		"|avg_fill:" + events.Percent(avgFill).String()
+	return &ret
+}
+
+//export get_creator_lotteries
+func get_creator_lotteries(payload *string) *string {
+	requireCurrentSchema()
+	payloadStr, _ := unwrapPayload(payload, "get_creator_lotteries payload missing")
+	args := parseGetCreatorLotteries(payloadStr)
+
+	creator := args.Creator.String()
+	stats := loadCreatorStats(creator)
+
+	var ids strings.Builder
+	written := uint64(0)
+	for n := args.Offset + 1; n <= stats.LotteryCount && written < args.Limit; n++ {
+		id := loadCreatorLotteryID(creator, n)
+		if id == 0 {
+			continue
+		}
+		if written > 0 {
+			ids.WriteString(",")
+		}
+		ids.WriteString(strconv.FormatUint(id, 10))
+		written++
+	}
+
+	ret := "total:" + strconv.FormatUint(stats.LotteryCount, 10) + "|ids:" + ids.String()
+	return &ret
+}
@@ -1,142 +1,222 @@
 package main
 
 import (
-	"fmt"
+	"encoding/hex"
+
+	"okinoko_lottery/events"
 	"okinoko_lottery/sdk"
-	"strconv"
-	"strings"
 )
 
+// Events are built and formatted by the okinoko_lottery/events package so indexers can parse
+// exactly what is emitted here. Every event is logged as a versioned, escaped v2 line; during the
+// compatibility period the legacy line (same fields, no version marker, no escaping) is logged
+// right before it so existing indexers keep working. The grammar is documented in the README.
+
+// emitLegacyEvents keeps emitting unescaped legacy lines next to the v2 lines.
+// Switch off once indexers have moved to the v2 format.
+const emitLegacyEvents = true
+
+// emitEvent logs an event in the v2 format and, during the compatibility period, the legacy format
+func emitEvent(ev events.Event) {
+	if emitLegacyEvents {
+		host.Log(events.FormatLegacy(ev))
+	}
+	host.Log(events.Format(ev))
+}
+
 // emitLotteryCreated logs a lottery creation event
 func emitLotteryCreated(l *Lottery) {
-	// Format: lc|id:<id>|creator:<creator>|name:<name>|created_at:<unix>|deadline:<unix>|burn:<percent>|ticket:<price>|asset:<asset>|winners:<count>|shares:<csv>|donation_account:<account>|donation_percent:<percent>
+	// Format: lc|v:2|id:<id>|creator:<creator>|name:<name>|created_at:<unix>|deadline:<unix>|burn:<percent>|ticket:<price>|asset:<asset>|winners:<count>|shares:<csv>|donation_account:<csv>|donation_percent:<csv>|protocol_fee:<percent>|creator_fee:<percent>|charity_id:<csv>|verified:true
 
-	var winnerShares strings.Builder
+	shares := make([]events.Percent, len(l.WinnerShares))
 	for i, share := range l.WinnerShares {
-		if i > 0 {
-			winnerShares.WriteString(",")
-		}
-		winnerShares.WriteString(strconv.FormatFloat(share, 'f', 2, 64))
+		shares[i] = events.Percent(share)
 	}
 
-	// Build event with optional donation info
-	event := fmt.Sprintf(
-		"lc|id:%d|creator:%s|name:%s|created_at:%d|deadline:%d|burn:%.2f|ticket:%.3f|asset:%s|winners:%d|shares:%s",
-		l.ID,
-		l.Creator.String(),
-		l.Name,
-		l.CreatedAt,
-		l.DeadlineUnix,
-		l.BurnPercent,
-		AmountToFloat(l.TicketPrice),
-		l.Asset.String(),
-		len(l.WinnerShares),
-		winnerShares.String(),
-	)
-
-	// Add donation info if configured
-	if l.DonationPercent > 0.0 && l.DonationAccount.String() != "" {
-		event += fmt.Sprintf("|donation_account:%s|donation_percent:%.2f", l.DonationAccount.String(), l.DonationPercent)
+	ev := &events.Created{
+		ID:        l.ID,
+		Creator:   l.Creator.String(),
+		Name:      l.Name,
+		CreatedAt: l.CreatedAt,
+		Deadline:  l.DeadlineUnix,
+		Burn:      events.Percent(l.BurnPercent),
+		Ticket:    events.Amount(l.TicketPrice),
+		Asset:     l.Asset.String(),
+		Winners:   uint64(len(l.WinnerShares)),
+		Shares:    shares,
 	}
 
-	sdk.Log(event)
+	// Add donation info if configured, unregistered recipients have to accept before tickets are sold
+	for _, d := range l.Donations {
+		if d.Percent > 0 {
+			ev.Donations = append(ev.Donations, events.DonationShare{Account: d.Account.String(), Percent: events.Percent(d.Percent), CharityID: d.CharityID})
+		}
+	}
+	ev.OptIn = len(ev.Donations) > 0
+	ev.Verified = verifiedCharity(l.Donations)
+	ev.ProtocolFee = events.Percent(l.ProtocolFeePercent)
+	ev.CreatorFee = events.Percent(l.CreatorFeePercent)
+
+	emitEvent(ev)
 }
 
 // emitLotteryMetadataChanged logs a lottery metadata change event
 func emitLotteryMetadataChanged(lotteryID uint64, metadata string) {
-	// Format: lm|id:<id>|metadata:<metadata>
+	// Format: lm|v:2|id:<id>|metadata:<metadata>
 
-	event := fmt.Sprintf(
-		"lm|id:%d|metadata:%s",
-		lotteryID,
-		metadata,
-	)
-
-	sdk.Log(event)
+	emitEvent(&events.MetadataChanged{
+		ID:       lotteryID,
+		Metadata: metadata,
+	})
 }
 
 // emitLotteryJoined logs a lottery join event
 func emitLotteryJoined(lotteryID uint64, participant sdk.Address, ticketCount uint64, totalPaid Amount, asset sdk.Asset, ticketStart uint64, ticketEnd uint64) {
-	// Format: lj|id:<id>|participant:<address>|tickets:<count>|paid:<amount>|asset:<asset>|ticket_start:<start>|ticket_end:<end>
-
-	event := fmt.Sprintf(
-		"lj|id:%d|participant:%s|tickets:%d|paid:%.3f|asset:%s|ticket_start:%d|ticket_end:%d",
-		lotteryID,
-		participant.String(),
-		ticketCount,
-		AmountToFloat(totalPaid),
-		asset.String(),
-		ticketStart,
-		ticketEnd,
-	)
-
-	sdk.Log(event)
+	// Format: lj|v:2|id:<id>|participant:<address>|tickets:<count>|paid:<amount>|asset:<asset>|ticket_start:<start>|ticket_end:<end>
+
+	emitEvent(&events.Joined{
+		ID:          lotteryID,
+		Participant: participant.String(),
+		Tickets:     ticketCount,
+		Paid:        events.Amount(totalPaid),
+		Asset:       asset.String(),
+		TicketStart: ticketStart,
+		TicketEnd:   ticketEnd,
+	})
 }
 
 // emitLotteryExecuted logs a lottery execution event
 func emitLotteryExecuted(l *Lottery, participantCount uint64) {
-	// Format: le|id:<id>|pool:%.3f|burned:%.3f|donated:%.3f|asset:<asset>|winners:<count>|seed:<seed>|tickets:<total>|participants:<count>|executed_at:<unix>
-
-	event := fmt.Sprintf(
-		"le|id:%d|pool:%.3f|burned:%.3f|donated:%.3f|asset:%s|winners:%d|seed:%d|tickets:%d|participants:%d|executed_at:%d",
-		l.ID,
-		AmountToFloat(l.Pool),
-		AmountToFloat(l.BurnedAmount),
-		AmountToFloat(l.DonatedAmount),
-		l.Asset.String(),
-		len(l.Winners),
-		l.RandomSeed,
-		l.TotalTickets,
-		participantCount,
-		l.ExecutedAt,
-	)
-
-	sdk.Log(event)
+	// Format: le|v:2|id:<id>|pool:<amount>|burned:<amount>|donated:<amount>|asset:<asset>|winners:<count>|seed:<seed>|tickets:<total>|participants:<count>|executed_at:<unix>|tickets_root:<hex>[|protocol_fee:<amount>][|creator_fee:<amount>]
+
+	emitEvent(&events.Executed{
+		ID:           l.ID,
+		Pool:         events.Amount(l.Pool),
+		Burned:       events.Amount(l.BurnedAmount),
+		Donated:      events.Amount(l.DonatedAmount),
+		Asset:        l.Asset.String(),
+		Winners:      uint64(len(l.Winners)),
+		Seed:         l.RandomSeed,
+		Tickets:      l.TotalTickets,
+		Participants: participantCount,
+		ExecutedAt:   l.ExecutedAt,
+		TicketsRoot:  hex.EncodeToString([]byte(l.TicketsRoot)),
+		ProtocolFee:  events.Amount(l.ProtocolFeeAmount),
+		CreatorFee:   events.Amount(l.CreatorFeeAmount),
+	})
 }
 
 // emitLotteryPayout logs a winner payout event
-func emitLotteryPayout(lotteryID uint64, winner sdk.Address, amount Amount, share float64, asset sdk.Asset, position int) {
-	// Format: lp|id:<id>|winner:<address>|amount:<amount>|share:<percent>|asset:<asset>|position:<n>
-
-	event := fmt.Sprintf(
-		"lp|id:%d|winner:%s|amount:%.3f|share:%.2f|asset:%s|position:%d",
-		lotteryID,
-		winner.String(),
-		AmountToFloat(amount),
-		share,
-		asset.String(),
-		position,
-	)
-
-	sdk.Log(event)
+func emitLotteryPayout(lotteryID uint64, winner sdk.Address, amount Amount, share BasisPoints, asset sdk.Asset, position int) {
+	// Format: lp|v:2|id:<id>|winner:<address>|amount:<amount>|share:<percent>|asset:<asset>|position:<n>
+
+	emitEvent(&events.Payout{
+		ID:       lotteryID,
+		Winner:   winner.String(),
+		Amount:   events.Amount(amount),
+		Share:    events.Percent(share),
+		Asset:    asset.String(),
+		Position: uint64(position),
+	})
 }
 
 // emitLotteryDonation logs a donation payout event
-func emitLotteryDonation(lotteryID uint64, recipient sdk.Address, amount Amount, percent float64, asset sdk.Asset) {
-	// Format: ld|id:<id>|recipient:<address>|amount:<amount>|percent:<percent>|asset:<asset>
-
-	event := fmt.Sprintf(
-		"ld|id:%d|recipient:%s|amount:%.3f|percent:%.2f|asset:%s",
-		lotteryID,
-		recipient.String(),
-		AmountToFloat(amount),
-		percent,
-		asset.String(),
-	)
-
-	sdk.Log(event)
+func emitLotteryDonation(lotteryID uint64, recipient sdk.Address, amount Amount, percent BasisPoints, asset sdk.Asset) {
+	// Format: ld|v:2|id:<id>|recipient:<address>|amount:<amount>|percent:<percent>|asset:<asset>
+
+	emitEvent(&events.Donation{
+		ID:        lotteryID,
+		Recipient: recipient.String(),
+		Amount:    events.Amount(amount),
+		Percent:   events.Percent(percent),
+		Asset:     asset.String(),
+	})
 }
 
 // emitLotteryUndistributed logs when undistributed funds are sent to null
 func emitLotteryUndistributed(lotteryID uint64, amount Amount, asset sdk.Asset) {
-	// Format: lu|id:<id>|amount:<amount>|asset:<asset>
+	// Format: lu|v:2|id:<id>|amount:<amount>|asset:<asset>
+
+	emitEvent(&events.Undistributed{
+		ID:     lotteryID,
+		Amount: events.Amount(amount),
+		Asset:  asset.String(),
+	})
+}
+
+// emitPauseChanged logs the actions a pause or unpause call toggled
+func emitPauseChanged(actions []string, paused bool) {
+	// Format: cp|v:2|actions:<csv>|paused:<true|false>|at:<unix>
+
+	emitEvent(&events.PauseChanged{
+		Actions: actions,
+		Paused:  paused,
+		At:      nowUnix(),
+	})
+}
+
+// emitConfigChanged logs the protocol limits after a set_config call
+func emitConfigChanged(c *Config) {
+	// Format: cc|v:2|min_burn_percent:<percent>|max_burn_percent:<percent>|min_deadline_hours:<hours>|max_deadline_hours:<hours>|max_donation_percent:<percent>|max_burn_donation_percent:<percent>|min_ticket_price:<amount>|max_name_length:<chars>|max_metadata_length:<chars>|at:<unix>|protocol_fee_percent:<percent>|max_creator_fee_percent:<percent>
+
+	ev := configEvent(c)
+	ev.At = nowUnix()
+	emitEvent(ev)
+}
+
+// emitTreasuryWithdrawn logs a withdrawal of collected protocol fees
+func emitTreasuryWithdrawn(asset sdk.Asset, amount Amount, recipient sdk.Address, balance Amount) {
+	// Format: tw|v:2|asset:<asset>|amount:<amount>|recipient:<address>|balance:<amount>
+
+	emitEvent(&events.TreasuryWithdrawn{
+		Asset:     asset.String(),
+		Amount:    events.Amount(amount),
+		Recipient: recipient.String(),
+		Balance:   events.Amount(balance),
+	})
+}
+
+// emitLotteryArchived logs the completion of a lottery's participant archive
+func emitLotteryArchived(lotteryID uint64, participants uint64, hash string) {
+	// Format: la|v:2|id:<id>|participants:<count>|hash:<hex>
+
+	emitEvent(&events.Archived{
+		ID:           lotteryID,
+		Participants: participants,
+		Hash:         hash,
+	})
+}
+
+// emitDonationAccepted logs a donation account accepting a lottery's donation
+func emitDonationAccepted(lotteryID uint64, account sdk.Address) {
+	// Format: lo|v:2|id:<id>|account:<address>
+
+	emitEvent(&events.DonationAccepted{
+		ID:      lotteryID,
+		Account: account.String(),
+	})
+}
+
+// emitCharityRegistered logs a charity added to the registry
+func emitCharityRegistered(c *Charity) {
+	// Format: cr|v:2|id:<id>|address:<address>|name:<name>|url:<url>|at:<unix>
+
+	emitEvent(&events.CharityRegistered{
+		ID:      c.ID,
+		Address: c.Address.String(),
+		Name:    c.Name,
+		URL:     c.URL,
+		At:      nowUnix(),
+	})
+}
 
-	event := fmt.Sprintf(
-		"lu|id:%d|amount:%.3f|asset:%s",
-		lotteryID,
-		AmountToFloat(amount),
-		asset.String(),
-	)
+// emitCharityRemoved logs a charity removed from the registry
+func emitCharityRemoved(c *Charity) {
+	// Format: cd|v:2|id:<id>|address:<address>|at:<unix>
 
-	sdk.Log(event)
+	emitEvent(&events.CharityRemoved{
+		ID:      c.ID,
+		Address: c.Address.String(),
+		At:      nowUnix(),
+	})
 }
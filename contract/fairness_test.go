@@ -0,0 +1,204 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// Statistical fairness checks for the draw. Every check runs selectRandomWinners over a fixed
+// range of seeds, so results are deterministic: a failure means random.go changed in a way
+// that biases results, not bad luck. The thresholds correspond to a significance level of
+// 0.001, which a correct implementation passes for all but a handful of seed ranges.
+
+// fairnessDraws is the number of seeds per distribution
+func fairnessDraws() int {
+	if testing.Short() {
+		return 4000
+	}
+	return 20000
+}
+
+// chiSquareCritical approximates the chi-square critical value at significance 0.001 with
+// the Wilson-Hilferty transformation, accurate to a few percent from 1 degree of freedom up
+func chiSquareCritical(df int) float64 {
+	const z = 3.0902 // standard normal quantile for 0.999
+	k := float64(df)
+	h := 2.0 / (9.0 * k)
+	return k * math.Pow(1-h+z*math.Sqrt(h), 3)
+}
+
+// chiSquare returns the chi-square statistic of observed counts against expected probabilities
+func chiSquare(observed []int, expected []float64, n int) float64 {
+	stat := 0.0
+	for i, p := range expected {
+		e := p * float64(n)
+		d := float64(observed[i]) - e
+		stat += d * d / e
+	}
+	return stat
+}
+
+// expectedPositions returns, for every prize position, the probability of each participant
+// winning it. Taking the first distinct addresses of a uniformly shuffled ticket pool is
+// sampling without replacement proportional to tickets: position k goes to participant i with
+// probability t_i / (tickets not yet won) given the winners of the earlier positions.
+func expectedPositions(tickets []uint64, positions int) [][]float64 {
+	probs := make([][]float64, positions)
+	for k := range probs {
+		probs[k] = make([]float64, len(tickets))
+	}
+	total := uint64(0)
+	for _, t := range tickets {
+		total += t
+	}
+
+	won := make([]bool, len(tickets))
+	var walk func(pos int, remaining uint64, p float64)
+	walk = func(pos int, remaining uint64, p float64) {
+		if pos == positions || remaining == 0 {
+			return
+		}
+		for i, t := range tickets {
+			if won[i] {
+				continue
+			}
+			q := p * float64(t) / float64(remaining)
+			probs[pos][i] += q
+			won[i] = true
+			walk(pos+1, remaining-t, q)
+			won[i] = false
+		}
+	}
+	walk(0, total, 1)
+	return probs
+}
+
+// drawCounts runs the draw over seeds 1..draws and counts, per position, how often each participant won it
+func drawCounts(t *testing.T, tickets []uint64, positions int, draws int) [][]int {
+	participants := make([]ParticipantEntry, len(tickets))
+	index := make(map[string]int, len(tickets))
+	total := uint64(0)
+	for i, n := range tickets {
+		participants[i] = ParticipantEntry{Address: "hive:p" + strconv.Itoa(i), Tickets: n}
+		index[participants[i].Address] = i
+		total += n
+	}
+
+	counts := make([][]int, positions)
+	for k := range counts {
+		counts[k] = make([]int, len(tickets))
+	}
+	wantWinners := min(positions, len(tickets))
+	for seed := 1; seed <= draws; seed++ {
+		winners := selectRandomWinners(participants, total, positions, uint64(seed))
+		if len(winners) != wantWinners {
+			t.Fatalf("seed %d: %d winners, want %d", seed, len(winners), wantWinners)
+		}
+		seen := make(map[string]bool, len(winners))
+		for k, w := range winners {
+			if seen[w.String()] {
+				t.Fatalf("seed %d: %s won twice", seed, w)
+			}
+			seen[w.String()] = true
+			counts[k][index[w.String()]]++
+		}
+	}
+	return counts
+}
+
+// TestWinnerFrequencyMatchesTicketShare applies a chi-square goodness-of-fit test to every
+// prize position for several ticket distributions
+func TestWinnerFrequencyMatchesTicketShare(t *testing.T) {
+	distributions := []struct {
+		name      string
+		tickets   []uint64
+		positions int
+	}{
+		{"equal single winner", []uint64{1, 1, 1, 1, 1}, 1},
+		{"skewed three winners", []uint64{1, 2, 5, 10, 32}, 3},
+		{"whale two winners", []uint64{3, 97}, 2},
+		{"many small holders", []uint64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, 5},
+		{"mixed with repeat buyers", []uint64{4, 4, 1, 7, 2, 2, 9, 1}, 4},
+	}
+
+	draws := fairnessDraws()
+	for _, d := range distributions {
+		t.Run(d.name, func(t *testing.T) {
+			counts := drawCounts(t, d.tickets, d.positions, draws)
+			expected := expectedPositions(d.tickets, d.positions)
+
+			for k := range counts {
+				stat := chiSquare(counts[k], expected[k], draws)
+				critical := chiSquareCritical(len(d.tickets) - 1)
+				if stat > critical {
+					t.Errorf("position %d: chi-square %.2f exceeds %.2f (observed %v, expected shares %v)", k+1, stat, critical, counts[k], expected[k])
+				}
+			}
+		})
+	}
+}
+
+// TestSmallHolderWinRate applies a binomial check to the smallest holder, whose share is the
+// easiest to lose through an off-by-one in the pool or the shuffle
+func TestSmallHolderWinRate(t *testing.T) {
+	tickets := []uint64{1, 49, 50}
+	draws := fairnessDraws()
+	counts := drawCounts(t, tickets, 1, draws)
+
+	p := 1.0 / 100.0
+	mean := p * float64(draws)
+	sd := math.Sqrt(float64(draws) * p * (1 - p))
+	if got := float64(counts[0][0]); math.Abs(got-mean) > 4*sd {
+		t.Errorf("1-ticket holder won %v of %d draws, expected %.1f ± %.1f", got, draws, mean, 4*sd)
+	}
+}
+
+// TestIntnUniform checks hashRandom.intn for uniformity, including bounds that do not divide 2^64
+func TestIntnUniform(t *testing.T) {
+	draws := 5 * fairnessDraws()
+	for _, n := range []int{2, 3, 7, 10, 61} {
+		rng := newHashRandom(uint64(n))
+		counts := make([]int, n)
+		for i := 0; i < draws; i++ {
+			counts[rng.intn(n)]++
+		}
+		expected := make([]float64, n)
+		for i := range expected {
+			expected[i] = 1 / float64(n)
+		}
+		if stat, critical := chiSquare(counts, expected, draws), chiSquareCritical(n-1); stat > critical {
+			t.Errorf("intn(%d): chi-square %.2f exceeds %.2f", n, stat, critical)
+		}
+	}
+}
+
+// TestFairnessSuiteDetectsBias makes sure the checks have teeth: a draw that ignores ticket
+// weights must fail the same chi-square test that selectRandomWinners passes
+func TestFairnessSuiteDetectsBias(t *testing.T) {
+	tickets := []uint64{1, 2, 5, 10, 32}
+	draws := fairnessDraws()
+
+	counts := make([]int, len(tickets))
+	for seed := 1; seed <= draws; seed++ {
+		counts[newHashRandom(uint64(seed)).intn(len(tickets))]++
+	}
+	expected := expectedPositions(tickets, 1)[0]
+	if stat := chiSquare(counts, expected, draws); stat <= chiSquareCritical(len(tickets)-1) {
+		t.Errorf("unweighted draw passed the fairness check (chi-square %.2f)", stat)
+	}
+}
+
+// TestExpectedPositions pins the reference probabilities used above to a hand-computed case
+func TestExpectedPositions(t *testing.T) {
+	// tickets 1, 3: second place goes to the 1-ticket holder whenever the 3-ticket holder wins first
+	probs := expectedPositions([]uint64{1, 3}, 2)
+	want := [][]float64{{0.25, 0.75}, {0.75, 0.25}}
+	for k := range want {
+		for i := range want[k] {
+			if math.Abs(probs[k][i]-want[k][i]) > 1e-12 {
+				t.Fatalf("position %d participant %d: got %v, want %v", k+1, i, probs[k][i], want[k][i])
+			}
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+)
+
+// Fuzz targets for everything that reads untrusted input. Run one with e.g.
+//
+//	go test -run x -fuzz FuzzParseCreateLottery ./contract
+//
+// Without -fuzz the seed corpus runs as part of the normal test suite.
+
+// catchAbort runs fn and returns the sdk.Abort message, "" if fn returned normally.
+// Any other panic is a bug and propagates to fail the fuzz run.
+func catchAbort(fn func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			aborted, ok := r.(sdk.Aborted)
+			if !ok {
+				panic(r)
+			}
+			msg = aborted.Msg
+		}
+	}()
+	fn()
+	return ""
+}
+
+// seedPayloads returns the payload string literals of the integration tests: every literal
+// that contains a field separator or a JSON object, or is a bare number like a lottery ID
+func seedPayloads(tb testing.TB) []string {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "test", "*_test.go"))
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("no integration tests found for the seed corpus: %v", err)
+	}
+
+	seen := map[string]bool{}
+	var payloads []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		ast.Inspect(parsed, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			v, err := strconv.Unquote(lit.Value)
+			if err != nil || seen[v] {
+				return true
+			}
+			if _, numErr := strconv.ParseUint(v, 10, 64); strings.Contains(v, "|") || strings.HasPrefix(v, "{") || numErr == nil {
+				seen[v] = true
+				payloads = append(payloads, v)
+			}
+			return true
+		})
+	}
+	return payloads
+}
+
+// addPayloadSeeds adds the integration test payloads plus raw and quoted JSON forms
+func addPayloadSeeds(f *testing.F) {
+	for _, p := range seedPayloads(f) {
+		f.Add(p)
+	}
+	f.Add(`{"name":"Fuzz","deadline_hours":24,"burn_percent":"10","winner_shares":["50","50"],"ticket_price":"1.000","metadata":"a|b","max_tickets":5}`)
+	f.Add(`"{\"lottery_id\":1,\"metadata\":\"x\"}"`)
+	f.Add(`'1|meta'`)
+	f.Add(`"`)
+	f.Add("NaN Burn|24|NaN|100|1.000")
+	f.Add("Inf Price|24|10|100|Inf")
+	f.Add("NaN Donation|24|10|100|1.000|hive:charity|NaN")
+	f.Add("Causes|24|10|100|1.000|donations=hive:a=5,did:key:z6Mk=2.5|creator_fee=1")
+	f.Add("Hook|24|10|100|1.000|donations=contract:dao=5,contract:=1,system:fees=1")
+	f.Add(`{"name":"Causes","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donations":[{"account":"hive:a","percent":"5"}]}`)
+}
+
+func FuzzUnwrapPayload(f *testing.F) {
+	addPayloadSeeds(f)
+	f.Fuzz(func(t *testing.T, payload string) {
+		var raw string
+		var format PayloadFormat
+		msg := catchAbort(func() { raw, format = unwrapPayload(&payload, "payload missing") })
+		if msg != "" {
+			if msg != "payload missing" {
+				t.Fatalf("unexpected abort %q", msg)
+			}
+			return
+		}
+		if raw == "" && strings.TrimSpace(payload) != `""` && strings.TrimSpace(payload) != "''" {
+			t.Fatalf("empty payload accepted: %q", payload)
+		}
+		if format == PayloadFormatJSON && !isJSONObject(raw) {
+			t.Fatalf("non-object reported as JSON: %q", raw)
+		}
+	})
+}
+
+func FuzzParseCreateLottery(f *testing.F) {
+	addPayloadSeeds(f)
+	f.Fuzz(func(t *testing.T, payload string) {
+		var args *CreateLotteryArgs
+		catchAbort(func() {
+			raw, format := unwrapPayload(&payload, "create_lottery payload missing")
+			args = parseCreateLottery(raw, format, defaultConfig())
+		})
+		if args == nil {
+			return
+		}
+
+		// Whatever got through must satisfy the documented bounds
+		if args.Name == "" || len(args.Name) > 100 {
+			t.Fatalf("invalid name accepted: %q", args.Name)
+		}
+		if args.BurnPercent < 500 || args.BurnPercent > 7500 {
+			t.Fatalf("invalid burn accepted: %v", args.BurnPercent)
+		}
+		total := BasisPoints(0)
+		for _, share := range args.WinnerShares {
+			if share <= 0 {
+				t.Fatalf("invalid share accepted: %v", args.WinnerShares)
+			}
+			total += share
+		}
+		if len(args.WinnerShares) == 0 || total != BasisPointsScale {
+			t.Fatalf("invalid shares accepted: %v", args.WinnerShares)
+		}
+		if args.TicketPrice < 1 {
+			t.Fatalf("invalid ticket price accepted: %v", args.TicketPrice)
+		}
+		donated := donationPercent(args.Donations)
+		if donated < 0 || donated > 5000 || args.BurnPercent+donated+args.CreatorFee > 9000 {
+			t.Fatalf("invalid donation accepted: %v", args.Donations)
+		}
+		for _, d := range args.Donations {
+			if !d.Account.IsValid() || d.Percent < 0 {
+				t.Fatalf("invalid donation recipient accepted: %v", d)
+			}
+		}
+		if len(args.MetaData) > 500 {
+			t.Fatalf("oversized metadata accepted: %d", len(args.MetaData))
+		}
+	})
+}
+
+func FuzzParseChangeLotteryMetadata(f *testing.F) {
+	addPayloadSeeds(f)
+	f.Fuzz(func(t *testing.T, payload string) {
+		var args *ChangeLotteryMetadataArgs
+		catchAbort(func() {
+			raw, format := unwrapPayload(&payload, "change_lottery_metadata payload missing")
+			args = parseChangeLotteryMetadata(raw, format, defaultConfig())
+		})
+		if args == nil {
+			return
+		}
+		if args.LotteryID == 0 || len(args.MetaData) > 500 {
+			t.Fatalf("invalid metadata change accepted: %+v", args)
+		}
+	})
+}
+
+// sampleMetadata is an executed lottery with winners and a donation
+func sampleMetadata() *LotteryMetadata {
+	return &LotteryMetadata{
+		ID: 7, Creator: "hive:creator", Name: "Fuzz Draw", CreatedAt: 1756857600, DeadlineHours: 24,
+		DeadlineUnix: 1756944000, MaxTickets: 100, BurnPercent: 1250, TicketPrice: 1500, Asset: sdk.AssetHive,
+		WinnerShares: []BasisPoints{5000, 3000, 2000}, State: LotteryStateExecuted,
+		Winners:    []Winner{{Address: "hive:alice", Amount: 4200, Share: 5000}, {Address: "hive:bob", Amount: 2520, Share: 3000}},
+		ExecutedAt: 1756944100, RandomSeed: math.MaxUint64, BurnedAmount: 1200,
+		Donations: []DonationRecipient{{Account: "hive:charity", Percent: 500, Accepted: true}}, DonatedAmount: 500,
+	}
+}
+
+// withHugeShareCount replaces the winner share count of a version 0 encoded sampleMetadata
+// with a value claiming far more entries than there are bytes
+func withHugeShareCount(encoded string) string {
+	m := sampleMetadata()
+	offset := 80 + len(m.Creator) + len(m.Name) + len(m.Asset)
+	return encoded[:offset] + strings.Repeat("\xff", 7) + "\x0f" + encoded[offset+8:]
+}
+
+func FuzzDecodeLotteryMetadata(f *testing.F) {
+	full := encodeLotteryMetadata(sampleMetadata())
+	legacy := encodeLotteryMetadataV0(sampleMetadata())
+	f.Add(full)
+	f.Add(legacy)
+	f.Add(encodeLotteryMetadata(&LotteryMetadata{}))
+	for _, cut := range []int{0, 1, 7, 8, 20, len(full) / 2, len(full) - 1} {
+		f.Add(full[:cut])
+		f.Add(legacy[:cut])
+	}
+	f.Add(withHugeShareCount(legacy))
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var m *LotteryMetadata
+		if msg := catchAbort(func() { m = decodeLotteryMetadata(data) }); msg != "" {
+			if !strings.HasPrefix(msg, "decode error:") {
+				t.Fatalf("unexpected abort %q", msg)
+			}
+			return
+		}
+		// Whatever decodes must survive a round trip unchanged. Version 0 input is upgraded
+		// and its percentages rounded to basis points, so only the re-encoding is compared.
+		encoded := encodeLotteryMetadata(m)
+		if again := encodeLotteryMetadata(decodeLotteryMetadata(encoded)); again != encoded {
+			t.Fatalf("round trip is not stable")
+		}
+	})
+}
+
+func FuzzDecodeParticipantEntry(f *testing.F) {
+	full := encodeParticipantEntry(&ParticipantEntry{Address: "hive:alice", Tickets: 42})
+	legacy := encodeParticipantEntryV0(&ParticipantEntry{Address: "hive:alice", Tickets: 42})
+	f.Add(full)
+	f.Add(full[:5])
+	f.Add(full[:len(full)-1])
+	f.Add(legacy)
+	f.Add(legacy[:len(legacy)-1])
+	f.Add(strings.Repeat("\xff", 8) + "hive:alice")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var p *ParticipantEntry
+		if msg := catchAbort(func() { p = decodeParticipantEntry(data) }); msg != "" {
+			if !strings.HasPrefix(msg, "decode error:") {
+				t.Fatalf("unexpected abort %q", msg)
+			}
+			return
+		}
+		if again := decodeParticipantEntry(encodeParticipantEntry(p)); *again != *p {
+			t.Fatalf("round trip changed %+v into %+v", p, again)
+		}
+	})
+}
+
+func FuzzParticipantEntryRoundTrip(f *testing.F) {
+	f.Add("hive:alice", uint64(1))
+	f.Add("", uint64(0))
+	f.Add("did:pkh:eip155:1:0xabc|x", uint64(math.MaxUint64))
+
+	f.Fuzz(func(t *testing.T, address string, tickets uint64) {
+		in := &ParticipantEntry{Address: address, Tickets: tickets}
+		out := decodeParticipantEntry(encodeParticipantEntry(in))
+		if *out != *in {
+			t.Fatalf("round trip changed %+v into %+v", in, out)
+		}
+	})
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"0", "1", "1.5", "5.000", "0.001", "1.0001", "-1", "1e3", ".5", "5.", "9223372036854775.807", "9223372036854775.808"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		amount, err := ParseAmount(value, sdk.AssetHive)
+		if err != nil {
+			return
+		}
+		// Whatever parses is non-negative and formats back to the same number
+		if amount < 0 {
+			t.Fatalf("%q parsed to negative %d", value, amount)
+		}
+		again, err := ParseAmount(events.Amount(amount).String(), sdk.AssetHive)
+		if err != nil || again != amount {
+			t.Fatalf("%q: %d does not round trip (%d, %v)", value, amount, again, err)
+		}
+	})
+}
+
+// TestDecodersRejectBadLengths pins the length prefix checks found by the fuzz targets
+func TestDecodersRejectBadLengths(t *testing.T) {
+	full := encodeLotteryMetadataV0(sampleMetadata())
+	hugeShares := withHugeShareCount(full)
+
+	tests := []struct {
+		name string
+		fn   func()
+		msg  string
+	}{
+		{"string length overflows int", func() { decodeParticipantEntry(strings.Repeat("\xff", 8) + "hive:alice") }, "decode error: insufficient data for string"},
+		{"string longer than data", func() { decodeParticipantEntry("\x20\x00\x00\x00\x00\x00\x00\x00hive:alice") }, "decode error: insufficient data for string"},
+		{"truncated uint64", func() { decodeParticipantEntry("\x00\x00\x00\x00\x00\x00\x00\x00\x01") }, "decode error: insufficient data for uint64"},
+		{"share count beyond data", func() { decodeLotteryMetadata(hugeShares) }, "decode error: invalid slice length"},
+		{"truncated metadata", func() { decodeLotteryMetadata(full[:len(full)-1]) }, "decode error: insufficient data for uint64"},
+		{"trailing bytes", func() {
+			decodeParticipantEntry(encodeParticipantEntryV0(&ParticipantEntry{Address: "hive:a", Tickets: 1}) + "x")
+		}, "decode error: trailing data"},
+		{"truncated config", func() { decodeConfig(encodeConfig(defaultConfig())[:5]) }, "decode error: invalid config record"},
+		{"config percent above 100", func() { decodeConfig("\x03\xa9\x4e") }, "decode error: invalid config record"},
+	}
+	for _, tt := range tests {
+		if msg := catchAbort(tt.fn); msg != tt.msg {
+			t.Errorf("%s: got %q, want %q", tt.name, msg, tt.msg)
+		}
+	}
+}
+
+// TestParseCreateLotteryRejectsNonFinite pins the NaN/Inf/overflow cases found by FuzzParseCreateLottery
+// and the percentages that do not fit into basis points
+func TestParseCreateLotteryRejectsNonFinite(t *testing.T) {
+	tests := map[string]string{
+		"X|24|NaN|100|1.000":               "invalid burn percent",
+		"X|24|Inf|100|1.000":               "invalid burn percent",
+		"X|24|10.005|100|1.000":            "invalid burn percent",
+		"X|24|10|33.333,66.667|1.000":      "invalid winner share: at most two decimals",
+		"X|24|10|100|NaN":                  "invalid ticket price",
+		"X|24|10|100|Inf":                  "invalid ticket price",
+		"X|24|10|100|1e300":                "invalid ticket price",
+		"X|24|10|100|9223372036854775.808": "ticket price too large",
+		"X|24|10|100|1.0005":               "ticket price must have at most 3 decimals",
+		"X|24|10|100|1.000|hive:a|NaN":     "invalid donation percent",
+		"X|24|10|100|1.000|hive:a|-Inf":    "invalid donation percent",
+		"X|24|10|100|1.000|hive:a|-1":      "donation percent must be between 0 and 50",
+	}
+	for payload, want := range tests {
+		msg := catchAbort(func() { parseCreateLottery(payload, PayloadFormatPipe, defaultConfig()) })
+		if msg != want {
+			t.Errorf("%s: got %q, want %q", payload, msg, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "okinoko_lottery/sdk"
+
+// Host is everything the lottery logic needs from the chain: contract state, the ledger,
+// the execution environment and the log. The wasm build talks to the real SDK (host_sdk.go),
+// native tests install an in-memory fake, so the business rules run under plain `go test`.
+// Aborts stay on sdk.Abort, natively it panics with sdk.Aborted.
+type Host interface {
+	// StateGet returns the value stored under key, nil if it is unset
+	StateGet(key string) *string
+	// StateSet stores value under key
+	StateSet(key string, value string)
+	// StateDelete removes key from the contract state
+	StateDelete(key string)
+
+	// Env returns the current execution environment
+	Env() sdk.Env
+	// EnvKey returns a single environment value, nil if it is unset
+	EnvKey(key string) *string
+
+	// Balance returns an account's balance of asset in the smallest unit
+	Balance(address sdk.Address, asset sdk.Asset) int64
+	// Draw moves amount from the caller to the contract, bounded by the transfer.allow intent
+	Draw(amount int64, asset sdk.Asset)
+	// Transfer moves amount from the contract to another account on the network
+	Transfer(to sdk.Address, amount int64, asset sdk.Asset)
+	// Withdraw unmaps amount from the contract to a Hive L1 account
+	Withdraw(to sdk.Address, amount int64, asset sdk.Asset)
+	// ContractCall calls an action of another contract, options carry the intents it may use
+	ContractCall(contractID string, method string, payload string, options *sdk.ContractCallOptions) *string
+
+	// Log emits a contract log line
+	Log(msg string)
+}
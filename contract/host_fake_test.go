@@ -0,0 +1,240 @@
+package main
+
+import (
+	"maps"
+	"strconv"
+	"testing"
+
+	"okinoko_lottery/sdk"
+)
+
+const (
+	fakeContractID = "contract:lottery"
+	fakeOwner      = "hive:owner"
+	fakeTimestamp  = "2025-09-03T00:00:00"
+	fakeFuture     = "2025-09-05T00:00:00"
+)
+
+// ledgerKey identifies one account balance
+type ledgerKey struct {
+	address sdk.Address
+	asset   sdk.Asset
+}
+
+// fakeHost is the in-memory Host for native tests. State is a map, the ledger tracks balances
+// per account and asset like the network does, and every call runs as its own transaction
+// whose state, balances and logs are rolled back when it aborts.
+type fakeHost struct {
+	state     map[string]string
+	balances  map[ledgerKey]int64
+	withdrawn map[ledgerKey]int64 // unmapped to Hive L1, e.g. burns to hive:null
+	calls     []contractCall
+	hooks     map[string]func(contractCall) // by contract ID, replaces the default deposit hook
+	logs      []string
+	env       sdk.Env
+	timestamp string
+}
+
+// fakeTxCount numbers transactions across all fake hosts, currentEnv caches the env by tx.id
+var fakeTxCount uint64
+
+// newFakeHost installs a fresh fake host for the duration of a test
+func newFakeHost(tb testing.TB) *fakeHost {
+	f := &fakeHost{
+		state:     make(map[string]string),
+		balances:  make(map[ledgerKey]int64),
+		withdrawn: make(map[ledgerKey]int64),
+		hooks:     make(map[string]func(contractCall)),
+		timestamp: fakeTimestamp,
+	}
+	host = f
+	tb.Cleanup(func() { host = nil })
+	return f
+}
+
+func (f *fakeHost) StateGet(key string) *string {
+	v, ok := f.state[key]
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func (f *fakeHost) StateSet(key string, value string) { f.state[key] = value }
+func (f *fakeHost) StateDelete(key string)            { delete(f.state, key) }
+func (f *fakeHost) Env() sdk.Env                      { return f.env }
+func (f *fakeHost) Log(msg string)                    { f.logs = append(f.logs, msg) }
+
+func (f *fakeHost) EnvKey(key string) *string {
+	var v string
+	switch key {
+	case "tx.id":
+		v = f.env.TxId
+	case "block.timestamp":
+		v = f.env.Timestamp
+	case "contract.id":
+		v = f.env.ContractId
+	case "contract.owner":
+		v = f.env.ContractOwner
+	default:
+		return nil
+	}
+	return &v
+}
+
+func (f *fakeHost) Balance(address sdk.Address, asset sdk.Asset) int64 {
+	return f.balances[ledgerKey{address, asset}]
+}
+
+// Draw enforces the transfer.allow limit and the caller's balance like the real ledger
+func (f *fakeHost) Draw(amount int64, asset sdk.Asset) {
+	allowed := int64(0)
+	for _, intent := range f.env.Intents {
+		if intent.Type == "transfer.allow" && intent.Args["token"] == asset.String() {
+			limit, err := ParseAmount(intent.Args["limit"], asset)
+			if err == nil {
+				allowed += int64(limit)
+			}
+		}
+	}
+	if amount > allowed {
+		sdk.Abort("draw exceeds transfer.allow limit")
+	}
+	f.move(f.env.Caller, sdk.Address(f.env.ContractId), amount, asset)
+}
+
+func (f *fakeHost) Transfer(to sdk.Address, amount int64, asset sdk.Asset) {
+	f.move(sdk.Address(f.env.ContractId), to, amount, asset)
+}
+
+func (f *fakeHost) Withdraw(to sdk.Address, amount int64, asset sdk.Asset) {
+	f.debit(sdk.Address(f.env.ContractId), amount, asset)
+	f.withdrawn[ledgerKey{to, asset}] += amount
+}
+
+// contractCall records one call to another contract
+type contractCall struct {
+	contractID string
+	method     string
+	payload    string
+	intents    []sdk.Intent
+}
+
+// ContractCall records the call and runs the callee's hook. Without one installed the callee draws
+// every transfer.allow it was given, like a contract implementing the deposit hook.
+func (f *fakeHost) ContractCall(contractID string, method string, payload string, options *sdk.ContractCallOptions) *string {
+	call := contractCall{contractID: contractID, method: method, payload: payload}
+	if options != nil {
+		call.intents = options.Intents
+	}
+	f.calls = append(f.calls, call)
+
+	if hook, ok := f.hooks[contractID]; ok {
+		hook(call)
+	} else {
+		for _, intent := range call.intents {
+			asset := sdk.Asset(intent.Args["token"])
+			if limit, err := ParseAmount(intent.Args["limit"], asset); err == nil && intent.Type == "transfer.allow" {
+				f.move(sdk.Address(f.env.ContractId), sdk.Address("contract:"+contractID), int64(limit), asset)
+			}
+		}
+	}
+	ret := ""
+	return &ret
+}
+
+func (f *fakeHost) move(from sdk.Address, to sdk.Address, amount int64, asset sdk.Asset) {
+	f.debit(from, amount, asset)
+	f.balances[ledgerKey{to, asset}] += amount
+}
+
+func (f *fakeHost) debit(from sdk.Address, amount int64, asset sdk.Asset) {
+	if amount <= 0 {
+		sdk.Abort("amount must be positive")
+	}
+	key := ledgerKey{from, asset}
+	if f.balances[key] < amount {
+		sdk.Abort("insufficient balance")
+	}
+	f.balances[key] -= amount
+}
+
+// fund credits an account on the fake ledger, amount in the smallest unit
+func (f *fakeHost) fund(address string, amount int64) {
+	f.balances[ledgerKey{sdk.Address(address), sdk.AssetHive}] += amount
+}
+
+// at sets the block timestamp for the following calls
+func (f *fakeHost) at(timestamp string) *fakeHost {
+	f.timestamp = timestamp
+	return f
+}
+
+// callResult is the outcome of one fake transaction
+type callResult struct {
+	Ret   string
+	Err   string // abort message, empty on success
+	Logs  []string
+	State map[string]string // state after the call, for inspection
+}
+
+// call runs an exported entrypoint as a transaction from sender. Aborts are recovered and
+// roll the transaction back, any other panic fails the test.
+func (f *fakeHost) call(tb testing.TB, fn func(*string) *string, payload string, sender string, intents ...sdk.Intent) (res callResult) {
+	tb.Helper()
+	fakeTxCount++
+	f.env = sdk.Env{
+		ContractId:    fakeContractID,
+		ContractOwner: fakeOwner,
+		TxId:          "tx-" + strconv.FormatUint(fakeTxCount, 10),
+		Timestamp:     f.timestamp,
+		Sender:        sdk.Sender{Address: sdk.Address(sender), RequiredAuths: []sdk.Address{sdk.Address(sender)}},
+		Caller:        sdk.Address(sender),
+		Intents:       intents,
+	}
+
+	state, balances, withdrawn, logStart, callStart := maps.Clone(f.state), maps.Clone(f.balances), maps.Clone(f.withdrawn), len(f.logs), len(f.calls)
+	defer func() {
+		if r := recover(); r != nil {
+			aborted, ok := r.(sdk.Aborted)
+			if !ok {
+				panic(r)
+			}
+			f.state, f.balances, f.withdrawn, f.logs, f.calls = state, balances, withdrawn, f.logs[:logStart], f.calls[:callStart]
+			res = callResult{Err: aborted.Msg, State: f.state}
+		}
+	}()
+
+	ret := fn(&payload)
+	if ret != nil {
+		res.Ret = *ret
+	}
+	res.Logs = append([]string{}, f.logs[logStart:]...)
+	res.State = f.state
+	return res
+}
+
+// mustCall is call that fails the test on abort
+func (f *fakeHost) mustCall(tb testing.TB, fn func(*string) *string, payload string, sender string, intents ...sdk.Intent) callResult {
+	tb.Helper()
+	res := f.call(tb, fn, payload, sender, intents...)
+	if res.Err != "" {
+		tb.Fatalf("call aborted: %s", res.Err)
+	}
+	return res
+}
+
+// acceptDonations lets every donation account of a lottery that still has to accept do so
+func (f *fakeHost) acceptDonations(tb testing.TB, lotteryID uint64) {
+	tb.Helper()
+	for _, d := range loadLotteryMetadata(lotteryID).Donations {
+		if !d.Accepted {
+			f.mustCall(tb, accept_donation, strconv.FormatUint(lotteryID, 10), d.Account.String())
+		}
+	}
+}
+
+// transferAllow builds a transfer.allow intent for HIVE
+func transferAllow(limit string) sdk.Intent {
+	return sdk.Intent{Type: "transfer.allow", Args: map[string]string{"limit": limit, "token": "hive"}}
+}
@@ -0,0 +1,6 @@
+//go:build !tinygo
+
+package main
+
+// host is installed by the native tests, see host_fake_test.go
+var host Host
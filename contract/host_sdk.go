@@ -0,0 +1,38 @@
+//go:build tinygo
+
+package main
+
+import "okinoko_lottery/sdk"
+
+// host is the real chain in the wasm build
+var host Host = sdkHost{}
+
+// sdkHost forwards every call to the wasm SDK imports
+type sdkHost struct{}
+
+func (sdkHost) StateGet(key string) *string       { return sdk.StateGetObject(key) }
+func (sdkHost) StateSet(key string, value string) { sdk.StateSetObject(key, value) }
+func (sdkHost) StateDelete(key string)            { sdk.StateDeleteObject(key) }
+func (sdkHost) Env() sdk.Env                      { return sdk.GetEnv() }
+func (sdkHost) EnvKey(key string) *string         { return sdk.GetEnvKey(key) }
+func (sdkHost) Log(msg string)                    { sdk.Log(msg) }
+
+func (sdkHost) Balance(address sdk.Address, asset sdk.Asset) int64 {
+	return sdk.GetBalance(address, asset)
+}
+
+func (sdkHost) Draw(amount int64, asset sdk.Asset) {
+	sdk.HiveDraw(amount, asset)
+}
+
+func (sdkHost) Transfer(to sdk.Address, amount int64, asset sdk.Asset) {
+	sdk.HiveTransfer(to, amount, asset)
+}
+
+func (sdkHost) Withdraw(to sdk.Address, amount int64, asset sdk.Asset) {
+	sdk.HiveWithdraw(to, amount, asset)
+}
+
+func (sdkHost) ContractCall(contractID string, method string, payload string, options *sdk.ContractCallOptions) *string {
+	return sdk.ContractCall(contractID, method, payload, options)
+}
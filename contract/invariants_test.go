@@ -0,0 +1,267 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ledgerAudit checks the contract's accounting against the fake ledger after every call.
+// It only trusts ledger movements: what the contract returns or stores about burns, donations
+// and payouts is compared against them, never used to compute the expectation.
+//
+//   - after every call, aborted or not, the contract's balance of each asset equals the sum
+//     of the pools of its active lotteries plus the treasury balance of the asset
+//   - after an execution, hive:null, the donation accounts, the creator and the winners received
+//     exactly the pool between them apart from the protocol fee, which is all the contract kept
+type ledgerAudit struct {
+	f *fakeHost
+}
+
+// newLedgerAudit installs a fresh fake host and audits every call made through it
+func newLedgerAudit(tb testing.TB) *ledgerAudit {
+	return &ledgerAudit{f: newFakeHost(tb)}
+}
+
+// call runs fn through the fake host and checks the invariants afterwards
+func (a *ledgerAudit) call(tb testing.TB, fn func(*string) *string, payload string, sender string, intents ...sdk.Intent) callResult {
+	tb.Helper()
+	active := a.activeLotteries()
+	balances, withdrawn := cloneLedger(a.f.balances), cloneLedger(a.f.withdrawn)
+
+	res := a.f.call(tb, fn, payload, sender, intents...)
+
+	a.checkPools(tb)
+	for id := range active {
+		if meta := loadLotteryMetadata(id); meta.State == LotteryStateExecuted {
+			a.checkSettlement(tb, meta, balances, withdrawn)
+		}
+	}
+	return res
+}
+
+// mustCall is call that fails the test on abort
+func (a *ledgerAudit) mustCall(tb testing.TB, fn func(*string) *string, payload string, sender string, intents ...sdk.Intent) callResult {
+	tb.Helper()
+	res := a.call(tb, fn, payload, sender, intents...)
+	if res.Err != "" {
+		tb.Fatalf("call aborted: %s", res.Err)
+	}
+	return res
+}
+
+// lotteryCount reads the lottery counter from state
+func (a *ledgerAudit) lotteryCount() uint64 {
+	v, ok := a.f.state[getCounterKey()]
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseUint(v, 10, 64)
+	return n
+}
+
+// activeLotteries returns the IDs of all lotteries that still hold their pool
+func (a *ledgerAudit) activeLotteries() map[uint64]bool {
+	active := make(map[uint64]bool)
+	for id := uint64(1); id <= a.lotteryCount(); id++ {
+		if meta := loadLotteryMetadata(id); meta != nil && meta.State == LotteryStateActive {
+			active[id] = true
+		}
+	}
+	return active
+}
+
+// checkPools asserts that the contract's balance of each asset equals the sum of its active pools and its treasury
+func (a *ledgerAudit) checkPools(tb testing.TB) {
+	tb.Helper()
+	pools := make(map[sdk.Asset]int64)
+	for id := range a.activeLotteries() {
+		pools[loadLotteryMetadata(id).Asset] += int64(loadLotteryPoolStats(id).Pool)
+	}
+	contract := sdk.Address(fakeContractID)
+	for key, balance := range a.f.balances {
+		if key.address == contract {
+			held := pools[key.asset] + int64(loadTreasuryBalance(key.asset))
+			assert.Equal(tb, held, balance, "contract balance of %s does not match its active pools and treasury", key.asset)
+		}
+	}
+	for asset, pool := range pools {
+		held := pool + int64(loadTreasuryBalance(asset))
+		assert.Equal(tb, held, a.f.Balance(contract, asset), "active pools of %s are not backed by the contract balance", asset)
+	}
+}
+
+// checkSettlement asserts that an execution paid out exactly the pool, measured on the ledger.
+// An account may be paid on L1 or on the network depending on its address, so both count.
+func (a *ledgerAudit) checkSettlement(tb testing.TB, meta *LotteryMetadata, balancesBefore, withdrawnBefore map[ledgerKey]int64) {
+	tb.Helper()
+	pool := int64(loadLotteryPoolStats(meta.ID).Pool)
+	received := func(address sdk.Address) int64 {
+		key := ledgerKey{address, meta.Asset}
+		return a.f.balances[key] - balancesBefore[key] + a.f.withdrawn[key] - withdrawnBefore[key]
+	}
+
+	// What every account is owed by the recorded execution, an account can hold several roles
+	owed := map[sdk.Address]int64{"hive:null": int64(meta.BurnedAmount)}
+	donated := int64(0)
+	for _, d := range meta.Donations {
+		amount := int64(ApplyBasisPoints(Amount(pool), d.Percent))
+		owed[d.Account] += amount
+		donated += amount
+	}
+	owed[meta.Creator] += int64(meta.CreatorFeeAmount)
+	for _, w := range meta.Winners {
+		owed[w.Address] += int64(w.Amount)
+	}
+
+	paid := int64(0)
+	for address, amount := range owed {
+		assert.Equal(tb, amount, received(address), "lottery %d: %s received a different amount than recorded", meta.ID, address)
+		paid += received(address)
+	}
+	kept := pool + received(fakeContractID)
+
+	assert.Equal(tb, pool, paid+kept, "lottery %d: paid %d + protocol fee %d != pool %d", meta.ID, paid, kept, pool)
+	assert.Equal(tb, int64(meta.ProtocolFeeAmount), kept, "lottery %d: contract kept a different amount than the recorded protocol fee", meta.ID)
+	assert.Equal(tb, int64(meta.DonatedAmount), donated, "lottery %d: recorded donation differs from the donation shares", meta.ID)
+}
+
+// cloneLedger copies a ledger map so later movements can be measured against it
+func cloneLedger(m map[ledgerKey]int64) map[ledgerKey]int64 {
+	c := make(map[ledgerKey]int64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// TestAccountingInvariants runs lifecycles whose splits do not divide evenly through the audit
+func TestAccountingInvariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		create string
+		joins  map[string]string
+	}{
+		{"even split", "Even|24|10|60,40|1.000|hive:charity|20", map[string]string{"hive:alice": "3.000", "hive:bob": "5.000", "hive:carol": "2.000"}},
+		{"thirds with remainder", "Thirds|24|7.5|33.33,33.33,33.34|0.333", map[string]string{"hive:alice": "1.000", "hive:bob": "2.331", "hive:carol": "0.999"}},
+		{"odd donation", "Odd|24|5.5|50,50|1.001|hive:charity|12.25", map[string]string{"hive:alice": "7.007", "hive:bob": "3.003"}},
+		{"more shares than players", "Few|24|10|50,30,20|1.000", map[string]string{"hive:alice": "1.000"}},
+		{"single ticket", "Tiny|24|75|100|0.001|hive:charity|15", map[string]string{"hive:carol": "0.001"}},
+		{"creator fee", "Cut|24|10|60,40|1.001|hive:charity|20|creator_fee=7.25", map[string]string{"hive:alice": "3.003", "hive:bob": "2.002"}},
+		{"several donation recipients", "Causes|24|5|100|0.333|donations=hive:charity=12.5,hive:shelter=7.25,did:key:z6Mk=0.01", map[string]string{"hive:alice": "0.999", "hive:carol": "1.332"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newLedgerAudit(t)
+			a.mustCall(t, create_lottery, tt.create, "hive:creator")
+			a.f.acceptDonations(t, 1)
+			for _, who := range []string{"hive:alice", "hive:bob", "hive:carol"} {
+				if limit, ok := tt.joins[who]; ok {
+					a.f.fund(who, 1_000_000)
+					a.mustCall(t, join_lottery, "1", who, transferAllow(limit))
+				}
+			}
+			a.f.at(fakeFuture)
+			a.mustCall(t, execute_lottery, "1", "hive:executor")
+			a.call(t, execute_lottery, "1", "hive:executor") // already executed, must not move funds
+		})
+	}
+}
+
+// TestAccountingInvariantsRandomized interleaves joins, failing joins and executions across
+// several lotteries with random prices, burns, donations, shares and protocol fees
+func TestAccountingInvariantsRandomized(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	rounds := 40
+	if testing.Short() {
+		rounds = 10
+	}
+
+	for round := 0; round < rounds; round++ {
+		a := newLedgerAudit(t)
+		players := make([]string, 2+rng.IntN(6))
+		for i := range players {
+			players[i] = "hive:player" + strconv.Itoa(i)
+			a.f.fund(players[i], int64(rng.IntN(50_000)))
+		}
+
+		lotteries := 1 + rng.IntN(4)
+		for i := 0; i < lotteries; i++ {
+			a.mustCall(t, set_config, "protocol_fee_percent="+events.Percent(rng.IntN(501)).String(), fakeOwner)
+			a.mustCall(t, create_lottery, randomCreatePayload(rng), "hive:creator")
+			if rng.IntN(4) > 0 { // the rest waits for its donation accounts and sells no tickets
+				a.f.acceptDonations(t, uint64(i+1))
+			}
+		}
+		for i := 0; i < 30; i++ {
+			id := strconv.Itoa(1 + rng.IntN(lotteries))
+			limit := strconv.FormatFloat(float64(rng.IntN(10_000))/1000, 'f', 3, 64)
+			a.call(t, join_lottery, id, players[rng.IntN(len(players))], transferAllow(limit))
+		}
+
+		a.f.at(fakeFuture)
+		for id := 1; id <= lotteries; id++ {
+			a.call(t, execute_lottery, strconv.Itoa(id), "hive:executor")
+		}
+		for id := range a.activeLotteries() {
+			require.Zero(t, loadLotteryPoolStats(id).TotalTickets, "round %d: lottery %d with tickets left active", round, id)
+		}
+		if fees := loadTreasuryBalance(sdk.AssetHive); fees > 0 {
+			a.mustCall(t, withdraw_treasury, "hive|"+events.Amount(fees).String(), fakeOwner)
+		}
+		if t.Failed() {
+			t.Fatalf("round %d failed", round)
+		}
+	}
+}
+
+// randomCreatePayload returns a valid create_lottery payload with fractional percentages
+func randomCreatePayload(rng *rand.Rand) string {
+	burn := 500 + rng.IntN(7001)
+	shares := ""
+	left := BasisPointsScale
+	for n := 1 + rng.IntN(4); n > 1 && left > 1; n-- {
+		share := 1 + rng.IntN(left-1)
+		shares += events.Percent(share).String() + ","
+		left -= share
+	}
+	shares += events.Percent(left).String()
+	price := events.Amount(1 + rng.IntN(3000)).String()
+
+	payload := "Random|24|" + events.Percent(burn).String() + "|" + shares + "|" + price
+	taken := burn
+	switch rng.IntN(3) {
+	case 0:
+		donation := rng.IntN(min(9000-burn, 5000) + 1)
+		payload += "|hive:charity|" + events.Percent(donation).String()
+		taken += donation
+	case 1:
+		left := min(9000-burn, 5000)
+		entries := ""
+		for i, account := range []string{"hive:charity", "hive:shelter", "did:key:z6Mk"} {
+			if left == 0 {
+				break
+			}
+			donation := 1 + rng.IntN(left)
+			if i > 0 {
+				entries += ","
+			}
+			entries += account + "=" + events.Percent(donation).String()
+			left -= donation
+			taken += donation
+		}
+		if entries != "" {
+			payload += "|donations=" + entries
+		}
+	}
+	if rng.IntN(2) == 0 {
+		payload += "|creator_fee=" + events.Percent(rng.IntN(min(9000-taken, 1000)+1)).String()
+	}
+	return payload
+}
@@ -7,45 +7,59 @@ import (
 
 //export create_lottery
 func create_lottery(payload *string) *string {
-	payloadStr := unwrapPayload(payload, "create_lottery payload missing")
-	args := parseCreateLottery(payloadStr)
+	requireCurrentSchema()
+	requireNotPaused("create_lottery")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "create_lottery payload missing")
+	cfg := loadConfig()
+	args := parseCreateLottery(payloadStr, format, cfg)
 
 	// No transfer intent needed for creation
 	sender := getSenderAddress()
 	now := nowUnix()
+	resolveDonationRecipients(args.Donations, sender)
 
 	// Create new lottery
 	lottery := &Lottery{
-		ID:              getNextLotteryID(),
-		Creator:         sender,
-		Name:            args.Name,
-		CreatedAt:       now,
-		DeadlineHours:   args.DeadlineHours,
-		DeadlineUnix:    now + int64(args.DeadlineHours*60*60),
-		MaxTickets:      args.MaxTickets,
-		BurnPercent:     args.BurnPercent,
-		TicketPrice:     args.TicketPrice,
-		Asset:           sdk.AssetHive, // Default to HIVE, could be parameterized in the future
-		WinnerShares:    args.WinnerShares,
-		Pool:            0,
-		Participants:    make(map[string]uint64),
-		State:           LotteryStateActive,
-		Winners:         []Winner{},
-		TotalTickets:    0,
-		DonationAccount: args.DonationAccount,
-		DonationPercent: args.DonationPercent,
-		DonatedAmount:   0,
-		Metadata:        args.MetaData,
+		ID:            getNextLotteryID(),
+		Creator:       sender,
+		Name:          args.Name,
+		CreatedAt:     now,
+		DeadlineHours: args.DeadlineHours,
+		DeadlineUnix:  now + int64(args.DeadlineHours*60*60),
+		MaxTickets:    args.MaxTickets,
+		BurnPercent:   args.BurnPercent,
+		TicketPrice:   args.TicketPrice,
+		Asset:         sdk.AssetHive, // Default to HIVE, could be parameterized in the future
+		WinnerShares:  args.WinnerShares,
+		Pool:          0,
+		Participants:  []ParticipantEntry{},
+		State:         LotteryStateActive,
+		Winners:       []Winner{},
+		TotalTickets:  0,
+		Donations:     args.Donations,
+		DonatedAmount: 0,
+		Metadata:      args.MetaData,
+
+		// Fixed at creation so a later set_config cannot change what participants signed up for
+		ProtocolFeePercent: cfg.ProtocolFee,
+		CreatorFeePercent:  args.CreatorFee,
 	}
 
 	// Save lottery
 	saveLottery(lottery)
+	recordCreatorLottery(lottery)
 
 	// Emit event
 	emitLotteryCreated(lottery)
 	if args.MetaData != "" {
 		emitLotteryMetadataChanged(lottery.ID, args.MetaData)
 	}
+	for _, d := range lottery.Donations {
+		if d.Account == sender && d.CharityID == 0 && d.Percent > 0 {
+			emitDonationAccepted(lottery.ID, sender)
+		}
+	}
 
 	ret := "lottery created with ID: " + strconv.FormatUint(lottery.ID, 10)
 	return &ret
@@ -53,8 +67,11 @@ func create_lottery(payload *string) *string {
 
 //export change_lottery_metadata
 func change_lottery_metadata(payload *string) *string {
-	payloadStr := unwrapPayload(payload, "change_lottery_metadata payload missing")
-	args := parseChangeLotteryMetadata(payloadStr)
+	requireCurrentSchema()
+	requireNotPaused("change_lottery_metadata")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "change_lottery_metadata payload missing")
+	args := parseChangeLotteryMetadata(payloadStr, format, loadConfig())
 
 	// Load lottery metadata
 	meta := loadLotteryMetadata(args.LotteryID)
@@ -80,12 +97,14 @@ func change_lottery_metadata(payload *string) *string {
 
 //export join_lottery
 func join_lottery(payload *string) *string {
-	payloadStr := unwrapPayload(payload, "join_lottery payload missing")
-	args := parseJoinLottery(payloadStr)
-
-	// Require transfer intent
-	transfer := getFirstTransferAllow()
-	if transfer == nil {
+	requireCurrentSchema()
+	requireNotPaused("join_lottery")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "join_lottery payload missing")
+	args := parseJoinLottery(payloadStr, format)
+
+	// Require a transfer intent, which asset it must be for is known once the lottery is loaded
+	if len(getTransferAllows()) == 0 {
 		sdk.Abort("transfer.allow intent required")
 	}
 
@@ -108,13 +127,19 @@ func join_lottery(payload *string) *string {
 		sdk.Abort("lottery deadline has passed")
 	}
 
-	// Check asset matches
-	if transfer.Token.String() != meta.Asset.String() {
-		sdk.Abort("asset mismatch")
+	// Unregistered donation accounts have to accept before tickets are sold
+	if pending := pendingDonation(meta.Donations); pending != nil {
+		sdk.Abort("donation account " + pending.Account.String() + " has not accepted the donation yet")
+	}
+
+	// Pick the allowance for the lottery's asset, others in the same transaction are left alone
+	transfer := getTransferAllow(meta.Asset)
+	if transfer == nil {
+		sdk.Abort("asset mismatch: no transfer.allow intent for " + meta.Asset.String())
 	}
 
 	// Calculate how many tickets can be bought
-	totalAmount := FloatToAmount(transfer.Limit)
+	totalAmount := transfer.Limit
 	if totalAmount < meta.TicketPrice {
 		sdk.Abort("insufficient funds for at least one ticket")
 	}
@@ -123,7 +148,7 @@ func join_lottery(payload *string) *string {
 	actualCost := Amount(ticketCount) * meta.TicketPrice
 
 	// Draw funds from sender to contract
-	sdk.HiveDraw(AmountToInt64(actualCost), meta.Asset)
+	host.Draw(AmountToInt64(actualCost), meta.Asset)
 
 	senderStr := sender.String()
 
@@ -167,6 +192,9 @@ func join_lottery(payload *string) *string {
 		}
 	}
 
+	// Update creator aggregates before the pool stats move on
+	recordCreatorJoin(meta, stats.TotalTickets, ticketCount, actualCost)
+
 	// Update pool stats
 	stats.Pool += actualCost
 	stats.TotalTickets += ticketCount
@@ -181,8 +209,11 @@ func join_lottery(payload *string) *string {
 
 //export execute_lottery
 func execute_lottery(payload *string) *string {
-	payloadStr := unwrapPayload(payload, "execute_lottery payload missing")
-	args := parseExecuteLottery(payloadStr)
+	requireCurrentSchema()
+	requireNotPaused("execute_lottery")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "execute_lottery payload missing")
+	args := parseExecuteLottery(payloadStr, format)
 
 	now := nowUnix()
 
@@ -210,31 +241,47 @@ func execute_lottery(payload *string) *string {
 	// Generate random seed
 	lottery.RandomSeed = generateRandomSeed()
 
-	// Calculate burn amount
-	burnAmount := Amount(float64(lottery.Pool) * lottery.BurnPercent / 100.0)
+	// Commit to who held which tickets in the pool the draw runs on
+	lottery.TicketsRoot = string(merkleRoot(ticketLeaves(lottery.Participants)))
+
+	// Split the pool in integer arithmetic: every part is its basis points of the pool
+	// (winners: of what is left after burn, donation and the fees) rounded down to the smallest unit.
+	// Whatever the rounding leaves over, plus the shares of positions nobody won, is burned.
+	burnAmount := ApplyBasisPoints(lottery.Pool, lottery.BurnPercent)
 	lottery.BurnedAmount = burnAmount
 
 	// Burn tokens by sending to null
 	nullReceiver := AddressFromString("hive:null")
 	if burnAmount > 0 {
-		sdk.HiveWithdraw(nullReceiver, AmountToInt64(burnAmount), lottery.Asset)
+		host.Withdraw(nullReceiver, AmountToInt64(burnAmount), lottery.Asset)
 	}
 
-	// Calculate and process donation if configured
+	// Pay every donation recipient its own share of the pool, Hive accounts on L1 as before
 	donationAmount := Amount(0)
-	if lottery.DonationPercent > 0.0 && lottery.DonationAccount.String() != "" {
-		donationAmount = Amount(float64(lottery.Pool) * lottery.DonationPercent / 100.0)
-		lottery.DonatedAmount = donationAmount
-
-		if donationAmount > 0 {
-			sdk.HiveWithdraw(lottery.DonationAccount, AmountToInt64(donationAmount), lottery.Asset)
-			// Emit donation event
-			emitLotteryDonation(lottery.ID, lottery.DonationAccount, donationAmount, lottery.DonationPercent, lottery.Asset)
+	for _, d := range lottery.Donations {
+		amount := ApplyBasisPoints(lottery.Pool, d.Percent)
+		if amount > 0 {
+			payout(d.Account, amount, lottery.Asset, true, payoutReference("donation", lottery.ID))
+			emitLotteryDonation(lottery.ID, d.Account, amount, d.Percent, lottery.Asset)
 		}
+		donationAmount += amount
+	}
+	lottery.DonatedAmount = donationAmount
+
+	// Keep the protocol fee in the contract, it is credited to the treasury
+	feeAmount := ApplyBasisPoints(lottery.Pool, lottery.ProtocolFeePercent)
+	lottery.ProtocolFeeAmount = feeAmount
+	if feeAmount > 0 {
+		creditTreasury(lottery.Asset, feeAmount)
 	}
 
-	// Calculate remaining pool for distribution (after burn and donation)
-	remainingPool := lottery.Pool - burnAmount - donationAmount
+	// Pay the creator's share, transferred on the network
+	creatorFeeAmount := ApplyBasisPoints(lottery.Pool, lottery.CreatorFeePercent)
+	lottery.CreatorFeeAmount = creatorFeeAmount
+	payout(lottery.Creator, creatorFeeAmount, lottery.Asset, false, payoutReference("creator_fee", lottery.ID))
+
+	// Calculate remaining pool for distribution (after burn, donation and fees)
+	remainingPool := lottery.Pool - burnAmount - donationAmount - feeAmount - creatorFeeAmount
 
 	// Select winners
 	winnerCount := len(lottery.WinnerShares)
@@ -243,17 +290,15 @@ func execute_lottery(payload *string) *string {
 	// Handle case where we have fewer participants than winner spots
 	actualWinnerCount := len(winnerAddresses)
 
-	// Distribute prizes
+	// Distribute prizes, on the network or to Hive L1 as each winner prefers
 	lottery.Winners = make([]Winner, 0, actualWinnerCount)
 	distributedTotal := Amount(0)
 
 	for i, winnerAddr := range winnerAddresses {
 		share := lottery.WinnerShares[i]
-		winAmount := Amount(float64(remainingPool) * share / 100.0)
+		winAmount := ApplyBasisPoints(remainingPool, share)
 
-		if winAmount > 0 {
-			sdk.HiveTransfer(winnerAddr, AmountToInt64(winAmount), lottery.Asset)
-		}
+		payout(winnerAddr, winAmount, lottery.Asset, prefersWithdraw(lottery.ID, winnerAddr), payoutReference("prize", lottery.ID))
 
 		winner := Winner{
 			Address: winnerAddr,
@@ -270,8 +315,7 @@ func execute_lottery(payload *string) *string {
 	// Send any undistributed funds to null (unclaimed shares + rounding remainder)
 	if distributedTotal < remainingPool {
 		undistributed := remainingPool - distributedTotal
-		nullReceiver := AddressFromString("hive:null")
-		sdk.HiveWithdraw(nullReceiver, AmountToInt64(undistributed), lottery.Asset)
+		host.Withdraw(nullReceiver, AmountToInt64(undistributed), lottery.Asset)
 		// Update total burned amount to include undistributed funds
 		lottery.BurnedAmount += undistributed
 		// Emit undistributed event
@@ -284,6 +328,7 @@ func execute_lottery(payload *string) *string {
 
 	// Save lottery
 	saveLottery(lottery)
+	recordCreatorExecution(lottery)
 
 	// Get participant count for event
 	participantCount := uint64(len(lottery.Participants))
@@ -297,8 +342,9 @@ func execute_lottery(payload *string) *string {
 
 //export verify_lottery
 func verify_lottery(payload *string) *string {
-	payloadStr := unwrapPayload(payload, "verify_lottery payload missing")
-	args := parseVerifyLottery(payloadStr)
+	requireCurrentSchema()
+	payloadStr, format := unwrapPayload(payload, "verify_lottery payload missing")
+	args := parseVerifyLottery(payloadStr, format)
 
 	// Load lottery (read-only, no state changes)
 	lottery := loadLottery(args.LotteryID)
@@ -311,6 +357,11 @@ func verify_lottery(payload *string) *string {
 		sdk.Abort("lottery not executed yet - nothing to verify")
 	}
 
+	// Archived participants are gone from state, the la event's hash commits to them
+	if lottery.ArchivedCount > 0 {
+		sdk.Abort("lottery archived - verify off-chain against the participants hash")
+	}
+
 	// Re-run winner selection with provided seed
 	winnerCount := len(lottery.WinnerShares)
 	verifiedWinners := selectRandomWinners(lottery.Participants, lottery.TotalTickets, winnerCount, args.Seed)
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Native tests run the business rules against the in-memory fake host. The end-to-end
+// tests against the compiled wasm live in test/.
+
+// v2Events parses the v2 lines of a call's logs, skipping the legacy copies
+func v2Events(t *testing.T, logs []string) []events.Event {
+	t.Helper()
+	var evs []events.Event
+	for _, line := range logs {
+		if events.IsLegacy(line) {
+			continue
+		}
+		ev, err := events.Parse(line)
+		require.NoError(t, err, line)
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// setupLottery creates lottery 1 and lets alice, bob and carol join with the given HIVE limits
+func setupLottery(t testing.TB, f *fakeHost, create string, joins map[string]string) {
+	f.mustCall(t, create_lottery, create, "hive:creator")
+	f.acceptDonations(t, 1)
+	for _, who := range []string{"hive:alice", "hive:bob", "hive:carol"} {
+		limit, ok := joins[who]
+		if !ok {
+			continue
+		}
+		f.fund(who, 1_000_000)
+		f.mustCall(t, join_lottery, "1", who, transferAllow(limit))
+	}
+}
+
+// TestNativeLifecycle tests create, join and execute with real ledger movements
+func TestNativeLifecycle(t *testing.T) {
+	f := newFakeHost(t)
+	setupLottery(t, f, "Native|24|10|60,40|1.000|hive:charity|20", map[string]string{
+		"hive:alice": "3.000",
+		"hive:bob":   "5.500", // buys 5, the remaining 0.500 is not drawn
+		"hive:carol": "2.000",
+	})
+
+	contract := sdk.Address(fakeContractID)
+	assert.Equal(t, int64(10_000), f.Balance(contract, sdk.AssetHive))
+	assert.Equal(t, int64(995_000), f.Balance("hive:bob", sdk.AssetHive))
+
+	res := f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Equal(t, "lottery executed with 2 winner(s)", res.Ret)
+
+	// The pool left the contract completely: 1.000 burned, 2.000 donated, 7.000 to the winners
+	assert.Equal(t, int64(0), f.Balance(contract, sdk.AssetHive))
+	assert.Equal(t, int64(1_000), f.withdrawn[ledgerKey{"hive:null", sdk.AssetHive}])
+	assert.Equal(t, int64(2_000), f.withdrawn[ledgerKey{"hive:charity", sdk.AssetHive}])
+
+	paid := int64(0)
+	for _, ev := range v2Events(t, res.Logs) {
+		if p, ok := ev.(*events.Payout); ok {
+			paid += int64(p.Amount)
+		}
+	}
+	assert.Equal(t, int64(7_000), paid)
+	total := int64(0)
+	for _, who := range []string{"hive:alice", "hive:bob", "hive:carol"} {
+		total += f.Balance(sdk.Address(who), sdk.AssetHive)
+	}
+	assert.Equal(t, int64(3_000_000-10_000+7_000), total)
+}
+
+// TestNativeAbortRollsBack tests that an aborted join leaves state, ledger and logs untouched
+func TestNativeAbortRollsBack(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Capped|24|10|100|1.000|max_tickets=3", "hive:creator")
+	f.fund("hive:alice", 10_000)
+
+	stateBefore := len(f.state)
+	res := f.call(t, join_lottery, "1", "hive:alice", transferAllow("5.000"))
+	assert.Equal(t, "lottery max tickets exceeded", res.Err)
+	assert.Len(t, f.state, stateBefore)
+	assert.Equal(t, int64(10_000), f.Balance("hive:alice", sdk.AssetHive))
+	assert.Empty(t, res.Logs)
+
+	res = f.call(t, join_lottery, "1", "hive:alice", transferAllow("3.000"))
+	assert.Empty(t, res.Err)
+	assert.Equal(t, int64(7_000), f.Balance("hive:alice", sdk.AssetHive))
+}
+
+// TestNativeEventsRoundTrip tests that everything the contract emits parses with the events package
+func TestNativeEventsRoundTrip(t *testing.T) {
+	f := newFakeHost(t)
+	res := f.mustCall(t, create_lottery, `{"name":"Pipes","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","metadata":"a|b"}`, "hive:creator")
+
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 2)
+	created := evs[0].(*events.Created)
+	assert.Equal(t, "Pipes", created.Name)
+	assert.Equal(t, events.Percent(1000), created.Burn)
+	assert.Equal(t, "a|b", evs[1].(*events.MetadataChanged).Metadata)
+	for i, line := range res.Logs {
+		if !events.IsLegacy(line) {
+			continue
+		}
+		assert.Equal(t, res.Logs[i+1], events.Format(must(events.Parse(line))))
+	}
+}
+
+// TestNativeJoinValidation tests join rejections without a wasm build
+func TestNativeJoinValidation(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Rules|24|10|100|2.000", "hive:creator")
+	f.fund("hive:alice", 10_000)
+
+	tests := []struct {
+		payload string
+		intents []sdk.Intent
+		err     string
+	}{
+		{"1", nil, "transfer.allow intent required"},
+		{"2", []sdk.Intent{transferAllow("2.000")}, "lottery not found"},
+		{"1", []sdk.Intent{transferAllow("1.000")}, "insufficient funds for at least one ticket"},
+		{"1", []sdk.Intent{{Type: "transfer.allow", Args: map[string]string{"limit": "2.000", "token": "hbd"}}}, "asset mismatch: no transfer.allow intent for hive"},
+		{"1", []sdk.Intent{transferAllow("20.000")}, "insufficient balance"},
+		{"1", []sdk.Intent{transferAllow("2.0001")}, "invalid intent limit: too many decimals"},
+		{"1", []sdk.Intent{transferAllow("2e3")}, "invalid intent limit: malformed amount"},
+		{"1", []sdk.Intent{transferAllow("99999999999999999")}, "invalid intent limit: amount out of range"},
+	}
+	for _, tt := range tests {
+		res := f.call(t, join_lottery, tt.payload, "hive:alice", tt.intents...)
+		assert.Equal(t, tt.err, res.Err, tt.payload)
+	}
+
+	res := f.at(fakeFuture).call(t, join_lottery, "1", "hive:alice", transferAllow("2.000"))
+	assert.Equal(t, "lottery deadline has passed", res.Err)
+}
+
+// TestCreatorFee tests that the creator's share is disclosed at creation and paid at execution
+func TestCreatorFee(t *testing.T) {
+	a := newLedgerAudit(t)
+	res := a.mustCall(t, create_lottery, "Cut|24|10|60,40|1.000|hive:charity|20|creator_fee=5|max_tickets=50", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	created := v2Events(t, res.Logs)[0].(*events.Created)
+	assert.Equal(t, events.Percent(500), created.CreatorFee)
+	assert.Contains(t, res.Logs[1], "|creator_fee:5.00")
+
+	a.f.fund("hive:alice", 10_000)
+	a.f.fund("hive:bob", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("6.000"))
+	a.mustCall(t, join_lottery, "1", "hive:bob", transferAllow("4.000"))
+
+	res = a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	var executed *events.Executed
+	var paid []events.Amount
+	for _, ev := range v2Events(t, res.Logs) {
+		switch e := ev.(type) {
+		case *events.Executed:
+			executed = e
+		case *events.Payout:
+			paid = append(paid, e.Amount)
+		}
+	}
+	require.NotNil(t, executed)
+	assert.Equal(t, events.Amount(500), executed.CreatorFee)
+	assert.Equal(t, []events.Amount{3900, 2600}, paid)
+	assert.Equal(t, int64(500), a.f.Balance("hive:creator", sdk.AssetHive))
+	assert.Equal(t, Amount(500), loadLotteryMetadata(1).CreatorFeeAmount)
+
+	// Without a fee the creator receives nothing and neither event carries the field
+	res = a.f.at("2025-01-01T00:00:00").mustCall(t, create_lottery, `{"name":"Free","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000"}`, "hive:creator")
+	assert.NotContains(t, strings.Join(res.Logs, "\n"), "creator_fee")
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+	res = a.f.at(fakeFuture).mustCall(t, execute_lottery, "2", "hive:executor")
+	assert.NotContains(t, strings.Join(res.Logs, "\n"), "creator_fee")
+	assert.Equal(t, int64(500), a.f.Balance("hive:creator", sdk.AssetHive))
+}
+
+// TestCreatorFeeValidation tests the creator fee bounds, which follow the config
+func TestCreatorFeeValidation(t *testing.T) {
+	f := newFakeHost(t)
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"X|24|10|100|1.000|creator_fee=abc", "invalid creator fee"},
+		{"X|24|10|100|1.000|creator_fee=-1", "creator fee must be between 0 and 10"},
+		{"X|24|10|100|1.000|creator_fee=10.01", "creator fee must be between 0 and 10"},
+		{"X|24|70|100|1.000|hive:charity|15|creator_fee=5.01", "burn percent + donation percent + creator fee must not exceed 90"},
+		{`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","creator_fee":"11"}`, "creator fee must be between 0 and 10"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, create_lottery, tt.payload, "hive:creator").Err, tt.payload)
+	}
+
+	f.mustCall(t, create_lottery, "X|24|70|100|1.000|hive:charity|15|max_tickets=9|creator_fee=5", "hive:creator")
+	f.mustCall(t, set_config, "max_creator_fee_percent=0", fakeOwner)
+	assert.Equal(t, "creator fee must be between 0 and 0", f.call(t, create_lottery, "X|24|10|100|1.000|creator_fee=0.01", "hive:creator").Err)
+	f.mustCall(t, create_lottery, "X|24|10|100|1.000|creator_fee=0", "hive:creator")
+}
+
+// TestDonationRecipients tests a donation split between several accounts, each paid and logged on its own
+func TestDonationRecipients(t *testing.T) {
+	a := newLedgerAudit(t)
+	res := a.mustCall(t, create_lottery, "Causes|24|10|100|1.000|donations=hive:ocean=15,did:key:z6Mk=5", "hive:creator")
+	created := v2Events(t, res.Logs)[0].(*events.Created)
+	assert.Equal(t, []events.DonationShare{{Account: "hive:ocean", Percent: 1500}, {Account: "did:key:z6Mk", Percent: 500}}, created.Donations)
+	assert.Contains(t, res.Logs[1], "|donation_account:hive:ocean,did:key:z6Mk|donation_percent:15.00,5.00")
+	assert.Len(t, loadLotteryMetadata(1).Donations, 2)
+	a.f.acceptDonations(t, 1)
+
+	a.f.fund("hive:alice", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("10.000"))
+	res = a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+
+	var donations []*events.Donation
+	var executed *events.Executed
+	for _, ev := range v2Events(t, res.Logs) {
+		switch e := ev.(type) {
+		case *events.Donation:
+			donations = append(donations, e)
+		case *events.Executed:
+			executed = e
+		}
+	}
+	assert.Equal(t, []*events.Donation{
+		{ID: 1, Recipient: "hive:ocean", Amount: 1500, Percent: 1500, Asset: "hive"},
+		{ID: 1, Recipient: "did:key:z6Mk", Amount: 500, Percent: 500, Asset: "hive"},
+	}, donations)
+	require.NotNil(t, executed)
+	assert.Equal(t, events.Amount(2000), executed.Donated)
+	assert.Equal(t, Amount(2000), loadCreatorStats("hive:creator").TotalDonated)
+
+	// JSON takes the same list
+	res = a.f.at("2025-01-01T00:00:00").mustCall(t, create_lottery,
+		`{"name":"Causes","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donations":[{"account":"hive:ocean","percent":"2.5"},{"account":"hive:forest","percent":"2.5"}]}`, "hive:creator")
+	assert.Len(t, v2Events(t, res.Logs)[0].(*events.Created).Donations, 2)
+}
+
+// TestDonationRecipientsValidation tests the per-recipient and combined donation checks
+func TestDonationRecipientsValidation(t *testing.T) {
+	f := newFakeHost(t)
+	many := ""
+	for i := 0; i < 11; i++ {
+		many += ",hive:c" + strconv.Itoa(i) + "=1"
+	}
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"X|24|10|100|1.000|donations=", "invalid donations entry: expected account=percent"},
+		{"X|24|10|100|1.000|donations=hive:a", "invalid donations entry: expected account=percent"},
+		{"X|24|10|100|1.000|donations=charity=5", "invalid donation account: charity"},
+		{"X|24|10|100|1.000|donations==5", "donation account cannot be empty if provided"},
+		{"X|24|10|100|1.000|donations=hive:a=5,hive:a=1", "duplicate donation account: hive:a"},
+		{"X|24|10|100|1.000|donations=hive:a=5,hive:b=0", "donation percent must be greater than 0"},
+		{"X|24|10|100|1.000|donations=hive:a=x", "invalid donation percent"},
+		{"X|24|10|100|1.000|donations=hive:a=30,hive:b=20.01", "donation percent must be between 0 and 50"},
+		{"X|24|50|100|1.000|donations=hive:a=30,hive:b=10.01", "burn percent + donation percent must not exceed 90"},
+		{"X|24|10|100|1.000|donations=" + many[1:], "at most 10 donation recipients allowed"},
+		{"X|24|70|100|1.000|donations=hive:a=10,hive:b=5|creator_fee=5.01", "burn percent + donation percent + creator fee must not exceed 90"},
+		{"X|24|10|100|1.000|hive:a|5|donations=hive:b=5", "donations cannot be combined with a donation account"},
+		{"X|24|10|100|1.000|charity|5", "invalid donation account: charity"},
+		{`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donation_account":"hive:a","donation_percent":"5","donations":[{"account":"hive:b","percent":"5"}]}`,
+			"donations cannot be combined with a donation account"},
+		{`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donations":[{"account":"hive:b"}]}`, "invalid donation percent"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, create_lottery, tt.payload, "hive:creator").Err, tt.payload)
+	}
+}
+
+// TestApplyBasisPoints tests that splits round down and stay exact beyond float64 precision
+func TestApplyBasisPoints(t *testing.T) {
+	assert.Equal(t, Amount(3333), ApplyBasisPoints(10_000, 3333))
+	assert.Equal(t, Amount(3), ApplyBasisPoints(10, 3333)) // 3.333 rounds down
+	assert.Equal(t, Amount(0), ApplyBasisPoints(1, 9999))
+	assert.Equal(t, Amount(10_001), ApplyBasisPoints(10_001, BasisPointsScale))
+	assert.Equal(t, Amount(900719925474099), ApplyBasisPoints(9007199254740993, 1000))
+	assert.Equal(t, Amount(math.MaxInt64), ApplyBasisPoints(math.MaxInt64, BasisPointsScale))
+}
+
+// TestJoinPicksMatchingAllowance tests transactions carrying allowances for several assets
+func TestJoinPicksMatchingAllowance(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Multi|24|10|100|1.000", "hive:creator")
+	f.fund("hive:alice", 10_000)
+	hbd := func(limit string) sdk.Intent {
+		return sdk.Intent{Type: "transfer.allow", Args: map[string]string{"limit": limit, "token": "hbd"}}
+	}
+	foreign := sdk.Intent{Type: "transfer.allow", Args: map[string]string{"limit": "1.000", "token": "btc"}}
+
+	// HBD first, the HIVE allowance further down is the one that counts
+	res := f.mustCall(t, join_lottery, "1", "hive:alice", hbd("50.000"), foreign, transferAllow("3.000"))
+	assert.Equal(t, "joined lottery with 3 ticket(s)", res.Ret)
+	assert.Equal(t, int64(7_000), f.Balance("hive:alice", sdk.AssetHive))
+
+	tests := []struct {
+		intents []sdk.Intent
+		err     string
+	}{
+		{[]sdk.Intent{foreign}, "transfer.allow intent required"},
+		{[]sdk.Intent{hbd("5.000"), foreign}, "asset mismatch: no transfer.allow intent for hive"},
+		{[]sdk.Intent{transferAllow("1.000"), transferAllow("2.000")}, "multiple transfer.allow intents for hive"},
+		{[]sdk.Intent{hbd("1.0001"), transferAllow("1.000")}, "invalid intent limit: too many decimals"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, join_lottery, "1", "hive:alice", tt.intents...).Err)
+	}
+}
+
+// TestJoinLargeLimitExact tests that limits beyond float32 precision buy the exact ticket count
+func TestJoinLargeLimitExact(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Whale|24|10|100|0.001", "hive:creator")
+	f.fund("hive:whale", 20_000_000_000)
+
+	// 16777.217 HIVE is 16777217 tickets, float32 rounds it to 16777216
+	res := f.mustCall(t, join_lottery, "1", "hive:whale", transferAllow("16777.217"))
+	assert.Equal(t, "joined lottery with 16777217 ticket(s)", res.Ret)
+	assert.Equal(t, int64(16_777_217), f.Balance(fakeContractID, sdk.AssetHive))
+}
+
+// TestCreatorStatsExact tests that the creator stats report amounts beyond float64 precision exactly
+func TestCreatorStatsExact(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Whale|24|10|100|9007199254740.993|max_tickets=3", "hive:creator")
+	f.fund("hive:whale", 9_007_199_254_740_993)
+	f.mustCall(t, join_lottery, "1", "hive:whale", transferAllow("9007199254740.993"))
+
+	res := f.mustCall(t, get_creator_stats, "hive:creator", "hive:anyone")
+	assert.Equal(t, "creator:hive:creator|lotteries:1|active:1|volume:9007199254740.993|donated:0.000|capped:1|avg_fill:33.33", res.Ret)
+}
+
+// TestParseAmount tests exact decimal parsing with the asset's precision
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Amount
+		err   error
+	}{
+		{"5", 5000, nil},
+		{"5.5", 5500, nil},
+		{"0.001", 1, nil},
+		{"16777.217", 16_777_217, nil},
+		{"9223372036854775.807", math.MaxInt64, nil},
+		{"9223372036854775.808", 0, errAmountRange},
+		{"1.0001", 0, errAmountDecimals},
+		{"-1", 0, errAmountSyntax},
+		{"+1", 0, errAmountSyntax},
+		{"1e3", 0, errAmountSyntax},
+		{".5", 0, errAmountSyntax},
+		{"5.", 0, errAmountSyntax},
+		{"", 0, errAmountSyntax},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.value, sdk.AssetHive)
+		assert.Equal(t, tt.err, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+
+	_, err := ParseAmount("1.000", sdk.Asset("btc"))
+	assert.Equal(t, errAmountAsset, err)
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// BenchmarkJoinLottery measures a join against a lottery that already has many participants
+func BenchmarkJoinLottery(b *testing.B) {
+	f := newFakeHost(b)
+	f.mustCall(b, create_lottery, "Bench|24|10|50,30,20|1.000", "hive:creator")
+	for i := 0; b.Loop(); i++ {
+		who := "hive:user" + strings.Repeat("x", i%7) + string(rune('a'+i%26))
+		f.fund(who, 1_000)
+		f.mustCall(b, join_lottery, "1", who, transferAllow("1.000"))
+	}
+}
+
+// BenchmarkExecuteLottery measures execution with 500 participants
+func BenchmarkExecuteLottery(b *testing.B) {
+	for b.Loop() {
+		b.StopTimer()
+		f := newFakeHost(b)
+		f.mustCall(b, create_lottery, "Bench|24|10|50,30,20|1.000", "hive:creator")
+		for i := range 500 {
+			who := "hive:user" + strings.Repeat("0", i%3) + string(rune('a'+i%26)) + string(rune('a'+i/26))
+			f.fund(who, 2_000)
+			f.mustCall(b, join_lottery, "1", who, transferAllow("2.000"))
+		}
+		f.at(fakeFuture)
+		b.StartTimer()
+
+		f.mustCall(b, execute_lottery, "1", "hive:executor")
+	}
+}
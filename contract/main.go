@@ -4,6 +4,17 @@
 //   - create_lottery: Create a new lottery with custom parameters
 //   - join_lottery: Join an existing lottery (multiple entries allowed)
 //   - execute_lottery: Execute lottery after deadline and select winners
+//   - get_ticket_proof: Merkle proof of a participant's tickets against the root committed at execution
+//   - archive_lottery: Prune the participants of an executed lottery, keeping a hash commitment
+//   - get_creator_stats: Lifetime aggregates across all lotteries of a creator
+//   - get_creator_lotteries: Paginated list of a creator's lottery IDs
+//   - migrate: Owner-only batched rewrite of the state into the current schema
+//   - pause/unpause: Owner-only emergency stop for individual actions
+//   - set_config/get_config: Owner-configurable protocol limits for new lotteries
+//   - withdraw_treasury: Owner-only withdrawal of the protocol fees kept at execution
+//   - register_charity/remove_charity/get_charity: Owner-managed registry of verified charities
+//   - accept_donation: Opt-in of a donation account that is not a registered charity
+//   - set_payout_preference/get_payout_preference: Prizes on the network or withdrawn to Hive L1
 // Modified: 2025-12-17
 ////////////////////////////////////////////////////////////////////////////////
 
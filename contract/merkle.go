@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/sdk"
+)
+
+// Ticket ownership commitment. Every participant is one leaf over its participant index, its
+// ticket range in the draw's pool and its address. The root is stored and emitted in the le event
+// at execution, so an address can prove which tickets it held with a get_ticket_proof result
+// instead of replaying every lj event.
+//
+//	leaf = SHA-256(0x00 || index || start || end || address)
+//	node = SHA-256(0x01 || left || right)
+//
+// index is 1-based, start and end are the inclusive 0-based ticket range, all three 8-byte big
+// endian. A node without a right sibling moves up a level unchanged.
+
+// ticketLeafHash hashes one participant's ticket range
+func ticketLeafHash(index uint64, start uint64, end uint64, address string) []byte {
+	buf := make([]byte, 0, 25+len(address))
+	buf = append(buf, 0x00)
+	buf = binary.BigEndian.AppendUint64(buf, index)
+	buf = binary.BigEndian.AppendUint64(buf, start)
+	buf = binary.BigEndian.AppendUint64(buf, end)
+	buf = append(buf, address...)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+// ticketLeaves builds the leaves of all participants in first-join order, the order of the draw's ticket pool
+func ticketLeaves(participants []ParticipantEntry) [][]byte {
+	leaves := make([][]byte, 0, len(participants))
+	next := uint64(0)
+	for i, p := range participants {
+		leaves = append(leaves, ticketLeafHash(uint64(i+1), next, next+p.Tickets-1, p.Address))
+		next += p.Tickets
+	}
+	return leaves
+}
+
+// merkleLevel hashes one level of the tree into the next
+func merkleLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		buf := make([]byte, 0, 65)
+		buf = append(buf, 0x01)
+		buf = append(buf, level[i]...)
+		buf = append(buf, level[i+1]...)
+		sum := sha256.Sum256(buf)
+		next = append(next, sum[:])
+	}
+	return next
+}
+
+// merkleRoot returns the root over the leaves, nil if there are none
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	for len(leaves) > 1 {
+		leaves = merkleLevel(leaves)
+	}
+	return leaves[0]
+}
+
+// merkleProof returns the sibling hashes from the leaf at pos (0-based) up to the root.
+// Levels where the node has no sibling contribute nothing.
+func merkleProof(leaves [][]byte, pos int) [][]byte {
+	var proof [][]byte
+	for len(leaves) > 1 {
+		if sibling := pos ^ 1; sibling < len(leaves) {
+			proof = append(proof, leaves[sibling])
+		}
+		leaves = merkleLevel(leaves)
+		pos /= 2
+	}
+	return proof
+}
+
+//export get_ticket_proof
+func get_ticket_proof(payload *string) *string {
+	requireCurrentSchema()
+	payloadStr, format := unwrapPayload(payload, "get_ticket_proof payload missing")
+	args := parseGetTicketProof(payloadStr, format)
+
+	lottery := loadLottery(args.LotteryID)
+	if lottery == nil {
+		sdk.Abort("lottery not found")
+	}
+	if lottery.State != LotteryStateExecuted {
+		sdk.Abort("lottery not executed yet")
+	}
+	if lottery.ArchivedCount > 0 {
+		sdk.Abort("lottery archived - build the proof off-chain from the lj events")
+	}
+	if lottery.TicketsRoot == "" {
+		sdk.Abort("lottery has no ticket root")
+	}
+	index := loadParticipantIndex(args.LotteryID, args.Address.String())
+	if index == 0 || index > uint64(len(lottery.Participants)) {
+		sdk.Abort("address holds no tickets in this lottery")
+	}
+
+	start := uint64(0)
+	for _, p := range lottery.Participants[:index-1] {
+		start += p.Tickets
+	}
+	end := start + lottery.Participants[index-1].Tickets - 1
+
+	proof := merkleProof(ticketLeaves(lottery.Participants), int(index-1))
+	siblings := make([]string, len(proof))
+	for i, sibling := range proof {
+		siblings[i] = hex.EncodeToString(sibling)
+	}
+
+	ret := "index:" + strconv.FormatUint(index, 10) +
+		"|address:" + args.Address.String() +
+		"|tickets:" + strconv.FormatUint(start, 10) + "-" + strconv.FormatUint(end, 10) +
+		"|participants:" + strconv.Itoa(len(lottery.Participants)) +
+		"|root:" + hex.EncodeToString([]byte(lottery.TicketsRoot)) +
+		"|proof:" + strings.Join(siblings, ",")
+	return &ret
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/verifier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTicketsRootMatchesVerifier tests that the root in le and every get_ticket_proof result
+// check out with the independent implementation in the verifier package
+func TestTicketsRootMatchesVerifier(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Proofs|24|10|60,40|1.000", "hive:creator")
+	joins := []struct {
+		who   string
+		limit string
+	}{
+		{"hive:alice", "3.000"}, {"hive:bob", "5.000"}, {"hive:carol", "1.000"},
+		{"hive:alice", "2.000"}, // a second purchase extends alice's range in the draw pool
+		{"hive:dave", "4.000"}, {"hive:erin", "2.000"},
+	}
+	for _, j := range joins {
+		f.fund(j.who, 10_000)
+		f.mustCall(t, join_lottery, "1", j.who, transferAllow(j.limit))
+	}
+	participants := []verifier.Participant{
+		{Address: "hive:alice", Tickets: 5}, {Address: "hive:bob", Tickets: 5}, {Address: "hive:carol", Tickets: 1},
+		{Address: "hive:dave", Tickets: 4}, {Address: "hive:erin", Tickets: 2},
+	}
+
+	assert.Equal(t, "lottery not executed yet", f.call(t, get_ticket_proof, "1|hive:alice", "hive:alice").Err)
+
+	res := f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	var root string
+	for _, ev := range v2Events(t, res.Logs) {
+		if e, ok := ev.(*events.Executed); ok {
+			root = e.TicketsRoot
+		}
+	}
+	require.Equal(t, verifier.TicketsRoot(participants), root)
+
+	for i, p := range participants {
+		res := f.mustCall(t, get_ticket_proof, `{"lottery_id":1,"address":"`+p.Address+`"}`, "hive:anyone")
+		proof, err := verifier.ParseTicketProof(res.Ret)
+		require.NoError(t, err, res.Ret)
+		assert.Equal(t, verifier.ProveTicket(participants, uint64(i+1)), proof)
+		assert.True(t, proof.Verify(root), p.Address)
+	}
+	carol := verifier.ProveTicket(participants, 3)
+	assert.Equal(t, "index:3|address:hive:carol|tickets:10-10|participants:5|root:"+root+"|proof:"+strings.Join(carol.Siblings, ","),
+		f.mustCall(t, get_ticket_proof, "1|hive:carol", "hive:anyone").Ret)
+}
+
+// TestGetTicketProofValidation tests payload and lottery checks
+func TestGetTicketProofValidation(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Proofs|24|10|100|1.000", "hive:creator")
+	f.fund("hive:alice", 10_000)
+	f.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+	f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{"", "get_ticket_proof payload missing"},
+		{"1", "invalid get_ticket_proof payload format: expected lotteryID|address"},
+		{"1| ", "address is required"},
+		{"0|hive:alice", "lottery ID must be greater than 0"},
+		{"2|hive:alice", "lottery not found"},
+		{"1|hive:bob", "address holds no tickets in this lottery"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, get_ticket_proof, tt.payload, "hive:anyone").Err, tt.payload)
+	}
+
+	// A single participant is the root itself, the proof is empty
+	res := f.mustCall(t, get_ticket_proof, "1|hive:alice", "hive:anyone")
+	proof, err := verifier.ParseTicketProof(res.Ret)
+	require.NoError(t, err)
+	assert.Empty(t, proof.Siblings)
+	assert.True(t, proof.Verify(proof.Root))
+
+	// Lotteries executed before the root existed have none
+	meta := loadLotteryMetadata(1)
+	meta.TicketsRoot = ""
+	saveLotteryMetadata(meta)
+	assert.Equal(t, "lottery has no ticket root", f.call(t, get_ticket_proof, "1|hive:alice", "hive:anyone").Err)
+
+	f.at(archiveTime).mustCall(t, archive_lottery, "1", "hive:anyone")
+	assert.Equal(t, "lottery archived - build the proof off-chain from the lj events", f.call(t, get_ticket_proof, "1|hive:alice", "hive:anyone").Err)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strconv"
+
+	"okinoko_lottery/sdk"
+)
+
+// stateSchemaVersion is the layout the whole state has to be in before regular actions run.
+//
+//	0: records without a version byte, written before the versioned codec
+//	1: every lm:, ls:, lpi: and cs: record written in a compact codec version (1 or later), and the
+//	   creator aggregates (cs:, cl:) counting every lottery, including those created before they were kept
+const stateSchemaVersion = 1
+
+// maxMigrateBatch caps how many records a single migrate call rewrites so it stays within the gas limit
+const maxMigrateBatch = 1000
+
+// requireCurrentSchema aborts while the state still has to be migrated.
+// Every entrypoint except migrate calls it first.
+func requireCurrentSchema() {
+	version := loadSchemaVersion()
+	if version < stateSchemaVersion {
+		sdk.Abort("state migration required")
+	}
+	if version > stateSchemaVersion {
+		sdk.Abort("state schema " + strconv.FormatUint(version, 10) + " is newer than this contract")
+	}
+}
+
+// migrateLotteryRecords rewrites a lottery's metadata and pool stats in the current encoding and
+// adds it to its creator's rebuilt aggregates. The decoders read every older version.
+func migrateLotteryRecords(id uint64) {
+	meta := loadLotteryMetadata(id)
+	if meta == nil {
+		return
+	}
+	saveLotteryMetadata(meta)
+	pool := loadLotteryPoolStats(id)
+	if dataPtr := host.StateGet(getLotteryPoolStatsKey(id)); dataPtr != nil && *dataPtr != "" {
+		saveLotteryPoolStats(id, pool)
+	}
+	rebuildCreatorLottery(meta, pool)
+}
+
+// migrateParticipantRecord rewrites one participant entry in the current encoding
+func migrateParticipantRecord(id uint64, index uint64) {
+	if entry := loadParticipantEntry(id, index); entry != nil {
+		saveParticipantEntry(id, index, entry)
+	}
+}
+
+//export migrate
+func migrate(payload *string) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "migrate payload missing")
+	args := parseMigrate(payloadStr, format)
+
+	version := loadSchemaVersion()
+	if version >= stateSchemaVersion {
+		ret := "state already at schema " + strconv.FormatUint(version, 10)
+		return &ret
+	}
+
+	// Walk the lotteries in ID order, each one's own records first and then its participants.
+	// The cursor points at the next record so an aborted or exhausted call resumes there.
+	count := loadLotteryCount()
+	id, index := loadMigrateCursor()
+	done := uint64(0)
+	for id <= count && done < args.BatchSize {
+		if index == 0 {
+			migrateLotteryRecords(id)
+			index = 1
+			done++
+			continue
+		}
+		participants := loadLotteryPoolStats(id).ParticipantCount
+		for index <= participants && done < args.BatchSize {
+			migrateParticipantRecord(id, index)
+			index++
+			done++
+		}
+		if index > participants {
+			id++
+			index = 0
+		}
+	}
+
+	if id > count {
+		saveSchemaVersion(stateSchemaVersion)
+		clearMigrateCursor()
+		ret := "migration complete: " + strconv.FormatUint(done, 10) + " record(s), schema " + strconv.FormatUint(stateSchemaVersion, 10)
+		return &ret
+	}
+	saveMigrateCursor(id, index)
+	ret := "migrated " + strconv.FormatUint(done, 10) + " record(s), next lottery " + strconv.FormatUint(id, 10)
+	return &ret
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyDeployment builds two lotteries, one executed, and rewrites the state as a contract
+// from before schema versions would have left it
+func legacyDeployment(t *testing.T) *ledgerAudit {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Old|24|10|60,40|1.000|hive:charity|5", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	a.mustCall(t, create_lottery, "Older|24|10|100|1.000", "hive:other")
+	for _, who := range []string{"hive:alice", "hive:bob", "hive:carol"} {
+		a.f.fund(who, 10_000)
+		a.mustCall(t, join_lottery, "1", who, transferAllow("2.000"))
+		a.mustCall(t, join_lottery, "2", who, transferAllow("1.000"))
+	}
+	a.f.at(fakeFuture)
+	a.mustCall(t, execute_lottery, "2", "hive:executor")
+
+	downgradeState(a.f.state)
+	delete(a.f.state, getSchemaVersionKey())
+	return a
+}
+
+// TestMigrateInBatches tests that a migration resumes from its cursor until every record is current
+func TestMigrateInBatches(t *testing.T) {
+	a := legacyDeployment(t)
+
+	// 2 lotteries with 3 participants each are 8 records, 3 per call needs three calls
+	res := a.mustCall(t, migrate, "3", fakeOwner)
+	assert.Equal(t, "migrated 3 record(s), next lottery 1", res.Ret)
+	assert.Equal(t, "1:3", a.f.state[getMigrateCursorKey()])
+	assert.Equal(t, "state migration required", a.call(t, join_lottery, "1", "hive:alice", transferAllow("1.000")).Err)
+
+	res = a.mustCall(t, migrate, `{"batch_size":3}`, fakeOwner)
+	assert.Equal(t, "migrated 3 record(s), next lottery 2", res.Ret)
+	assert.Equal(t, "2:2", a.f.state[getMigrateCursorKey()])
+
+	res = a.mustCall(t, migrate, "3", fakeOwner)
+	assert.Equal(t, "migration complete: 2 record(s), schema 1", res.Ret)
+	assert.NotContains(t, a.f.state, getMigrateCursorKey())
+	assert.Equal(t, "1", a.f.state[getSchemaVersionKey()])
+
+	for key, value := range a.f.state {
+		for _, prefix := range []string{"lm:", "ls:", "lpi:", "cs:"} {
+			if strings.HasPrefix(key, prefix) {
+				assert.Equal(t, byte(codecVersion), value[0], key)
+			}
+		}
+	}
+
+	res = a.mustCall(t, migrate, "3", fakeOwner)
+	assert.Equal(t, "state already at schema 1", res.Ret)
+
+	// The migrated lottery runs to completion with the ledger still adding up
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Empty(t, a.activeLotteries())
+	assert.Contains(t, a.mustCall(t, get_creator_stats, "hive:creator", "hive:alice").Ret, "lotteries:1|active:0")
+}
+
+// dropCreatorRecords removes the creator aggregates and lottery lists like a contract from before
+// they were kept would have left the state
+func dropCreatorRecords(state map[string]string) {
+	for key := range state {
+		if strings.HasPrefix(key, "cs:") || strings.HasPrefix(key, "cl:") {
+			delete(state, key)
+		}
+	}
+}
+
+// TestMigrateRebuildsCreatorStats tests that the migration counts every lottery in its creator's
+// aggregates exactly once, whether they were kept before or not
+func TestMigrateRebuildsCreatorStats(t *testing.T) {
+	want := map[string][2]string{
+		"hive:creator": {"creator:hive:creator|lotteries:1|active:1|volume:6.000|donated:0.000|capped:0|avg_fill:0.00", "total:1|ids:1"},
+		"hive:other":   {"creator:hive:other|lotteries:1|active:0|volume:3.000|donated:0.000|capped:0|avg_fill:0.00", "total:1|ids:2"},
+	}
+	for _, drop := range []bool{false, true} {
+		a := legacyDeployment(t)
+		if drop {
+			dropCreatorRecords(a.f.state)
+		}
+		a.mustCall(t, migrate, "1000", fakeOwner)
+		for creator, w := range want {
+			assert.Equal(t, w[0], a.mustCall(t, get_creator_stats, creator, "hive:anyone").Ret, "dropped %v", drop)
+			assert.Equal(t, w[1], a.mustCall(t, get_creator_lotteries, creator, "hive:anyone").Ret, "dropped %v", drop)
+		}
+	}
+
+	// Lottery 1 predates the aggregates, lottery 2 was recorded at creation, joins and executions on
+	// lottery 1 changed the aggregates of lottery 2 only
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Before|24|10|100|1.000|max_tickets=4", "hive:creator")
+	dropCreatorRecords(a.f.state)
+	a.mustCall(t, create_lottery, "After|24|10|100|1.000", "hive:creator")
+	a.f.fund("hive:alice", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("2.000"))
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+	a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	downgradeState(a.f.state)
+	delete(a.f.state, getSchemaVersionKey())
+
+	a.mustCall(t, migrate, "2", fakeOwner)
+	a.mustCall(t, migrate, "2", fakeOwner)
+	assert.Equal(t, "creator:hive:creator|lotteries:2|active:1|volume:3.000|donated:0.000|capped:1|avg_fill:50.00", a.mustCall(t, get_creator_stats, "hive:creator", "hive:anyone").Ret)
+	assert.Equal(t, "total:2|ids:1,2", a.mustCall(t, get_creator_lotteries, "hive:creator", "hive:anyone").Ret)
+}
+
+// TestMigrateRefusesUnmigratedState tests that every regular action waits for the migration
+func TestMigrateRefusesUnmigratedState(t *testing.T) {
+	a := legacyDeployment(t)
+	stateBefore := len(a.f.state)
+
+	calls := []struct {
+		fn      func(*string) *string
+		payload string
+	}{
+		{create_lottery, "New|24|10|100|1.000"},
+		{change_lottery_metadata, "1|x"},
+		{join_lottery, "1"},
+		{execute_lottery, "1"},
+		{verify_lottery, "2|1"},
+		{get_creator_stats, "hive:creator"},
+		{get_creator_lotteries, "hive:creator"},
+	}
+	for _, c := range calls {
+		assert.Equal(t, "state migration required", a.call(t, c.fn, c.payload, "hive:creator", transferAllow("1.000")).Err, c.payload)
+	}
+	assert.Len(t, a.f.state, stateBefore)
+
+	a.f.state[getSchemaVersionKey()] = "2"
+	assert.Equal(t, "state schema 2 is newer than this contract", a.call(t, get_creator_stats, "hive:creator", "hive:alice").Err)
+}
+
+// TestMigrateValidation tests owner and payload checks
+func TestMigrateValidation(t *testing.T) {
+	a := legacyDeployment(t)
+
+	tests := []struct {
+		payload string
+		sender  string
+		err     string
+	}{
+		{"100", "hive:creator", "only the contract owner can do this"},
+		{"", fakeOwner, "migrate payload missing"},
+		{"abc", fakeOwner, "invalid batch size"},
+		{"0", fakeOwner, "batch size must be between 1 and 1000"},
+		{"1001", fakeOwner, "batch size must be between 1 and 1000"},
+		{`{"batch_size":0}`, fakeOwner, "batch size must be between 1 and 1000"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, a.call(t, migrate, tt.payload, tt.sender).Err, tt.payload)
+	}
+	assert.True(t, strings.HasPrefix(a.call(t, migrate, `{"batch_size":"5"}`, fakeOwner).Err, "invalid migrate JSON payload: "))
+	assert.NotContains(t, a.f.state, getMigrateCursorKey())
+}
+
+// TestFreshContractSchema tests that a new deployment records the current schema with its first lottery
+func TestFreshContractSchema(t *testing.T) {
+	f := newFakeHost(t)
+	assert.Equal(t, "state already at schema 1", f.mustCall(t, migrate, "10", fakeOwner).Ret)
+	assert.Empty(t, f.state)
+
+	f.mustCall(t, create_lottery, "Fresh|24|10|100|1.000", "hive:creator")
+	require.Contains(t, f.state, getSchemaVersionKey())
+	assert.Equal(t, "1", f.state[getSchemaVersionKey()])
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+
+	"okinoko_lottery/sdk"
+)
+
+// pausableActions are the entrypoints the contract owner can stop in an emergency, in the order
+// they are stored and reported. Every entrypoint that changes state on a user's behalf is listed.
+// Queries, migrate and pause/unpause themselves always run, so a paused contract can still be
+// inspected and be brought back; the other owner-only actions (set_config, withdraw_treasury,
+// register_charity, remove_charity) run too, the owner does not need to be stopped by their own pause.
+var pausableActions = []string{
+	"create_lottery",
+	"change_lottery_metadata",
+	"join_lottery",
+	"execute_lottery",
+	"archive_lottery",
+	"accept_donation",
+	"set_payout_preference",
+}
+
+// isPausableAction checks if an action name is one of pausableActions
+func isPausableAction(action string) bool {
+	for _, a := range pausableActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// requireNotPaused aborts if the contract owner has paused the action.
+// Every pausable entrypoint calls it right after the schema check.
+func requireNotPaused(action string) {
+	for _, a := range loadPausedActions() {
+		if a == action {
+			sdk.Abort(action + " is paused")
+		}
+	}
+}
+
+// setPaused pauses or unpauses the requested actions and emits one cp event for the ones that changed
+func setPaused(payload *string, name string, paused bool) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, name+" payload missing")
+	args := parsePause(payloadStr, format, name)
+
+	current := make(map[string]bool)
+	for _, a := range loadPausedActions() {
+		current[a] = true
+	}
+	changed := make([]string, 0, len(args.Actions))
+	for _, a := range args.Actions {
+		if current[a] != paused {
+			current[a] = paused
+			changed = append(changed, a)
+		}
+	}
+
+	// Keep the stored list in pausableActions order so it reads the same however it was built
+	stored := make([]string, 0, len(pausableActions))
+	for _, a := range pausableActions {
+		if current[a] {
+			stored = append(stored, a)
+		}
+	}
+	if len(changed) > 0 {
+		savePausedActions(stored)
+		emitPauseChanged(changed, paused)
+	}
+
+	ret := "paused: none"
+	if len(stored) > 0 {
+		ret = "paused: " + strings.Join(stored, ",")
+	}
+	return &ret
+}
+
+//export pause
+func pause(payload *string) *string {
+	return setPaused(payload, "pause", true)
+}
+
+//export unpause
+func unpause(payload *string) *string {
+	return setPaused(payload, "unpause", false)
+}
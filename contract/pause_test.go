@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPauseJoinsKeepsExecution tests the emergency case: new tickets stop while running lotteries can still finish
+func TestPauseJoinsKeepsExecution(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Paused|24|10|100|1.000", "hive:creator")
+	a.f.fund("hive:alice", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("2.000"))
+
+	res := a.mustCall(t, pause, "join_lottery,create_lottery", fakeOwner)
+	assert.Equal(t, "paused: create_lottery,join_lottery", res.Ret)
+	assert.Equal(t, "create_lottery,join_lottery", a.f.state[getPausedKey()])
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 1)
+	paused := evs[0].(*events.PauseChanged)
+	assert.Equal(t, []string{"create_lottery", "join_lottery"}, paused.Actions)
+	assert.True(t, paused.Paused)
+	assert.Equal(t, nowUnix(), paused.At)
+
+	assert.Equal(t, "join_lottery is paused", a.call(t, join_lottery, "1", "hive:alice", transferAllow("1.000")).Err)
+	assert.Equal(t, "create_lottery is paused", a.call(t, create_lottery, "Next|24|10|100|1.000", "hive:creator").Err)
+	a.mustCall(t, change_lottery_metadata, "1|still editable", "hive:creator")
+	assert.Contains(t, a.mustCall(t, get_creator_stats, "hive:creator", "hive:anyone").Ret, "lotteries:1")
+
+	a.f.at(fakeFuture)
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Empty(t, a.activeLotteries())
+
+	res = a.mustCall(t, unpause, `{"actions":["all"]}`, fakeOwner)
+	assert.Equal(t, "paused: none", res.Ret)
+	assert.NotContains(t, a.f.state, getPausedKey())
+	evs = v2Events(t, res.Logs)
+	require.Len(t, evs, 1)
+	assert.Equal(t, []string{"create_lottery", "join_lottery"}, evs[0].(*events.PauseChanged).Actions)
+	assert.False(t, evs[0].(*events.PauseChanged).Paused)
+	a.mustCall(t, create_lottery, "Next|24|10|100|1.000", "hive:creator")
+}
+
+// TestPauseAll tests that every pausable entrypoint checks the flag and that repeated toggles emit nothing
+func TestPauseAll(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "Open|24|10|100|1.000", "hive:creator")
+	res := f.mustCall(t, pause, "all", fakeOwner)
+	assert.Equal(t, "paused: "+strings.Join(pausableActions, ","), res.Ret)
+
+	calls := []struct {
+		fn      func(*string) *string
+		name    string
+		payload string
+	}{
+		{create_lottery, "create_lottery", "New|24|10|100|1.000"},
+		{change_lottery_metadata, "change_lottery_metadata", "1|x"},
+		{join_lottery, "join_lottery", "1"},
+		{execute_lottery, "execute_lottery", "1"},
+		{archive_lottery, "archive_lottery", "1"},
+		{accept_donation, "accept_donation", "1"},
+		{set_payout_preference, "set_payout_preference", "withdraw"},
+	}
+	for _, c := range calls {
+		assert.Equal(t, c.name+" is paused", f.call(t, c.fn, c.payload, "hive:creator", transferAllow("1.000")).Err)
+	}
+
+	res = f.mustCall(t, pause, "join_lottery", fakeOwner)
+	assert.Empty(t, res.Logs)
+	res = f.mustCall(t, unpause, "join_lottery", fakeOwner)
+	assert.Len(t, v2Events(t, res.Logs), 1)
+	assert.Equal(t, "paused: create_lottery,change_lottery_metadata,execute_lottery,archive_lottery,accept_donation,set_payout_preference", res.Ret)
+	assert.Empty(t, f.mustCall(t, unpause, "join_lottery", fakeOwner).Logs)
+}
+
+// TestPauseValidation tests owner and payload checks
+func TestPauseValidation(t *testing.T) {
+	f := newFakeHost(t)
+
+	tests := []struct {
+		fn      func(*string) *string
+		payload string
+		sender  string
+		err     string
+	}{
+		{pause, "all", "hive:creator", "only the contract owner can do this"},
+		{unpause, "all", "hive:creator", "only the contract owner can do this"},
+		{pause, "", fakeOwner, "pause payload missing"},
+		{unpause, "", fakeOwner, "unpause payload missing"},
+		{pause, "join_lottery,verify_lottery", fakeOwner, "action cannot be paused: verify_lottery"},
+		{pause, "migrate", fakeOwner, "action cannot be paused: migrate"},
+		{pause, `{"actions":[]}`, fakeOwner, "at least one action is required"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, tt.fn, tt.payload, tt.sender).Err, tt.payload)
+	}
+	assert.True(t, strings.HasPrefix(f.call(t, unpause, `{"actions":"all"}`, fakeOwner).Err, "invalid unpause JSON payload: "))
+	assert.Empty(t, f.state)
+}
@@ -1,24 +1,37 @@
 package main
 
 import (
+	"okinoko_lottery/events"
 	"okinoko_lottery/sdk"
 	"strconv"
 	"strings"
 )
 
 // parseCreateLottery parses the payload for create_lottery
-// Format: name|deadlineHours|burnPercent|winnerShare1,winnerShare2,...|ticketPrice[|donationAccount|donationPercent][|metaData][|max_tickets=<count>]
-// Example: "My Lottery|168|10|50,30,20|5.000" or "My Lottery|168|10|50,30,20|5.000|hive:charity|5|My meta|max_tickets=1000"
-func parseCreateLottery(payload string) *CreateLotteryArgs {
+// Format: name|deadlineHours|burnPercent|winnerShare1,winnerShare2,...|ticketPrice[|donationAccount|donationPercent][|metaData][|max_tickets=<count>][|creator_fee=<percent>][|donations=<account>=<percent>,...]
+// Example: "My Lottery|168|10|50,30,20|5.000" or "My Lottery|168|7.5|33.33,33.33,33.34|5.000|hive:charity|5|My meta|max_tickets=1000|creator_fee=2.5"
+// or "My Lottery|168|10|100|5.000|donations=hive:charity=5,hive:shelter=2.5"
+// Percentages and winner shares take up to two decimals. The bounds come from cfg, see set_config.
+// The trailing key=value options may come in any order. donations replaces the positional donation pair.
+// JSON: {"name":"My Lottery","deadline_hours":168,"burn_percent":"10","winner_shares":["50","30","20"],"ticket_price":"5.000",
+// "donation_account":"hive:charity","donation_percent":"5","metadata":"My|meta","max_tickets":1000,"creator_fee":"2.5"}
+// or with "donations":[{"account":"hive:charity","percent":"5"},{"account":"hive:shelter","percent":"2.5"}] instead of the donation pair
+func parseCreateLottery(payload string, format PayloadFormat, cfg *Config) *CreateLotteryArgs {
+	if format == PayloadFormatJSON {
+		return parseCreateLotteryJSON(payload, cfg)
+	}
+
 	parts := strings.Split(payload, "|")
-	if len(parts) < 5 || len(parts) > 9 {
-		sdk.Abort("invalid create_lottery payload format: expected 5 to 9 parts")
+	if len(parts) < 5 || len(parts) > 11 {
+		sdk.Abort("invalid create_lottery payload format: expected 5 to 11 parts")
 	}
 
 	maxTickets := uint64(0)
-	if len(parts) > 5 {
+	creatorFee, donations := "", ""
+	hasMaxTickets, hasCreatorFee, hasDonations := false, false, false
+	for len(parts) > 5 {
 		last := strings.TrimSpace(parts[len(parts)-1])
-		if strings.HasPrefix(last, "max_tickets=") {
+		if strings.HasPrefix(last, "max_tickets=") && !hasMaxTickets {
 			value := strings.TrimSpace(strings.TrimPrefix(last, "max_tickets="))
 			if value == "" {
 				sdk.Abort("max tickets must be greater than 0")
@@ -28,144 +41,347 @@ func parseCreateLottery(payload string) *CreateLotteryArgs {
 				sdk.Abort("max tickets must be greater than 0")
 			}
 			maxTickets = parsed
-			parts = parts[:len(parts)-1]
+			hasMaxTickets = true
+		} else if strings.HasPrefix(last, "creator_fee=") && !hasCreatorFee {
+			creatorFee = strings.TrimPrefix(last, "creator_fee=")
+			hasCreatorFee = true
+		} else if strings.HasPrefix(last, "donations=") && !hasDonations {
+			donations = strings.TrimPrefix(last, "donations=")
+			hasDonations = true
+		} else {
+			break
 		}
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 8 {
+		sdk.Abort("invalid create_lottery payload format: expected 5 to 11 parts")
 	}
 
 	name := strings.TrimSpace(parts[0])
+	validateLotteryName(name, cfg)
+
+	deadlineHours, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		sdk.Abort("invalid deadline hours")
+	}
+	validateDeadlineHours(deadlineHours, cfg)
+
+	burnPercent := parseBurnPercent(parts[2], cfg)
+	winnerShares := parseWinnerShares(strings.Split(strings.TrimSpace(parts[3]), ","))
+	ticketPrice := parseTicketPrice(parts[4], cfg)
+
+	args := &CreateLotteryArgs{
+		Name:          name,
+		DeadlineHours: deadlineHours,
+		MaxTickets:    maxTickets,
+		BurnPercent:   burnPercent,
+		WinnerShares:  winnerShares,
+		TicketPrice:   ticketPrice,
+		MetaData:      "",
+	}
+
+	// Parse optional donation parameters
+	if len(parts) == 7 || len(parts) == 8 {
+		if hasDonations {
+			sdk.Abort("donations cannot be combined with a donation account")
+		}
+		args.Donations = []DonationRecipient{{
+			Account: parseDonationAccount(parts[5]),
+			Percent: parseDonationPercent(parts[6], burnPercent, cfg),
+		}}
+	}
+	if hasDonations {
+		var accounts, percents []string
+		for _, entry := range strings.Split(donations, ",") {
+			account, percent, ok := strings.Cut(entry, "=")
+			if !ok {
+				sdk.Abort("invalid donations entry: expected account=percent")
+			}
+			accounts = append(accounts, account)
+			percents = append(percents, percent)
+		}
+		args.Donations = parseDonations(accounts, percents, burnPercent, cfg)
+	}
+	if hasCreatorFee {
+		args.CreatorFee = parseCreatorFee(creatorFee, burnPercent+donationPercent(args.Donations), cfg)
+	}
+
+	// Parse optional metadata
+	if len(parts) == 6 {
+		args.MetaData = strings.TrimSpace(parts[5])
+	} else if len(parts) == 8 {
+		args.MetaData = strings.TrimSpace(parts[7])
+	}
+	validateMetadata(args.MetaData, cfg)
+
+	return args
+}
+
+// parseCreateLotteryJSON parses the JSON form of create_lottery with the same rules as the pipe format.
+// Optional fields may be omitted; metadata may contain any character.
+func parseCreateLotteryJSON(payload string, cfg *Config) *CreateLotteryArgs {
+	var in CreateLotteryJSON
+	if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+		sdk.Abort("invalid create_lottery JSON payload: " + err.Error())
+	}
+
+	maxTickets := uint64(0)
+	if in.MaxTickets != nil {
+		if *in.MaxTickets == 0 {
+			sdk.Abort("max tickets must be greater than 0")
+		}
+		maxTickets = *in.MaxTickets
+	}
+
+	name := strings.TrimSpace(in.Name)
+	validateLotteryName(name, cfg)
+	validateDeadlineHours(in.DeadlineHours, cfg)
+	burnPercent := parseBurnPercent(in.BurnPercent, cfg)
+	winnerShares := parseWinnerShares(in.WinnerShares)
+	ticketPrice := parseTicketPrice(in.TicketPrice, cfg)
+
+	args := &CreateLotteryArgs{
+		Name:          name,
+		DeadlineHours: in.DeadlineHours,
+		MaxTickets:    maxTickets,
+		BurnPercent:   burnPercent,
+		WinnerShares:  winnerShares,
+		TicketPrice:   ticketPrice,
+		MetaData:      strings.TrimSpace(in.Metadata),
+	}
+
+	// Donation is optional, a percent without an account is rejected like an empty pipe field
+	donationAccount := strings.TrimSpace(in.DonationAccount)
+	if donationAccount == "" && strings.TrimSpace(in.DonationPercent) != "" {
+		sdk.Abort("donation account cannot be empty if provided")
+	}
+	if donationAccount != "" {
+		if in.Donations != nil {
+			sdk.Abort("donations cannot be combined with a donation account")
+		}
+		args.Donations = []DonationRecipient{{
+			Account: parseDonationAccount(donationAccount),
+			Percent: parseDonationPercent(in.DonationPercent, burnPercent, cfg),
+		}}
+	}
+	if in.Donations != nil {
+		accounts := make([]string, len(in.Donations))
+		percents := make([]string, len(in.Donations))
+		for i, d := range in.Donations {
+			accounts[i], percents[i] = d.Account, d.Percent
+		}
+		args.Donations = parseDonations(accounts, percents, burnPercent, cfg)
+	}
+	if strings.TrimSpace(in.CreatorFee) != "" {
+		args.CreatorFee = parseCreatorFee(in.CreatorFee, burnPercent+donationPercent(args.Donations), cfg)
+	}
+
+	validateMetadata(args.MetaData, cfg)
+
+	return args
+}
+
+// validateLotteryName enforces the lottery name rules
+func validateLotteryName(name string, cfg *Config) {
 	if name == "" {
 		sdk.Abort("lottery name is required")
 	}
-	if len(name) > 100 {
-		sdk.Abort("lottery name must be 100 characters or less")
+	if uint64(len(name)) > cfg.MaxNameLength {
+		sdk.Abort("lottery name must be " + strconv.FormatUint(cfg.MaxNameLength, 10) + " characters or less")
 	}
 	if strings.Contains(name, "|") {
 		sdk.Abort("lottery name cannot contain pipe character")
 	}
+}
 
-	deadlineHours, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
-	if err != nil {
-		sdk.Abort("invalid deadline hours")
-	}
-	if deadlineHours < 1 {
-		sdk.Abort("deadline must be at least 1 hour")
+// validateDeadlineHours enforces the lottery duration bounds
+func validateDeadlineHours(deadlineHours uint64, cfg *Config) {
+	if deadlineHours < cfg.MinDeadlineHours {
+		unit := " hours"
+		if cfg.MinDeadlineHours == 1 {
+			unit = " hour"
+		}
+		sdk.Abort("deadline must be at least " + strconv.FormatUint(cfg.MinDeadlineHours, 10) + unit)
 	}
-	if deadlineHours > 2160 {
-		sdk.Abort("deadline must be 2160 hours or less")
+	if deadlineHours > cfg.MaxDeadlineHours {
+		sdk.Abort("deadline must be " + strconv.FormatUint(cfg.MaxDeadlineHours, 10) + " hours or less")
 	}
+}
 
-	burnPercent, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+// parseBasisPoints parses a percentage with up to two decimals (e.g. "33.33") into basis points.
+// Anything ParseFloat would round, like "33.333" or "1e1", is rejected instead.
+func parseBasisPoints(value string, errMsg string) BasisPoints {
+	p, err := events.ParsePercent(strings.TrimSpace(value))
 	if err != nil {
-		sdk.Abort("invalid burn percent")
+		sdk.Abort(errMsg)
 	}
-	if burnPercent < 5.0 || burnPercent > 75.0 {
-		sdk.Abort("burn percent must be between 5 and 75")
+	return BasisPoints(p)
+}
+
+// parseBurnPercent parses the burn rate and enforces its bounds
+func parseBurnPercent(value string, cfg *Config) BasisPoints {
+	burnPercent := parseBasisPoints(value, "invalid burn percent")
+	if burnPercent < cfg.MinBurnPercent || burnPercent > cfg.MaxBurnPercent {
+		sdk.Abort("burn percent must be between " + formatPercentLimit(cfg.MinBurnPercent) + " and " + formatPercentLimit(cfg.MaxBurnPercent))
 	}
+	return burnPercent
+}
 
-	// Parse winner shares (integers only)
-	shareStrs := strings.Split(strings.TrimSpace(parts[3]), ",")
+// parseWinnerShares parses the winner shares (up to two decimals) and checks they sum to exactly 100
+func parseWinnerShares(shareStrs []string) []BasisPoints {
 	if len(shareStrs) == 0 {
 		sdk.Abort("at least one winner required")
 	}
 
-	winnerShares := make([]float64, len(shareStrs))
-	totalShares := 0.0
+	winnerShares := make([]BasisPoints, len(shareStrs))
+	totalShares := BasisPoints(0)
 	for i, s := range shareStrs {
-		shareInt, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
-		if err != nil {
-			sdk.Abort("invalid winner share: must be integer")
+		share := parseBasisPoints(s, "invalid winner share: at most two decimals")
+		if share <= 0 || share > BasisPointsScale {
+			sdk.Abort("winner share must be between 0.01 and 100")
 		}
-		if shareInt <= 0 || shareInt > 100 {
-			sdk.Abort("winner share must be between 1 and 100")
-		}
-		share := float64(shareInt)
 		winnerShares[i] = share
 		totalShares += share
 	}
 
 	// Check shares sum to 100
-	if totalShares != 100.0 {
+	if totalShares != BasisPointsScale {
 		sdk.Abort("winner shares must sum to 100")
 	}
 
-	ticketPrice, err := strconv.ParseFloat(strings.TrimSpace(parts[4]), 64)
-	if err != nil {
+	return winnerShares
+}
+
+// parseTicketPrice parses a human ticket price (e.g. "5.000") and enforces the minimum price.
+// Lotteries are always created in HIVE, so the price takes HIVE's three decimals.
+func parseTicketPrice(value string, cfg *Config) Amount {
+	ticketPrice, err := ParseAmount(strings.TrimSpace(value), sdk.AssetHive)
+	switch err {
+	case nil:
+	case errAmountDecimals:
+		sdk.Abort("ticket price must have at most 3 decimals")
+	case errAmountRange:
+		sdk.Abort("ticket price too large")
+	default:
 		sdk.Abort("invalid ticket price")
 	}
-	if ticketPrice < 0.001 {
-		sdk.Abort("ticket price must be at least 0.001")
+	if ticketPrice < cfg.MinTicketPrice {
+		sdk.Abort("ticket price must be at least " + events.Amount(cfg.MinTicketPrice).String())
 	}
+	return ticketPrice
+}
 
-	args := &CreateLotteryArgs{
-		Name:            name,
-		DeadlineHours:   deadlineHours,
-		MaxTickets:      maxTickets,
-		BurnPercent:     burnPercent,
-		WinnerShares:    winnerShares,
-		TicketPrice:     FloatToAmount(ticketPrice),
-		DonationAccount: sdk.Address(""),
-		DonationPercent: 0.0,
-		MetaData:        "",
+// parseDonationPercent parses the donation rate and enforces its bounds and the combined burn + donation cap
+func parseDonationPercent(value string, burnPercent BasisPoints, cfg *Config) BasisPoints {
+	percent := parseBasisPoints(value, "invalid donation percent")
+	checkDonationPercent(percent, burnPercent, cfg)
+	return percent
+}
+
+// checkDonationPercent enforces the donation bounds on the combined rate of all recipients
+func checkDonationPercent(percent BasisPoints, burnPercent BasisPoints, cfg *Config) {
+	if percent < 0 || percent > cfg.MaxDonationPercent {
+		sdk.Abort("donation percent must be between 0 and " + formatPercentLimit(cfg.MaxDonationPercent))
 	}
 
-	// Parse optional donation parameters
-	if len(parts) == 7 || len(parts) == 8 {
-		donationAccount := strings.TrimSpace(parts[5])
-		if donationAccount == "" {
-			sdk.Abort("donation account cannot be empty if provided")
-		}
-		// donation account could be a dao project in future - for now just basic user address
-		args.DonationAccount = sdk.Address(donationAccount)
+	// Validate total percentages stay within the cap so that the rest goes to winners
+	if burnPercent+percent > cfg.MaxBurnAndDonation {
+		sdk.Abort("burn percent + donation percent must not exceed " + formatPercentLimit(cfg.MaxBurnAndDonation))
+	}
+}
 
-		donationPercent, err := strconv.ParseFloat(strings.TrimSpace(parts[6]), 64)
-		if err != nil {
-			sdk.Abort("invalid donation percent")
-		}
-		if donationPercent < 0.0 || donationPercent > 50.0 {
-			sdk.Abort("donation percent must be between 0 and 50")
+// parseDonationAccount validates a donation recipient's address
+func parseDonationAccount(value string) sdk.Address {
+	account := sdk.Address(strings.TrimSpace(value))
+	if account == "" {
+		sdk.Abort("donation account cannot be empty if provided")
+	}
+	if !payableAddress(account) {
+		sdk.Abort("invalid donation account: " + account.String())
+	}
+	return account
+}
+
+// maxDonationRecipients caps the donation list of a lottery, every recipient costs a withdrawal at execution
+const maxDonationRecipients = 10
+
+// parseDonations parses a list of donation recipients. Each needs a share above 0 and may appear
+// only once; the bounds of a single donation apply to the combined rate.
+func parseDonations(accounts, percents []string, burnPercent BasisPoints, cfg *Config) []DonationRecipient {
+	if len(accounts) == 0 {
+		sdk.Abort("donations cannot be empty if provided")
+	}
+	if len(accounts) > maxDonationRecipients {
+		sdk.Abort("at most " + strconv.Itoa(maxDonationRecipients) + " donation recipients allowed")
+	}
+
+	donations := make([]DonationRecipient, 0, len(accounts))
+	seen := make(map[sdk.Address]bool, len(accounts))
+	for i, value := range accounts {
+		account := parseDonationAccount(value)
+		if seen[account] {
+			sdk.Abort("duplicate donation account: " + account.String())
 		}
-		args.DonationPercent = donationPercent
+		seen[account] = true
 
-		// Validate total percentages don't exceed 90% so that at least 10% goes to winners
-		if burnPercent+donationPercent > 90.0 {
-			sdk.Abort("burn percent + donation percent must not exceed 90")
+		percent := parseBasisPoints(percents[i], "invalid donation percent")
+		if percent <= 0 {
+			sdk.Abort("donation percent must be greater than 0")
 		}
+		donations = append(donations, DonationRecipient{Account: account, Percent: percent})
 	}
+	checkDonationPercent(donationPercent(donations), burnPercent, cfg)
+	return donations
+}
 
-	// Parse optional metadata
-	if len(parts) == 6 {
-		args.MetaData = strings.TrimSpace(parts[5])
-	} else if len(parts) == 8 {
-		args.MetaData = strings.TrimSpace(parts[7])
+// parseCreatorFee parses the creator's share of the pool, which counts towards the burn + donation cap
+// together with the burn and donation rates already taken
+func parseCreatorFee(value string, taken BasisPoints, cfg *Config) BasisPoints {
+	creatorFee := parseBasisPoints(value, "invalid creator fee")
+	if creatorFee < 0 || creatorFee > cfg.MaxCreatorFeePercent {
+		sdk.Abort("creator fee must be between 0 and " + formatPercentLimit(cfg.MaxCreatorFeePercent))
 	}
-	if len(args.MetaData) > 500 {
-		sdk.Abort("metadata must be 500 characters or less")
+	if taken+creatorFee > cfg.MaxBurnAndDonation {
+		sdk.Abort("burn percent + donation percent + creator fee must not exceed " + formatPercentLimit(cfg.MaxBurnAndDonation))
 	}
+	return creatorFee
+}
 
-	return args
+// validateMetadata enforces the metadata size limit
+func validateMetadata(metaData string, cfg *Config) {
+	if uint64(len(metaData)) > cfg.MaxMetadataLength {
+		sdk.Abort("metadata must be " + strconv.FormatUint(cfg.MaxMetadataLength, 10) + " characters or less")
+	}
 }
 
 // parseChangeLotteryMetadata parses the payload for change_lottery_metadata
 // Format: lotteryID|metaData
 // Example: "1|New metadata for the lottery"
-func parseChangeLotteryMetadata(payload string) *ChangeLotteryMetadataArgs {
+// JSON: {"lottery_id":1,"metadata":"New metadata for the lottery"}
+func parseChangeLotteryMetadata(payload string, format PayloadFormat, cfg *Config) *ChangeLotteryMetadataArgs {
+	if format == PayloadFormatJSON {
+		var in ChangeLotteryMetadataJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid change_lottery_metadata JSON payload: " + err.Error())
+		}
+		validateLotteryID(in.LotteryID)
+		metaData := strings.TrimSpace(in.Metadata)
+		validateMetadata(metaData, cfg)
+		return &ChangeLotteryMetadataArgs{
+			LotteryID: in.LotteryID,
+			MetaData:  metaData,
+		}
+	}
+
 	parts := strings.SplitN(payload, "|", 2)
 	if len(parts) != 2 {
 		sdk.Abort("invalid change_lottery_metadata payload format: expected lotteryID|metaData")
 	}
 
-	lotteryID, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
-	if err != nil {
-		sdk.Abort("invalid lottery ID")
-	}
-	if lotteryID == 0 {
-		sdk.Abort("lottery ID must be greater than 0")
-	}
+	lotteryID := parseLotteryID(parts[0])
 
 	metaData := strings.TrimSpace(parts[1])
-	if len(metaData) > 500 {
-		sdk.Abort("metadata must be 500 characters or less")
-	}
+	validateMetadata(metaData, cfg)
 
 	return &ChangeLotteryMetadataArgs{
 		LotteryID: lotteryID,
@@ -176,61 +392,574 @@ func parseChangeLotteryMetadata(payload string) *ChangeLotteryMetadataArgs {
 // parseJoinLottery parses the payload for join_lottery
 // Format: lotteryID
 // Example: "1"
-func parseJoinLottery(payload string) *JoinLotteryArgs {
-	lotteryID, err := strconv.ParseUint(strings.TrimSpace(payload), 10, 64)
-	if err != nil {
-		sdk.Abort("invalid lottery ID")
-	}
-	if lotteryID == 0 {
-		sdk.Abort("lottery ID must be greater than 0")
+// JSON: {"lottery_id":1}
+func parseJoinLottery(payload string, format PayloadFormat) *JoinLotteryArgs {
+	if format == PayloadFormatJSON {
+		return &JoinLotteryArgs{
+			LotteryID: parseLotteryIDJSON(payload, "join_lottery"),
+		}
 	}
 
 	return &JoinLotteryArgs{
-		LotteryID: lotteryID,
+		LotteryID: parseLotteryID(payload),
 	}
 }
 
 // parseExecuteLottery parses the payload for execute_lottery
 // Format: lotteryID
 // Example: "1"
-func parseExecuteLottery(payload string) *ExecuteLotteryArgs {
-	lotteryID, err := strconv.ParseUint(strings.TrimSpace(payload), 10, 64)
-	if err != nil {
-		sdk.Abort("invalid lottery ID")
-	}
-	if lotteryID == 0 {
-		sdk.Abort("lottery ID must be greater than 0")
+// JSON: {"lottery_id":1}
+func parseExecuteLottery(payload string, format PayloadFormat) *ExecuteLotteryArgs {
+	if format == PayloadFormatJSON {
+		return &ExecuteLotteryArgs{
+			LotteryID: parseLotteryIDJSON(payload, "execute_lottery"),
+		}
 	}
 
 	return &ExecuteLotteryArgs{
+		LotteryID: parseLotteryID(payload),
+	}
+}
+
+// parseArchiveLottery parses the payload for archive_lottery
+// Format: lotteryID
+// Example: "1"
+// JSON: {"lottery_id":1}
+func parseArchiveLottery(payload string, format PayloadFormat) *ArchiveLotteryArgs {
+	if format == PayloadFormatJSON {
+		return &ArchiveLotteryArgs{
+			LotteryID: parseLotteryIDJSON(payload, "archive_lottery"),
+		}
+	}
+
+	return &ArchiveLotteryArgs{
+		LotteryID: parseLotteryID(payload),
+	}
+}
+
+// parseGetTicketProof parses the payload for get_ticket_proof
+// Format: lotteryID|address
+// Example: "1|hive:alice"
+// JSON: {"lottery_id":1,"address":"hive:alice"}
+func parseGetTicketProof(payload string, format PayloadFormat) *GetTicketProofArgs {
+	var lotteryID uint64
+	var address string
+	if format == PayloadFormatJSON {
+		var in GetTicketProofJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid get_ticket_proof JSON payload: " + err.Error())
+		}
+		validateLotteryID(in.LotteryID)
+		lotteryID, address = in.LotteryID, in.Address
+	} else {
+		parts := strings.Split(payload, "|")
+		if len(parts) != 2 {
+			sdk.Abort("invalid get_ticket_proof payload format: expected lotteryID|address")
+		}
+		lotteryID = parseLotteryID(parts[0])
+		address = parts[1]
+	}
+
+	address = strings.TrimSpace(address)
+	if address == "" {
+		sdk.Abort("address is required")
+	}
+	return &GetTicketProofArgs{
 		LotteryID: lotteryID,
+		Address:   sdk.Address(address),
 	}
 }
 
 // parseVerifyLottery parses the payload for verify_lottery
 // Format: lotteryID|seed
 // Example: "1|12345678901234567890"
-func parseVerifyLottery(payload string) *VerifyLotteryArgs {
+// JSON: {"lottery_id":1,"seed":"12345678901234567890"}
+func parseVerifyLottery(payload string, format PayloadFormat) *VerifyLotteryArgs {
+	if format == PayloadFormatJSON {
+		var in VerifyLotteryJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid verify_lottery JSON payload: " + err.Error())
+		}
+		validateLotteryID(in.LotteryID)
+		return &VerifyLotteryArgs{
+			LotteryID: in.LotteryID,
+			Seed:      in.Seed,
+		}
+	}
+
 	parts := strings.Split(payload, "|")
 	if len(parts) != 2 {
 		sdk.Abort("invalid verify_lottery payload format: expected lotteryID|seed")
 	}
 
-	lotteryID, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+	lotteryID := parseLotteryID(parts[0])
+
+	seed, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		sdk.Abort("invalid seed")
+	}
+
+	return &VerifyLotteryArgs{
+		LotteryID: lotteryID,
+		Seed:      seed,
+	}
+}
+
+// parseLotteryID parses a lottery ID given in pipe format
+func parseLotteryID(value string) uint64 {
+	lotteryID, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
 	if err != nil {
 		sdk.Abort("invalid lottery ID")
 	}
+	validateLotteryID(lotteryID)
+	return lotteryID
+}
+
+// parseLotteryIDJSON parses a {"lottery_id":<id>} payload for the given action
+func parseLotteryIDJSON(payload string, action string) uint64 {
+	var in LotteryIDJSON
+	if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+		sdk.Abort("invalid " + action + " JSON payload: " + err.Error())
+	}
+	validateLotteryID(in.LotteryID)
+	return in.LotteryID
+}
+
+// validateLotteryID rejects the zero ID, lottery IDs start at 1
+func validateLotteryID(lotteryID uint64) {
 	if lotteryID == 0 {
 		sdk.Abort("lottery ID must be greater than 0")
 	}
+}
 
-	seed, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+// parseMigrate parses the payload for migrate
+// Format: batchSize
+// Example: "500"
+// JSON: {"batch_size":500}
+func parseMigrate(payload string, format PayloadFormat) *MigrateArgs {
+	var batchSize uint64
+	if format == PayloadFormatJSON {
+		var in MigrateJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid migrate JSON payload: " + err.Error())
+		}
+		batchSize = in.BatchSize
+	} else {
+		var err error
+		batchSize, err = strconv.ParseUint(strings.TrimSpace(payload), 10, 64)
+		if err != nil {
+			sdk.Abort("invalid batch size")
+		}
+	}
+	if batchSize == 0 || batchSize > maxMigrateBatch {
+		sdk.Abort("batch size must be between 1 and " + strconv.Itoa(maxMigrateBatch))
+	}
+	return &MigrateArgs{BatchSize: batchSize}
+}
+
+// parsePause parses the payload for pause and unpause, name is the calling action for error messages.
+// The actions are returned deduplicated in the order of pausableActions, "all" selects every one.
+// Format: all | action,action,...
+// Example: "join_lottery,create_lottery"
+// JSON: {"actions":["join_lottery","create_lottery"]}
+func parsePause(payload string, format PayloadFormat, name string) *PauseArgs {
+	var requested []string
+	if format == PayloadFormatJSON {
+		var in PauseJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid " + name + " JSON payload: " + err.Error())
+		}
+		requested = in.Actions
+	} else {
+		requested = strings.Split(payload, ",")
+	}
+	if len(requested) == 0 {
+		sdk.Abort("at least one action is required")
+	}
+
+	selected := make(map[string]bool, len(requested))
+	for _, action := range requested {
+		action = strings.TrimSpace(action)
+		if action == "all" {
+			for _, a := range pausableActions {
+				selected[a] = true
+			}
+			continue
+		}
+		if !isPausableAction(action) {
+			sdk.Abort("action cannot be paused: " + action)
+		}
+		selected[action] = true
+	}
+
+	args := &PauseArgs{Actions: make([]string, 0, len(selected))}
+	for _, a := range pausableActions {
+		if selected[a] {
+			args.Actions = append(args.Actions, a)
+		}
+	}
+	return args
+}
+
+// parseSetConfig parses the payload for set_config and returns base with the given limits replaced.
+// Limits that are not mentioned keep their value, the result still has to pass validateConfig.
+// Format: key=value|key=value|...
+// Example: "max_deadline_hours=720|min_ticket_price=0.100"
+// JSON: {"max_deadline_hours":720,"min_ticket_price":"0.100"}
+func parseSetConfig(payload string, format PayloadFormat, base *Config) *Config {
+	var pairs [][2]string
+	if format == PayloadFormatJSON {
+		var in SetConfigJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid set_config JSON payload: " + err.Error())
+		}
+		str := func(key string, v *string) {
+			if v != nil {
+				pairs = append(pairs, [2]string{key, *v})
+			}
+		}
+		num := func(key string, v *uint64) {
+			if v != nil {
+				pairs = append(pairs, [2]string{key, strconv.FormatUint(*v, 10)})
+			}
+		}
+		str("min_burn_percent", in.MinBurnPercent)
+		str("max_burn_percent", in.MaxBurnPercent)
+		num("min_deadline_hours", in.MinDeadlineHours)
+		num("max_deadline_hours", in.MaxDeadlineHours)
+		str("max_donation_percent", in.MaxDonationPercent)
+		str("max_burn_donation_percent", in.MaxBurnDonationPercent)
+		str("min_ticket_price", in.MinTicketPrice)
+		num("max_name_length", in.MaxNameLength)
+		num("max_metadata_length", in.MaxMetadataLength)
+		str("protocol_fee_percent", in.ProtocolFeePercent)
+		str("max_creator_fee_percent", in.MaxCreatorFeePercent)
+	} else {
+		for _, part := range strings.Split(payload, "|") {
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				sdk.Abort("invalid set_config payload format: expected key=value")
+			}
+			key = strings.TrimSpace(key)
+			for _, kv := range pairs {
+				if kv[0] == key {
+					sdk.Abort("duplicate config key: " + key)
+				}
+			}
+			pairs = append(pairs, [2]string{key, value})
+		}
+	}
+	if len(pairs) == 0 {
+		sdk.Abort("at least one config value is required")
+	}
+
+	cfg := *base
+	for _, kv := range pairs {
+		key, value := kv[0], strings.TrimSpace(kv[1])
+		switch key {
+		case "min_burn_percent":
+			cfg.MinBurnPercent = parseConfigPercent(key, value)
+		case "max_burn_percent":
+			cfg.MaxBurnPercent = parseConfigPercent(key, value)
+		case "min_deadline_hours":
+			cfg.MinDeadlineHours = parseConfigUint(key, value)
+		case "max_deadline_hours":
+			cfg.MaxDeadlineHours = parseConfigUint(key, value)
+		case "max_donation_percent":
+			cfg.MaxDonationPercent = parseConfigPercent(key, value)
+		case "max_burn_donation_percent":
+			cfg.MaxBurnAndDonation = parseConfigPercent(key, value)
+		case "min_ticket_price":
+			price, err := ParseAmount(value, sdk.AssetHive)
+			if err != nil {
+				sdk.Abort("invalid min_ticket_price")
+			}
+			cfg.MinTicketPrice = price
+		case "max_name_length":
+			cfg.MaxNameLength = parseConfigUint(key, value)
+		case "max_metadata_length":
+			cfg.MaxMetadataLength = parseConfigUint(key, value)
+		case "protocol_fee_percent":
+			cfg.ProtocolFee = parseConfigPercent(key, value)
+		case "max_creator_fee_percent":
+			cfg.MaxCreatorFeePercent = parseConfigPercent(key, value)
+		default:
+			sdk.Abort("unknown config key: " + key)
+		}
+	}
+	return &cfg
+}
+
+// parseWithdrawTreasury parses the payload for withdraw_treasury
+// Format: asset|amount[|recipient]
+// Example: "hive|12.500" or "hive|12.500|hive:ops"
+// JSON: {"asset":"hive","amount":"12.500","to":"hive:ops"}
+func parseWithdrawTreasury(payload string, format PayloadFormat) *WithdrawTreasuryArgs {
+	var asset, amount, recipient string
+	if format == PayloadFormatJSON {
+		var in WithdrawTreasuryJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid withdraw_treasury JSON payload: " + err.Error())
+		}
+		asset, amount, recipient = in.Asset, in.Amount, in.To
+	} else {
+		parts := strings.Split(payload, "|")
+		if len(parts) < 2 || len(parts) > 3 {
+			sdk.Abort("invalid withdraw_treasury payload format: expected asset|amount[|recipient]")
+		}
+		asset, amount = parts[0], parts[1]
+		if len(parts) == 3 {
+			recipient = parts[2]
+		}
+	}
+
+	args := &WithdrawTreasuryArgs{Asset: sdk.Asset(strings.ToLower(strings.TrimSpace(asset)))}
+	value, err := ParseAmount(strings.TrimSpace(amount), args.Asset)
+	switch err {
+	case nil:
+	case errAmountAsset:
+		sdk.Abort("unsupported asset: " + args.Asset.String())
+	case errAmountDecimals:
+		sdk.Abort("amount must have at most 3 decimals")
+	default:
+		sdk.Abort("invalid amount")
+	}
+	if value <= 0 {
+		sdk.Abort("amount must be greater than 0")
+	}
+	args.Amount = value
+
+	if recipient = strings.TrimSpace(recipient); recipient != "" {
+		args.Recipient = sdk.Address(recipient)
+		if !payableAddress(args.Recipient) {
+			sdk.Abort("invalid recipient address")
+		}
+	}
+	return args
+}
+
+// parseSetPayoutPreference parses the payload for set_payout_preference
+// Format: preference or lotteryID|preference, preference is transfer, withdraw or default
+// Example: "withdraw" or "1|transfer"
+// JSON: {"lottery_id":1,"preference":"transfer"}, lottery_id may be left out
+func parseSetPayoutPreference(payload string, format PayloadFormat) *SetPayoutPreferenceArgs {
+	args := &SetPayoutPreferenceArgs{}
+	var preference string
+	if format == PayloadFormatJSON {
+		var in SetPayoutPreferenceJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid set_payout_preference JSON payload: " + err.Error())
+		}
+		args.LotteryID, preference = in.LotteryID, in.Preference
+	} else {
+		parts := strings.Split(payload, "|")
+		switch len(parts) {
+		case 1:
+			preference = parts[0]
+		case 2:
+			args.LotteryID = parseLotteryID(parts[0])
+			preference = parts[1]
+		default:
+			sdk.Abort("invalid set_payout_preference payload format: expected [lotteryID|]preference")
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(preference)) {
+	case PayoutPreferenceDefault.String():
+		args.Preference = PayoutPreferenceDefault
+	case PayoutPreferenceTransfer.String():
+		args.Preference = PayoutPreferenceTransfer
+	case PayoutPreferenceWithdraw.String():
+		args.Preference = PayoutPreferenceWithdraw
+	default:
+		sdk.Abort("payout preference must be transfer, withdraw or default")
+	}
+	return args
+}
+
+// parseGetPayoutPreference parses the payload for get_payout_preference
+// Format: address or address|lotteryID
+// Example: "hive:alice" or "hive:alice|1"
+// JSON: {"address":"hive:alice","lottery_id":1}, lottery_id may be left out
+func parseGetPayoutPreference(payload string, format PayloadFormat) *GetPayoutPreferenceArgs {
+	args := &GetPayoutPreferenceArgs{}
+	var address string
+	if format == PayloadFormatJSON {
+		var in GetPayoutPreferenceJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid get_payout_preference JSON payload: " + err.Error())
+		}
+		address, args.LotteryID = in.Address, in.LotteryID
+	} else {
+		parts := strings.Split(payload, "|")
+		if len(parts) > 2 {
+			sdk.Abort("invalid get_payout_preference payload format: expected address[|lotteryID]")
+		}
+		address = parts[0]
+		if len(parts) == 2 {
+			args.LotteryID = parseLotteryID(parts[1])
+		}
+	}
+
+	args.Address = sdk.Address(strings.TrimSpace(address))
+	if args.Address == "" {
+		sdk.Abort("address is required")
+	}
+	return args
+}
+
+// Bounds of a charity registry entry
+const (
+	maxCharityNameLength = 100
+	maxCharityURLLength  = 200
+)
+
+// parseRegisterCharity parses the payload for register_charity
+// Format: address|name|url
+// Example: "hive:oceandao|Ocean DAO|https://oceandao.org"
+// JSON: {"address":"hive:oceandao","name":"Ocean DAO","url":"https://oceandao.org"}
+func parseRegisterCharity(payload string, format PayloadFormat) *RegisterCharityArgs {
+	var address, name, url string
+	if format == PayloadFormatJSON {
+		var in RegisterCharityJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid register_charity JSON payload: " + err.Error())
+		}
+		address, name, url = in.Address, in.Name, in.URL
+	} else {
+		parts := strings.Split(payload, "|")
+		if len(parts) != 3 {
+			sdk.Abort("invalid register_charity payload format: expected address|name|url")
+		}
+		address, name, url = parts[0], parts[1], parts[2]
+	}
+
+	args := &RegisterCharityArgs{
+		Address: sdk.Address(strings.TrimSpace(address)),
+		Name:    strings.TrimSpace(name),
+		URL:     strings.TrimSpace(url),
+	}
+	if !payableAddress(args.Address) {
+		sdk.Abort("invalid charity address")
+	}
+	if args.Name == "" || len(args.Name) > maxCharityNameLength {
+		sdk.Abort("charity name must be between 1 and " + strconv.Itoa(maxCharityNameLength) + " characters")
+	}
+	if strings.Contains(args.Name, "|") {
+		sdk.Abort("charity name cannot contain pipe character")
+	}
+	if !strings.HasPrefix(args.URL, "https://") || len(args.URL) == len("https://") || strings.ContainsAny(args.URL, " |") {
+		sdk.Abort("charity URL must be an https:// link")
+	}
+	if len(args.URL) > maxCharityURLLength {
+		sdk.Abort("charity URL must be " + strconv.Itoa(maxCharityURLLength) + " characters or less")
+	}
+	return args
+}
+
+// parseRemoveCharity parses the payload for remove_charity
+// Format: charityID
+// Example: "3"
+// JSON: {"charity_id":3}
+func parseRemoveCharity(payload string, format PayloadFormat) uint64 {
+	id := uint64(0)
+	if format == PayloadFormatJSON {
+		var in CharityIDJSON
+		if err := in.UnmarshalJSON([]byte(payload)); err != nil {
+			sdk.Abort("invalid remove_charity JSON payload: " + err.Error())
+		}
+		id = in.CharityID
+	} else {
+		v, err := strconv.ParseUint(strings.TrimSpace(payload), 10, 64)
+		if err != nil {
+			sdk.Abort("invalid charity ID")
+		}
+		id = v
+	}
+	if id == 0 {
+		sdk.Abort("charity ID must be greater than 0")
+	}
+	return id
+}
+
+// parseAcceptDonation parses the payload for accept_donation
+// Format: lotteryID
+// Example: "1"
+// JSON: {"lottery_id":1}
+func parseAcceptDonation(payload string, format PayloadFormat) *AcceptDonationArgs {
+	if format == PayloadFormatJSON {
+		return &AcceptDonationArgs{
+			LotteryID: parseLotteryIDJSON(payload, "accept_donation"),
+		}
+	}
+
+	return &AcceptDonationArgs{
+		LotteryID: parseLotteryID(payload),
+	}
+}
+
+// parseConfigPercent parses a percentage limit between 0 and 100
+func parseConfigPercent(key string, value string) BasisPoints {
+	bps := parseBasisPoints(value, "invalid "+key)
+	if bps < 0 || bps > BasisPointsScale {
+		sdk.Abort("invalid " + key)
+	}
+	return bps
+}
+
+// parseConfigUint parses a whole-number limit
+func parseConfigUint(key string, value string) uint64 {
+	v, err := strconv.ParseUint(value, 10, 64)
 	if err != nil {
-		sdk.Abort("invalid seed")
+		sdk.Abort("invalid " + key)
 	}
+	return v
+}
 
-	return &VerifyLotteryArgs{
-		LotteryID: lotteryID,
-		Seed:      seed,
+// parseGetCreatorStats parses the payload for get_creator_stats
+// Format: creator
+// Example: "hive:alice"
+func parseGetCreatorStats(payload string) sdk.Address {
+	creator := strings.TrimSpace(payload)
+	if creator == "" {
+		sdk.Abort("creator address is required")
+	}
+	return sdk.Address(creator)
+}
+
+// parseGetCreatorLotteries parses the payload for get_creator_lotteries
+// Format: creator[|offset[|limit]]
+// Example: "hive:alice" or "hive:alice|20|10"
+func parseGetCreatorLotteries(payload string) *GetCreatorLotteriesArgs {
+	parts := strings.Split(payload, "|")
+	if len(parts) > 3 {
+		sdk.Abort("invalid get_creator_lotteries payload format: expected creator[|offset[|limit]]")
 	}
+
+	args := &GetCreatorLotteriesArgs{
+		Creator: parseGetCreatorStats(parts[0]),
+		Offset:  0,
+		Limit:   maxCreatorLotteriesPage,
+	}
+
+	if len(parts) > 1 {
+		offset, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			sdk.Abort("invalid offset")
+		}
+		args.Offset = offset
+	}
+
+	if len(parts) > 2 {
+		limit, err := strconv.ParseUint(strings.TrimSpace(parts[2]), 10, 64)
+		if err != nil {
+			sdk.Abort("invalid limit")
+		}
+		if limit == 0 || limit > maxCreatorLotteriesPage {
+			sdk.Abort("limit must be between 1 and 100")
+		}
+		args.Limit = limit
+	}
+
+	return args
 }
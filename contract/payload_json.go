@@ -0,0 +1,136 @@
+package main
+
+// JSON payload shapes accepted alongside the pipe format.
+// Decoders are generated by tinyjson into payload_json_tinyjson.go, regenerate after changing these types.
+// tinyjson has no float support, so percentages and amounts are decimal strings parsed by the same
+// helpers as the pipe format.
+
+// CreateLotteryJSON is the JSON form of the create_lottery payload
+//
+//tinyjson:json
+type CreateLotteryJSON struct {
+	Name            string   `json:"name"`
+	DeadlineHours   uint64   `json:"deadline_hours"`
+	BurnPercent     string   `json:"burn_percent"`
+	WinnerShares    []string `json:"winner_shares"`
+	TicketPrice     string   `json:"ticket_price"`
+	DonationAccount string   `json:"donation_account"`
+	DonationPercent string   `json:"donation_percent"`
+	Metadata        string   `json:"metadata"`
+	MaxTickets      *uint64  `json:"max_tickets"`
+	CreatorFee      string   `json:"creator_fee"`
+
+	Donations []DonationJSON `json:"donations"` // several recipients instead of the donation pair
+}
+
+// DonationJSON is one donation recipient in the create_lottery JSON payload
+//
+//tinyjson:json
+type DonationJSON struct {
+	Account string `json:"account"`
+	Percent string `json:"percent"`
+}
+
+// LotteryIDJSON is the JSON form of the join_lottery, execute_lottery, archive_lottery and accept_donation payloads
+//
+//tinyjson:json
+type LotteryIDJSON struct {
+	LotteryID uint64 `json:"lottery_id"`
+}
+
+// ChangeLotteryMetadataJSON is the JSON form of the change_lottery_metadata payload
+//
+//tinyjson:json
+type ChangeLotteryMetadataJSON struct {
+	LotteryID uint64 `json:"lottery_id"`
+	Metadata  string `json:"metadata"`
+}
+
+// VerifyLotteryJSON is the JSON form of the verify_lottery payload.
+// The seed is a string because it does not fit into a JavaScript number.
+//
+//tinyjson:json
+type VerifyLotteryJSON struct {
+	LotteryID uint64 `json:"lottery_id"`
+	Seed      uint64 `json:"seed,string"`
+}
+
+// MigrateJSON is the JSON form of the migrate payload
+//
+//tinyjson:json
+type MigrateJSON struct {
+	BatchSize uint64 `json:"batch_size"`
+}
+
+// PauseJSON is the JSON form of the pause and unpause payloads
+//
+//tinyjson:json
+type PauseJSON struct {
+	Actions []string `json:"actions"`
+}
+
+// SetConfigJSON is the JSON form of the set_config payload, omitted fields keep their value
+//
+//tinyjson:json
+type SetConfigJSON struct {
+	MinBurnPercent         *string `json:"min_burn_percent"`
+	MaxBurnPercent         *string `json:"max_burn_percent"`
+	MinDeadlineHours       *uint64 `json:"min_deadline_hours"`
+	MaxDeadlineHours       *uint64 `json:"max_deadline_hours"`
+	MaxDonationPercent     *string `json:"max_donation_percent"`
+	MaxBurnDonationPercent *string `json:"max_burn_donation_percent"`
+	MinTicketPrice         *string `json:"min_ticket_price"`
+	MaxNameLength          *uint64 `json:"max_name_length"`
+	MaxMetadataLength      *uint64 `json:"max_metadata_length"`
+	ProtocolFeePercent     *string `json:"protocol_fee_percent"`
+	MaxCreatorFeePercent   *string `json:"max_creator_fee_percent"`
+}
+
+// WithdrawTreasuryJSON is the JSON form of the withdraw_treasury payload
+//
+//tinyjson:json
+type WithdrawTreasuryJSON struct {
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+	To     string `json:"to"`
+}
+
+// SetPayoutPreferenceJSON is the JSON form of the set_payout_preference payload
+//
+//tinyjson:json
+type SetPayoutPreferenceJSON struct {
+	LotteryID  uint64 `json:"lottery_id"`
+	Preference string `json:"preference"`
+}
+
+// GetPayoutPreferenceJSON is the JSON form of the get_payout_preference payload
+//
+//tinyjson:json
+type GetPayoutPreferenceJSON struct {
+	Address   string `json:"address"`
+	LotteryID uint64 `json:"lottery_id"`
+}
+
+// RegisterCharityJSON is the JSON form of the register_charity payload
+//
+//tinyjson:json
+type RegisterCharityJSON struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+}
+
+// CharityIDJSON is the JSON form of the remove_charity payload
+//
+//tinyjson:json
+type CharityIDJSON struct {
+	CharityID uint64 `json:"charity_id"`
+}
+
+// GetTicketProofJSON is the JSON form of the get_ticket_proof payload
+//
+//tinyjson:json
+type GetTicketProofJSON struct {
+	LotteryID uint64 `json:"lottery_id"`
+	Address   string `json:"address"`
+}
@@ -6,8 +6,32 @@ import (
 	"strings"
 )
 
+// PayloadFormat tells the parsers how an unwrapped payload is encoded.
+type PayloadFormat uint8
+
+const (
+	PayloadFormatPipe PayloadFormat = 0 // positional fields separated by |
+	PayloadFormatJSON PayloadFormat = 1 // JSON object with named fields
+)
+
 // unwrapPayload trims quotes and whitespace, aborting if the payload is empty.
-func unwrapPayload(payload *string, errMsg string) string {
+// Payloads that are a JSON object (raw or quoted) are reported as PayloadFormatJSON.
+func unwrapPayload(payload *string, errMsg string) (string, PayloadFormat) {
+	raw := unwrapPayloadString(payload, errMsg)
+	if isJSONObject(raw) {
+		return raw, PayloadFormatJSON
+	}
+	return raw, PayloadFormatPipe
+}
+
+// isJSONObject reports whether the payload looks like a JSON object.
+// A pipe payload is only taken for JSON if its first field starts with '{' and its last field ends with '}'.
+func isJSONObject(raw string) bool {
+	return len(raw) >= 2 && raw[0] == '{' && raw[len(raw)-1] == '}'
+}
+
+// unwrapPayloadString trims quotes and whitespace, aborting if the payload is empty.
+func unwrapPayloadString(payload *string, errMsg string) string {
 	if payload == nil {
 		sdk.Abort(errMsg)
 	}
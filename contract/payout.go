@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+)
+
+// Every amount leaving the contract goes through payout, which picks the ledger operation the
+// recipient's address supports instead of assuming a Hive account:
+//
+//	hive:             L1 withdrawal when requested, internal transfer otherwise
+//	did:pkh, did:key  internal transfer, they have no Hive L1 wallet to withdraw to
+//	contract:         deposit hook, the contract draws the amount itself so it can book it
+//
+// A deposit hook runs code of another contract in the middle of a payout, so every action that
+// changes state aborts while one runs (requireNoDeposit). Burns keep using host.Withdraw to
+// hive:null directly.
+
+// depositHookMethod is the action called on a contract that receives a payout. It comes with a
+// transfer.allow intent for the amount and a payload naming what is paid, e.g. "prize|7".
+const depositHookMethod = "deposit"
+
+// contractAddressPrefix marks addresses of other contracts on the network
+const contractAddressPrefix = "contract:"
+
+// payableAddress reports whether the contract can pay out to an address: a user account the SDK
+// recognises (IsValid, which also covers system: addresses that cannot receive) or another contract,
+// which IsValid does not cover.
+func payableAddress(a sdk.Address) bool {
+	if a.Domain() == sdk.AddressDomainContract {
+		return validContractID(strings.TrimPrefix(a.String(), contractAddressPrefix))
+	}
+	return a.IsValid() && a.Domain() == sdk.AddressDomainUser
+}
+
+// validContractID reports whether id is a contract ID of the network: letters and digits only, so
+// it can neither break an event line (',', '|', ':', CR, LF) nor the deposit hook call.
+func validContractID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// payout sends amount of asset from the contract to an account. withdraw asks for an L1 withdrawal,
+// it only applies to hive: accounts. reference is the deposit hook payload for contract recipients.
+func payout(to sdk.Address, amount Amount, asset sdk.Asset, withdraw bool, reference string) {
+	if amount <= 0 {
+		return
+	}
+	switch {
+	case to.Domain() == sdk.AddressDomainContract:
+		depositToContract(to, amount, asset, reference)
+	case withdraw && to.Type() == sdk.AddressTypeHive:
+		host.Withdraw(to, AmountToInt64(amount), asset)
+	default:
+		host.Transfer(to, AmountToInt64(amount), asset)
+	}
+}
+
+// depositToContract calls the deposit hook of a receiving contract and lets it draw the amount.
+// Whatever the hook leaves is transferred directly, so a contract that draws less cannot strand
+// funds in this contract or hold up an execution.
+func depositToContract(to sdk.Address, amount Amount, asset sdk.Asset, reference string) {
+	self := selfAddress()
+	before := host.Balance(self, asset)
+
+	host.StateSet(getDepositLockKey(), to.String())
+	options := &sdk.ContractCallOptions{Intents: []sdk.Intent{{
+		Type: "transfer.allow",
+		Args: map[string]string{"limit": events.Amount(amount).String(), "token": asset.String()},
+	}}}
+	host.ContractCall(strings.TrimPrefix(to.String(), contractAddressPrefix), depositHookMethod, reference, options)
+	host.StateDelete(getDepositLockKey())
+
+	drawn := before - host.Balance(self, asset)
+	if drawn < 0 || drawn > AmountToInt64(amount) {
+		sdk.Abort("deposit hook of " + to.String() + " changed the contract balance beyond the payout")
+	}
+	if rest := AmountToInt64(amount) - drawn; rest > 0 {
+		host.Transfer(to, rest, asset)
+	}
+}
+
+// requireNoDeposit aborts while a deposit hook runs. It guards every action that changes state: a
+// hook calling back in could otherwise pay into the balance its payout is measured by, pay out of
+// it or act on a lottery whose execution is only half booked.
+func requireNoDeposit() {
+	if host.StateGet(getDepositLockKey()) != nil {
+		sdk.Abort("payout to a contract in progress")
+	}
+}
+
+// selfAddress returns the ledger address of this contract
+func selfAddress() sdk.Address {
+	id := currentEnv().ContractId
+	if id == "" {
+		if idPtr := host.EnvKey("contract.id"); idPtr != nil {
+			id = *idPtr
+		}
+	}
+	if sdk.Address(id).Domain() != sdk.AddressDomainContract {
+		id = contractAddressPrefix + id
+	}
+	return sdk.Address(id)
+}
+
+// payoutReference builds the deposit hook payload for a payout of a lottery
+func payoutReference(kind string, lotteryID uint64) string {
+	return kind + "|" + strconv.FormatUint(lotteryID, 10)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPayoutRouting tests that every recipient is paid with the operation its address type supports
+func TestPayoutRouting(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Routes|24|10|50,30,20|1.000|donations=hive:ocean=10,did:key:z6Mk=5,contract:dao=5|creator_fee=5", "did:pkh:eip155:1:0xabc")
+	a.f.acceptDonations(t, 1)
+	for _, player := range []string{"hive:alice", "did:key:z6Bob", "contract:player"} {
+		a.f.fund(player, 1_000)
+		a.mustCall(t, join_lottery, "1", player, transferAllow("1.000"))
+	}
+	a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+
+	hive := func(address sdk.Address) ledgerKey { return ledgerKey{address, sdk.AssetHive} }
+	// Hive donations are withdrawn to L1, everything else stays on the network
+	assert.Equal(t, int64(300), a.f.withdrawn[hive("hive:ocean")])
+	assert.Equal(t, int64(150), a.f.balances[hive("did:key:z6Mk")])
+	assert.Equal(t, int64(150), a.f.balances[hive("contract:dao")])
+	assert.Equal(t, int64(150), a.f.balances[hive("did:pkh:eip155:1:0xabc")])
+	assert.Zero(t, a.f.withdrawn[hive("hive:alice")])
+
+	meta := loadLotteryMetadata(1)
+	var prize Amount
+	for _, w := range meta.Winners {
+		if w.Address == "contract:player" {
+			prize = w.Amount
+		}
+	}
+	require.Len(t, a.f.calls, 2)
+	assert.Equal(t, contractCall{contractID: "dao", method: depositHookMethod, payload: "donation|1",
+		intents: []sdk.Intent{{Type: "transfer.allow", Args: map[string]string{"limit": "0.150", "token": "hive"}}}}, a.f.calls[0])
+	assert.Equal(t, "prize|1", a.f.calls[1].payload)
+	assert.Equal(t, events.Amount(prize).String(), a.f.calls[1].intents[0].Args["limit"])
+	assert.Nil(t, a.f.StateGet(getDepositLockKey()))
+}
+
+// TestDepositHookFallback tests a contract whose deposit hook does not draw
+func TestDepositHookFallback(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Hooks|24|10|100|1.000|contract:dao|10", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	a.f.fund("hive:alice", 1_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+
+	// A hook that draws nothing still gets its donation, transferred directly
+	a.f.hooks["dao"] = func(contractCall) {}
+	a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Equal(t, int64(100), a.f.Balance("contract:dao", sdk.AssetHive))
+	assert.Nil(t, a.f.StateGet(getDepositLockKey()))
+}
+
+// TestDepositHookGuard tests that a hook can neither call back into the contract nor keep a
+// half booked execution: both abort it as a whole
+func TestDepositHookGuard(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "Hooks|24|10|100|1.000|contract:dao|10", "hive:creator")
+	a.mustCall(t, create_lottery, "Other|24|10|100|1.000", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	a.f.fund("hive:alice", 2_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+	a.f.fund("contract:dao", 1_000)
+	a.f.at(fakeFuture)
+
+	callbacks := []struct {
+		fn      func(*string) *string
+		payload string
+	}{
+		{join_lottery, "2"},
+		{execute_lottery, "2"},
+		{execute_lottery, "1"},
+		{archive_lottery, "2"},
+		{create_lottery, "X|24|10|100|1.000"},
+		{set_payout_preference, "withdraw"},
+		{set_config, "max_name_length=50"},
+	}
+	for _, c := range callbacks {
+		a.f.hooks["dao"] = func(contractCall) {
+			payload := c.payload
+			c.fn(&payload)
+		}
+		// Executed by the owner so the owner actions get past their sender check
+		assert.Equal(t, "payout to a contract in progress", a.call(t, execute_lottery, "1", fakeOwner).Err, c.payload)
+	}
+
+	// A hook that aborts fails the execution, which is rolled back and can be retried
+	a.f.hooks["dao"] = func(contractCall) { sdk.Abort("deposit rejected") }
+	assert.Equal(t, "deposit rejected", a.call(t, execute_lottery, "1", "hive:executor").Err)
+	assert.Equal(t, LotteryStateActive, loadLotteryMetadata(1).State)
+	assert.Nil(t, a.f.StateGet(getDepositLockKey()))
+	assert.Equal(t, int64(1_000), a.f.Balance("contract:dao", sdk.AssetHive))
+
+	delete(a.f.hooks, "dao")
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+	assert.Equal(t, int64(1_100), a.f.Balance("contract:dao", sdk.AssetHive))
+}
+
+// TestPayableAddresses tests which addresses can be named as recipients
+func TestPayableAddresses(t *testing.T) {
+	f := newFakeHost(t)
+	for _, account := range []string{"hive:ocean", "did:key:z6Mk", "did:pkh:eip155:1:0xabc", "contract:dao"} {
+		assert.Empty(t, f.call(t, create_lottery, "X|24|10|100|1.000|"+account+"|5", "hive:creator").Err, account)
+	}
+	for _, account := range []string{"contract:", "system:fees", "ocean"} {
+		assert.Equal(t, "invalid donation account: "+account, f.call(t, create_lottery, "X|24|10|100|1.000|"+account+"|5", "hive:creator").Err)
+	}
+	// Contract IDs cannot carry separators into the event lines or the hook call
+	for _, account := range []string{"contract:a,b", "contract:a|burn:0", "contract:a:b", "contract:a\nb"} {
+		payload := `{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donations":[{"account":` + strconv.Quote(account) + `,"percent":"5"}]}`
+		assert.Equal(t, "invalid donation account: "+account, f.call(t, create_lottery, payload, "hive:creator").Err, account)
+	}
+	assert.Equal(t, "invalid recipient address", f.call(t, withdraw_treasury, "hive|1.000|system:fees", fakeOwner).Err)
+}
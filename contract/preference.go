@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strconv"
+
+	"okinoko_lottery/sdk"
+)
+
+// Winners choose how they receive prizes: kept on the Magi Network (transfer, the default) or
+// withdrawn to their Hive L1 wallet. A preference can be set for all lotteries under pp:<address>
+// and overridden for a single lottery under ppl:<id>:<address>; execution reads the lottery's
+// first, then the account's. Only hive: accounts have an L1 wallet to withdraw to, and only
+// participants can set one for a lottery, so archiving removes every ppl: key with the lottery.
+
+// resolvePayoutPreference returns the preference that applies to an account's payouts from a lottery
+func resolvePayoutPreference(lotteryID uint64, address sdk.Address) (PayoutPreference, string) {
+	if p := loadPayoutPreference(getLotteryPayoutPreferenceKey(lotteryID, address.String())); p != PayoutPreferenceDefault {
+		return p, "lottery"
+	}
+	if p := loadPayoutPreference(getPayoutPreferenceKey(address.String())); p != PayoutPreferenceDefault {
+		return p, "account"
+	}
+	return PayoutPreferenceTransfer, "default"
+}
+
+// prefersWithdraw reports whether an account asked for its payouts from a lottery on Hive L1
+func prefersWithdraw(lotteryID uint64, address sdk.Address) bool {
+	p, _ := resolvePayoutPreference(lotteryID, address)
+	return p == PayoutPreferenceWithdraw
+}
+
+//export set_payout_preference
+func set_payout_preference(payload *string) *string {
+	requireCurrentSchema()
+	requireNotPaused("set_payout_preference")
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "set_payout_preference payload missing")
+	args := parseSetPayoutPreference(payloadStr, format)
+
+	sender := getSenderAddress()
+	if args.Preference == PayoutPreferenceWithdraw && sender.Type() != sdk.AddressTypeHive {
+		sdk.Abort("only hive: accounts can withdraw to Hive L1")
+	}
+
+	key := getPayoutPreferenceKey(sender.String())
+	if args.LotteryID != 0 {
+		meta := loadLotteryMetadata(args.LotteryID)
+		if meta == nil {
+			sdk.Abort("lottery not found")
+		}
+		if meta.State != LotteryStateActive {
+			sdk.Abort("lottery is not active")
+		}
+		if loadParticipantIndex(args.LotteryID, sender.String()) == 0 {
+			sdk.Abort("only participants can set a preference for a lottery")
+		}
+		key = getLotteryPayoutPreferenceKey(args.LotteryID, sender.String())
+	}
+	savePayoutPreference(key, args.Preference)
+
+	ret := "payout preference set to " + args.Preference.String()
+	if args.LotteryID != 0 {
+		ret += " for lottery " + strconv.FormatUint(args.LotteryID, 10)
+	}
+	return &ret
+}
+
+//export get_payout_preference
+func get_payout_preference(payload *string) *string {
+	requireCurrentSchema()
+	payloadStr, format := unwrapPayload(payload, "get_payout_preference payload missing")
+	args := parseGetPayoutPreference(payloadStr, format)
+
+	var p PayoutPreference
+	var source string
+	if args.LotteryID != 0 {
+		p, source = resolvePayoutPreference(args.LotteryID, args.Address)
+	} else if p = loadPayoutPreference(getPayoutPreferenceKey(args.Address.String())); p != PayoutPreferenceDefault {
+		source = "account"
+	} else {
+		p, source = PayoutPreferenceTransfer, "default"
+	}
+
+	ret := "preference:" + p.String() + "|source:" + source
+	return &ret
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"okinoko_lottery/sdk"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPayoutPreference tests that prizes follow the lottery's, then the account's preference
+func TestPayoutPreference(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, create_lottery, "One|24|10|100|1.000|creator_fee=5", "hive:creator")
+	a.mustCall(t, create_lottery, "Two|24|10|100|1.000", "hive:creator")
+
+	a.f.fund("hive:alice", 2_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("1.000"))
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+	assert.Equal(t, "only participants can set a preference for a lottery", a.call(t, set_payout_preference, "1|withdraw", "hive:creator").Err)
+
+	assert.Equal(t, "preference:transfer|source:default", a.mustCall(t, get_payout_preference, "hive:alice", "hive:anyone").Ret)
+	assert.Equal(t, "payout preference set to withdraw", a.mustCall(t, set_payout_preference, "withdraw", "hive:alice").Ret)
+	assert.Equal(t, "payout preference set to transfer for lottery 2", a.mustCall(t, set_payout_preference, `{"lottery_id":2,"preference":"transfer"}`, "hive:alice").Ret)
+	a.mustCall(t, set_payout_preference, "withdraw", "hive:creator")
+
+	assert.Equal(t, "preference:withdraw|source:account", a.mustCall(t, get_payout_preference, "hive:alice|1", "hive:anyone").Ret)
+	assert.Equal(t, "preference:transfer|source:lottery", a.mustCall(t, get_payout_preference, `{"address":"hive:alice","lottery_id":2}`, "hive:anyone").Ret)
+	assert.Equal(t, "preference:withdraw|source:account", a.mustCall(t, get_payout_preference, "hive:creator|1", "hive:anyone").Ret)
+
+	a.f.at(fakeFuture)
+	a.mustCall(t, execute_lottery, "1", "hive:executor")
+	a.mustCall(t, execute_lottery, "2", "hive:executor")
+
+	hive := func(address sdk.Address) ledgerKey { return ledgerKey{address, sdk.AssetHive} }
+	assert.Equal(t, int64(850), a.f.withdrawn[hive("hive:alice")])
+	assert.Equal(t, int64(900), a.f.balances[hive("hive:alice")])
+	// The creator fee is no prize, it is transferred whatever the creator prefers
+	assert.Zero(t, a.f.withdrawn[hive("hive:creator")])
+	assert.Equal(t, int64(50), a.f.balances[hive("hive:creator")])
+	assert.Equal(t, "lottery is not active", a.call(t, set_payout_preference, "1|transfer", "hive:alice").Err)
+
+	// default clears the preference again
+	a.mustCall(t, set_payout_preference, "default", "hive:alice")
+	assert.Nil(t, a.f.StateGet(getPayoutPreferenceKey("hive:alice")))
+}
+
+// TestPayoutPreferenceValidation tests the payloads and who may withdraw
+func TestPayoutPreferenceValidation(t *testing.T) {
+	f := newFakeHost(t)
+	f.mustCall(t, create_lottery, "One|24|10|100|1.000", "hive:creator")
+	f.mustCall(t, set_payout_preference, "transfer", "did:key:z6Mk")
+
+	tests := []struct {
+		payload string
+		sender  string
+		err     string
+	}{
+		{"withdraw", "did:key:z6Mk", "only hive: accounts can withdraw to Hive L1"},
+		{"1|withdraw", "contract:dao", "only hive: accounts can withdraw to Hive L1"},
+		{"l1", "hive:alice", "payout preference must be transfer, withdraw or default"},
+		{"1|2|withdraw", "hive:alice", "invalid set_payout_preference payload format: expected [lotteryID|]preference"},
+		{"x|withdraw", "hive:alice", "invalid lottery ID"},
+		{"2|withdraw", "hive:alice", "lottery not found"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, set_payout_preference, tt.payload, tt.sender).Err, tt.payload)
+	}
+	assert.Equal(t, "address is required", f.call(t, get_payout_preference, " |1", "hive:alice").Err)
+}
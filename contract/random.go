@@ -93,18 +93,19 @@ func (r *hashRandom) intn(n int) int {
 	}
 }
 
-// selectRandomWinners picks random winners from weighted ticket pool
-// Returns winner addresses and their ticket counts
-func selectRandomWinners(participants map[string]uint64, totalTickets uint64, winnerCount int, seed uint64) []sdk.Address {
+// selectRandomWinners picks random winners from weighted ticket pool.
+// The pool is built in participant order (first-join order, each participant's tickets contiguous)
+// so the draw can be replayed off-chain from the public participant list, see the verifier package.
+func selectRandomWinners(participants []ParticipantEntry, totalTickets uint64, winnerCount int, seed uint64) []sdk.Address {
 	if winnerCount == 0 || totalTickets == 0 {
 		return []sdk.Address{}
 	}
 
 	// Build weighted ticket pool
 	ticketPool := make([]sdk.Address, 0, totalTickets)
-	for addr, count := range participants {
-		for i := uint64(0); i < count; i++ {
-			ticketPool = append(ticketPool, sdk.Address(addr))
+	for _, p := range participants {
+		for i := uint64(0); i < p.Tickets; i++ {
+			ticketPool = append(ticketPool, sdk.Address(p.Address))
 		}
 	}
 
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"okinoko_lottery/verifier"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDrawMatchesOfflineVerifier pins selectRandomWinners to the independent implementation
+// in the verifier package over random participant lists, seeds and winner counts
+func TestDrawMatchesOfflineVerifier(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for round := 0; round < 300; round++ {
+		participants := make([]ParticipantEntry, 1+rng.IntN(20))
+		list := make([]verifier.Participant, len(participants))
+		total := uint64(0)
+		for i := range participants {
+			participants[i] = ParticipantEntry{Address: "hive:p" + strconv.Itoa(i), Tickets: 1 + rng.Uint64N(10)}
+			list[i] = verifier.Participant{Address: participants[i].Address, Tickets: participants[i].Tickets}
+			total += participants[i].Tickets
+		}
+		seed := rng.Uint64()
+		winnerCount := 1 + rng.IntN(5)
+
+		got := selectRandomWinners(participants, total, winnerCount, seed)
+		want := verifier.SelectWinners(list, winnerCount, seed)
+		if !assert.Len(t, got, len(want), "round %d", round) {
+			continue
+		}
+		for i := range want {
+			assert.Equal(t, want[i].Address, got[i].String(), "round %d position %d", round, i+1)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"okinoko_lottery/sdk"
 	"strconv"
+	"strings"
 )
 
 // Gas-optimized state key builders
@@ -33,15 +34,80 @@ func getParticipantLookupKey(lotteryID uint64, address string) string {
 	return "lpu:" + strconv.FormatUint(lotteryID, 10) + ":" + address
 }
 
+// getCreatorStatsKey returns the storage key for a creator's lifetime aggregates
+func getCreatorStatsKey(creator string) string {
+	return "cs:" + creator
+}
+
+// getCreatorLotteryKey returns the storage key for the n-th (1-based) lottery ID of a creator
+func getCreatorLotteryKey(creator string, n uint64) string {
+	return "cl:" + creator + ":" + strconv.FormatUint(n, 10)
+}
+
 // getCounterKey returns the storage key for the lottery counter
 func getCounterKey() string {
 	return "counter"
 }
 
+// getSchemaVersionKey returns the storage key for the state schema version
+func getSchemaVersionKey() string {
+	return "schema"
+}
+
+// getMigrateCursorKey returns the storage key for the position of a running migration
+func getMigrateCursorKey() string {
+	return "migrate"
+}
+
+// getPausedKey returns the storage key for the list of actions paused by the contract owner
+func getPausedKey() string {
+	return "paused"
+}
+
+// getConfigKey returns the storage key for the protocol limits set by the contract owner
+func getConfigKey() string {
+	return "config"
+}
+
+// getTreasuryKey returns the storage key for the protocol fees collected in an asset
+func getTreasuryKey(asset sdk.Asset) string {
+	return "treasury:" + asset.String()
+}
+
+// getCharityKey returns the storage key for a charity registry entry by ID
+func getCharityKey(id uint64) string {
+	return "ch:" + strconv.FormatUint(id, 10)
+}
+
+// getCharityLookupKey returns the storage key for looking up a charity's ID by address
+func getCharityLookupKey(address string) string {
+	return "cha:" + address
+}
+
+// getCharityCounterKey returns the storage key for the charity ID counter
+func getCharityCounterKey() string {
+	return "charities"
+}
+
+// getPayoutPreferenceKey returns the storage key for an account's payout preference for all lotteries
+func getPayoutPreferenceKey(address string) string {
+	return "pp:" + address
+}
+
+// getLotteryPayoutPreferenceKey returns the storage key for an account's payout preference for one lottery
+func getLotteryPayoutPreferenceKey(lotteryID uint64, address string) string {
+	return "ppl:" + strconv.FormatUint(lotteryID, 10) + ":" + address
+}
+
+// getDepositLockKey returns the storage key that is set while a contract's deposit hook runs
+func getDepositLockKey() string {
+	return "deposit"
+}
+
 // loadLotteryMetadata retrieves lottery metadata from state
 func loadLotteryMetadata(id uint64) *LotteryMetadata {
 	key := getLotteryMetadataKey(id)
-	dataPtr := sdk.StateGetObject(key)
+	dataPtr := host.StateGet(key)
 	if dataPtr == nil || *dataPtr == "" {
 		return nil
 	}
@@ -52,13 +118,13 @@ func loadLotteryMetadata(id uint64) *LotteryMetadata {
 func saveLotteryMetadata(m *LotteryMetadata) {
 	key := getLotteryMetadataKey(m.ID)
 	data := encodeLotteryMetadata(m)
-	sdk.StateSetObject(key, data)
+	host.StateSet(key, data)
 }
 
 // loadLotteryMetadataValue retrieves the free-form lottery metadata string
 func loadLotteryMetadataValue(id uint64) string {
 	key := getLotteryMetadataValueKey(id)
-	dataPtr := sdk.StateGetObject(key)
+	dataPtr := host.StateGet(key)
 	if dataPtr == nil {
 		return ""
 	}
@@ -68,13 +134,13 @@ func loadLotteryMetadataValue(id uint64) string {
 // saveLotteryMetadataValue stores the free-form lottery metadata string
 func saveLotteryMetadataValue(id uint64, value string) {
 	key := getLotteryMetadataValueKey(id)
-	sdk.StateSetObject(key, value)
+	host.StateSet(key, value)
 }
 
 // loadLotteryPoolStats retrieves lottery pool statistics
 func loadLotteryPoolStats(id uint64) *LotteryPoolStats {
 	key := getLotteryPoolStatsKey(id)
-	dataPtr := sdk.StateGetObject(key)
+	dataPtr := host.StateGet(key)
 	if dataPtr == nil || *dataPtr == "" {
 		return &LotteryPoolStats{
 			Pool:             0,
@@ -89,13 +155,13 @@ func loadLotteryPoolStats(id uint64) *LotteryPoolStats {
 func saveLotteryPoolStats(id uint64, s *LotteryPoolStats) {
 	key := getLotteryPoolStatsKey(id)
 	data := encodeLotteryPoolStats(s)
-	sdk.StateSetObject(key, data)
+	host.StateSet(key, data)
 }
 
 // loadParticipantIndex retrieves a participant's index, returns 0 if not found
 func loadParticipantIndex(lotteryID uint64, address string) uint64 {
 	key := getParticipantLookupKey(lotteryID, address)
-	dataPtr := sdk.StateGetObject(key)
+	dataPtr := host.StateGet(key)
 	if dataPtr == nil || *dataPtr == "" {
 		return 0
 	}
@@ -109,13 +175,13 @@ func loadParticipantIndex(lotteryID uint64, address string) uint64 {
 // saveParticipantIndex stores a participant's index
 func saveParticipantIndex(lotteryID uint64, address string, index uint64) {
 	key := getParticipantLookupKey(lotteryID, address)
-	sdk.StateSetObject(key, strconv.FormatUint(index, 10))
+	host.StateSet(key, strconv.FormatUint(index, 10))
 }
 
 // loadParticipantEntry retrieves a participant entry by index
 func loadParticipantEntry(lotteryID uint64, index uint64) *ParticipantEntry {
 	key := getParticipantIndexKey(lotteryID, index)
-	dataPtr := sdk.StateGetObject(key)
+	dataPtr := host.StateGet(key)
 	if dataPtr == nil || *dataPtr == "" {
 		return nil
 	}
@@ -126,24 +192,60 @@ func loadParticipantEntry(lotteryID uint64, index uint64) *ParticipantEntry {
 func saveParticipantEntry(lotteryID uint64, index uint64, entry *ParticipantEntry) {
 	key := getParticipantIndexKey(lotteryID, index)
 	data := encodeParticipantEntry(entry)
-	sdk.StateSetObject(key, data)
+	host.StateSet(key, data)
 }
 
-// loadAllParticipants retrieves all participants for a lottery
-func loadAllParticipants(lotteryID uint64) map[string]uint64 {
+// loadAllParticipants retrieves all participants for a lottery in participant index (first-join) order
+func loadAllParticipants(lotteryID uint64) []ParticipantEntry {
 	stats := loadLotteryPoolStats(lotteryID)
-	participants := make(map[string]uint64, stats.ParticipantCount)
+	participants := make([]ParticipantEntry, 0, stats.ParticipantCount)
 
 	for i := uint64(1); i <= stats.ParticipantCount; i++ {
 		entry := loadParticipantEntry(lotteryID, i)
 		if entry != nil {
-			participants[entry.Address] = entry.Tickets
+			participants = append(participants, *entry)
 		}
 	}
 
 	return participants
 }
 
+// loadCreatorStats retrieves a creator's aggregates, returning zero values for unknown creators
+func loadCreatorStats(creator string) *CreatorStats {
+	key := getCreatorStatsKey(creator)
+	dataPtr := host.StateGet(key)
+	if dataPtr == nil || *dataPtr == "" {
+		return &CreatorStats{}
+	}
+	return decodeCreatorStats(*dataPtr)
+}
+
+// saveCreatorStats stores a creator's aggregates
+func saveCreatorStats(creator string, s *CreatorStats) {
+	key := getCreatorStatsKey(creator)
+	host.StateSet(key, encodeCreatorStats(s))
+}
+
+// loadCreatorLotteryID retrieves the n-th (1-based) lottery ID of a creator, returns 0 if not found
+func loadCreatorLotteryID(creator string, n uint64) uint64 {
+	key := getCreatorLotteryKey(creator, n)
+	dataPtr := host.StateGet(key)
+	if dataPtr == nil || *dataPtr == "" {
+		return 0
+	}
+	id, err := strconv.ParseUint(*dataPtr, 10, 64)
+	if err != nil {
+		sdk.Abort("invalid creator lottery index")
+	}
+	return id
+}
+
+// saveCreatorLotteryID stores the n-th (1-based) lottery ID of a creator
+func saveCreatorLotteryID(creator string, n uint64, lotteryID uint64) {
+	key := getCreatorLotteryKey(creator, n)
+	host.StateSet(key, strconv.FormatUint(lotteryID, 10))
+}
+
 // loadLottery retrieves a full lottery from state (loads both metadata and participants)
 func loadLottery(id uint64) *Lottery {
 	meta := loadLotteryMetadata(id)
@@ -156,29 +258,39 @@ func loadLottery(id uint64) *Lottery {
 
 	// Combine into full lottery struct
 	return &Lottery{
-		ID:              meta.ID,
-		Creator:         meta.Creator,
-		Name:            meta.Name,
-		CreatedAt:       meta.CreatedAt,
-		DeadlineHours:   meta.DeadlineHours,
-		DeadlineUnix:    meta.DeadlineUnix,
-		MaxTickets:      meta.MaxTickets,
-		BurnPercent:     meta.BurnPercent,
-		TicketPrice:     meta.TicketPrice,
-		Asset:           meta.Asset,
-		WinnerShares:    meta.WinnerShares,
-		Pool:            stats.Pool,
-		Participants:    participants,
-		State:           meta.State,
-		Winners:         meta.Winners,
-		ExecutedAt:      meta.ExecutedAt,
-		RandomSeed:      meta.RandomSeed,
-		TotalTickets:    stats.TotalTickets,
-		BurnedAmount:    meta.BurnedAmount,
-		DonationAccount: meta.DonationAccount,
-		DonationPercent: meta.DonationPercent,
-		DonatedAmount:   meta.DonatedAmount,
-		Metadata:        loadLotteryMetadataValue(id),
+		ID:            meta.ID,
+		Creator:       meta.Creator,
+		Name:          meta.Name,
+		CreatedAt:     meta.CreatedAt,
+		DeadlineHours: meta.DeadlineHours,
+		DeadlineUnix:  meta.DeadlineUnix,
+		MaxTickets:    meta.MaxTickets,
+		BurnPercent:   meta.BurnPercent,
+		TicketPrice:   meta.TicketPrice,
+		Asset:         meta.Asset,
+		WinnerShares:  meta.WinnerShares,
+		Pool:          stats.Pool,
+		Participants:  participants,
+		State:         meta.State,
+		Winners:       meta.Winners,
+		ExecutedAt:    meta.ExecutedAt,
+		RandomSeed:    meta.RandomSeed,
+		TotalTickets:  stats.TotalTickets,
+		BurnedAmount:  meta.BurnedAmount,
+		Donations:     meta.Donations,
+		DonatedAmount: meta.DonatedAmount,
+		Metadata:      loadLotteryMetadataValue(id),
+
+		ArchivedCount:    meta.ArchivedCount,
+		ParticipantsHash: meta.ParticipantsHash,
+
+		TicketsRoot: meta.TicketsRoot,
+
+		ProtocolFeePercent: meta.ProtocolFeePercent,
+		ProtocolFeeAmount:  meta.ProtocolFeeAmount,
+
+		CreatorFeePercent: meta.CreatorFeePercent,
+		CreatorFeeAmount:  meta.CreatorFeeAmount,
 	}
 }
 
@@ -187,25 +299,35 @@ func loadLottery(id uint64) *Lottery {
 func saveLottery(l *Lottery) {
 	// Save metadata
 	meta := &LotteryMetadata{
-		ID:              l.ID,
-		Creator:         l.Creator,
-		Name:            l.Name,
-		CreatedAt:       l.CreatedAt,
-		DeadlineHours:   l.DeadlineHours,
-		DeadlineUnix:    l.DeadlineUnix,
-		MaxTickets:      l.MaxTickets,
-		BurnPercent:     l.BurnPercent,
-		TicketPrice:     l.TicketPrice,
-		Asset:           l.Asset,
-		WinnerShares:    l.WinnerShares,
-		State:           l.State,
-		Winners:         l.Winners,
-		ExecutedAt:      l.ExecutedAt,
-		RandomSeed:      l.RandomSeed,
-		BurnedAmount:    l.BurnedAmount,
-		DonationAccount: l.DonationAccount,
-		DonationPercent: l.DonationPercent,
-		DonatedAmount:   l.DonatedAmount,
+		ID:            l.ID,
+		Creator:       l.Creator,
+		Name:          l.Name,
+		CreatedAt:     l.CreatedAt,
+		DeadlineHours: l.DeadlineHours,
+		DeadlineUnix:  l.DeadlineUnix,
+		MaxTickets:    l.MaxTickets,
+		BurnPercent:   l.BurnPercent,
+		TicketPrice:   l.TicketPrice,
+		Asset:         l.Asset,
+		WinnerShares:  l.WinnerShares,
+		State:         l.State,
+		Winners:       l.Winners,
+		ExecutedAt:    l.ExecutedAt,
+		RandomSeed:    l.RandomSeed,
+		BurnedAmount:  l.BurnedAmount,
+		Donations:     l.Donations,
+		DonatedAmount: l.DonatedAmount,
+
+		ArchivedCount:    l.ArchivedCount,
+		ParticipantsHash: l.ParticipantsHash,
+
+		TicketsRoot: l.TicketsRoot,
+
+		ProtocolFeePercent: l.ProtocolFeePercent,
+		ProtocolFeeAmount:  l.ProtocolFeeAmount,
+
+		CreatorFeePercent: l.CreatorFeePercent,
+		CreatorFeeAmount:  l.CreatorFeeAmount,
 	}
 	saveLotteryMetadata(meta)
 	saveLotteryMetadataValue(l.ID, l.Metadata)
@@ -219,19 +341,205 @@ func saveLottery(l *Lottery) {
 	saveLotteryPoolStats(l.ID, stats)
 }
 
+// loadLotteryCount returns how many lotteries have been created
+func loadLotteryCount() uint64 {
+	counterPtr := host.StateGet(getCounterKey())
+	if counterPtr == nil || *counterPtr == "" {
+		return 0
+	}
+	counter, err := strconv.ParseUint(*counterPtr, 10, 64)
+	if err != nil {
+		sdk.Abort("invalid counter state")
+	}
+	return counter
+}
+
 // getNextLotteryID returns the next available lottery ID and increments the counter
 func getNextLotteryID() uint64 {
-	key := getCounterKey()
-	counterPtr := sdk.StateGetObject(key)
-	var counter uint64
-	if counterPtr != nil && *counterPtr != "" {
-		var err error
-		counter, err = strconv.ParseUint(*counterPtr, 10, 64)
+	counter := loadLotteryCount()
+	if counter == 0 {
+		// A fresh contract starts out on the current schema
+		saveSchemaVersion(stateSchemaVersion)
+	}
+	counter++
+	host.StateSet(getCounterKey(), strconv.FormatUint(counter, 10))
+	return counter
+}
+
+// loadSchemaVersion returns the schema version the state is stored in.
+// State written before versions were recorded has no key and counts as version 0,
+// a contract without any lottery has nothing to migrate and is on the current version.
+func loadSchemaVersion() uint64 {
+	dataPtr := host.StateGet(getSchemaVersionKey())
+	if dataPtr == nil || *dataPtr == "" {
+		if loadLotteryCount() == 0 {
+			return stateSchemaVersion
+		}
+		return 0
+	}
+	version, err := strconv.ParseUint(*dataPtr, 10, 64)
+	if err != nil {
+		sdk.Abort("invalid schema version")
+	}
+	return version
+}
+
+// saveSchemaVersion records the schema version the state is stored in
+func saveSchemaVersion(version uint64) {
+	host.StateSet(getSchemaVersionKey(), strconv.FormatUint(version, 10))
+}
+
+// loadMigrateCursor returns the next lottery and participant index a running migration
+// has to rewrite. Participant index 0 stands for the lottery's own records.
+func loadMigrateCursor() (uint64, uint64) {
+	dataPtr := host.StateGet(getMigrateCursorKey())
+	if dataPtr == nil || *dataPtr == "" {
+		return 1, 0
+	}
+	idStr, indexStr, ok := strings.Cut(*dataPtr, ":")
+	id, err1 := strconv.ParseUint(idStr, 10, 64)
+	index, err2 := strconv.ParseUint(indexStr, 10, 64)
+	if !ok || err1 != nil || err2 != nil {
+		sdk.Abort("invalid migrate cursor")
+	}
+	return id, index
+}
+
+// saveMigrateCursor stores the position a migration continues from
+func saveMigrateCursor(lotteryID uint64, index uint64) {
+	host.StateSet(getMigrateCursorKey(), strconv.FormatUint(lotteryID, 10)+":"+strconv.FormatUint(index, 10))
+}
+
+// clearMigrateCursor removes the cursor once a migration is complete
+func clearMigrateCursor() {
+	host.StateDelete(getMigrateCursorKey())
+}
+
+// loadConfig retrieves the protocol limits, the defaults until the owner has set any
+func loadConfig() *Config {
+	dataPtr := host.StateGet(getConfigKey())
+	if dataPtr == nil || *dataPtr == "" {
+		return defaultConfig()
+	}
+	return decodeConfig(*dataPtr)
+}
+
+// saveConfig stores the protocol limits
+func saveConfig(c *Config) {
+	host.StateSet(getConfigKey(), encodeConfig(c))
+}
+
+// loadTreasuryBalance returns the protocol fees held for an asset, 0 if none were collected
+func loadTreasuryBalance(asset sdk.Asset) Amount {
+	dataPtr := host.StateGet(getTreasuryKey(asset))
+	if dataPtr == nil || *dataPtr == "" {
+		return 0
+	}
+	balance, err := strconv.ParseInt(*dataPtr, 10, 64)
+	if err != nil || balance < 0 {
+		sdk.Abort("invalid treasury balance")
+	}
+	return Amount(balance)
+}
+
+// saveTreasuryBalance stores the protocol fees held for an asset, a zero balance removes the key
+func saveTreasuryBalance(asset sdk.Asset, balance Amount) {
+	if balance == 0 {
+		host.StateDelete(getTreasuryKey(asset))
+		return
+	}
+	host.StateSet(getTreasuryKey(asset), strconv.FormatInt(int64(balance), 10))
+}
+
+// loadPausedActions returns the actions the contract owner has paused, nil if none
+func loadPausedActions() []string {
+	dataPtr := host.StateGet(getPausedKey())
+	if dataPtr == nil || *dataPtr == "" {
+		return nil
+	}
+	return strings.Split(*dataPtr, ",")
+}
+
+// savePausedActions stores the paused actions, an empty list removes the key
+func savePausedActions(actions []string) {
+	if len(actions) == 0 {
+		host.StateDelete(getPausedKey())
+		return
+	}
+	host.StateSet(getPausedKey(), strings.Join(actions, ","))
+}
+
+// loadPayoutPreference reads a payout preference stored under key, default if none is set
+func loadPayoutPreference(key string) PayoutPreference {
+	dataPtr := host.StateGet(key)
+	if dataPtr == nil || *dataPtr == "" {
+		return PayoutPreferenceDefault
+	}
+	switch *dataPtr {
+	case PayoutPreferenceTransfer.String():
+		return PayoutPreferenceTransfer
+	case PayoutPreferenceWithdraw.String():
+		return PayoutPreferenceWithdraw
+	}
+	sdk.Abort("invalid payout preference state")
+	return PayoutPreferenceDefault
+}
+
+// savePayoutPreference stores a payout preference under key, the default removes it
+func savePayoutPreference(key string, p PayoutPreference) {
+	if p == PayoutPreferenceDefault {
+		host.StateDelete(key)
+		return
+	}
+	host.StateSet(key, p.String())
+}
+
+// loadCharity retrieves a charity registry entry, nil if there is none with the ID
+func loadCharity(id uint64) *Charity {
+	dataPtr := host.StateGet(getCharityKey(id))
+	if dataPtr == nil || *dataPtr == "" {
+		return nil
+	}
+	return decodeCharity(*dataPtr)
+}
+
+// loadCharityID returns the registry ID of an address, 0 if it is not a registered charity
+func loadCharityID(address string) uint64 {
+	dataPtr := host.StateGet(getCharityLookupKey(address))
+	if dataPtr == nil || *dataPtr == "" {
+		return 0
+	}
+	id, err := strconv.ParseUint(*dataPtr, 10, 64)
+	if err != nil {
+		sdk.Abort("invalid charity lookup")
+	}
+	return id
+}
+
+// saveCharity stores a charity registry entry and its address lookup
+func saveCharity(c *Charity) {
+	host.StateSet(getCharityKey(c.ID), encodeCharity(c))
+	host.StateSet(getCharityLookupKey(c.Address.String()), strconv.FormatUint(c.ID, 10))
+}
+
+// deleteCharity removes a charity registry entry and its address lookup
+func deleteCharity(c *Charity) {
+	host.StateDelete(getCharityKey(c.ID))
+	host.StateDelete(getCharityLookupKey(c.Address.String()))
+}
+
+// getNextCharityID returns the next available charity ID and increments the counter.
+// IDs of removed charities are not reused.
+func getNextCharityID() uint64 {
+	counter := uint64(0)
+	if counterPtr := host.StateGet(getCharityCounterKey()); counterPtr != nil && *counterPtr != "" {
+		v, err := strconv.ParseUint(*counterPtr, 10, 64)
 		if err != nil {
-			sdk.Abort("invalid counter state")
+			sdk.Abort("invalid charity counter state")
 		}
+		counter = v
 	}
 	counter++
-	sdk.StateSetObject(key, strconv.FormatUint(counter, 10))
+	host.StateSet(getCharityCounterKey(), strconv.FormatUint(counter, 10))
 	return counter
 }
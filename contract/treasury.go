@@ -0,0 +1,40 @@
+package main
+
+import (
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+)
+
+// The protocol fee is a share of every pool that stays in the contract when a lottery is executed.
+// Its rate comes from the config (protocol_fee_percent) and is fixed per lottery at creation. The fees
+// are booked per asset under treasury:<asset> and only leave the contract through withdraw_treasury.
+
+// creditTreasury adds collected protocol fees to the asset's treasury balance
+func creditTreasury(asset sdk.Asset, amount Amount) {
+	saveTreasuryBalance(asset, loadTreasuryBalance(asset)+amount)
+}
+
+//export withdraw_treasury
+func withdraw_treasury(payload *string) *string {
+	requireContractOwner()
+	requireNoDeposit()
+	payloadStr, format := unwrapPayload(payload, "withdraw_treasury payload missing")
+	args := parseWithdrawTreasury(payloadStr, format)
+
+	balance := loadTreasuryBalance(args.Asset)
+	if args.Amount > balance {
+		sdk.Abort("amount exceeds treasury balance of " + events.Amount(balance).String())
+	}
+	recipient := args.Recipient
+	if recipient == "" {
+		recipient = getSenderAddress()
+	}
+
+	balance -= args.Amount
+	saveTreasuryBalance(args.Asset, balance)
+	payout(recipient, args.Amount, args.Asset, false, "treasury")
+	emitTreasuryWithdrawn(args.Asset, args.Amount, recipient, balance)
+
+	ret := "withdrew " + events.Amount(args.Amount).String() + " " + args.Asset.String() + ", treasury balance: " + events.Amount(balance).String()
+	return &ret
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/sdk"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProtocolFee tests that the fee rate is fixed at creation, is taken from the pool next to
+// burn and donation and ends up in the treasury
+func TestProtocolFee(t *testing.T) {
+	a := newLedgerAudit(t)
+	res := a.mustCall(t, set_config, "protocol_fee_percent=2.5", fakeOwner)
+	assert.Equal(t, events.Percent(250), v2Events(t, res.Logs)[0].(*events.ConfigChanged).ProtocolFeePercent)
+
+	res = a.mustCall(t, create_lottery, "Fees|24|10|60,40|1.000|hive:charity|20", "hive:creator")
+	a.f.acceptDonations(t, 1)
+	created := v2Events(t, res.Logs)[0].(*events.Created)
+	assert.Equal(t, events.Percent(250), created.ProtocolFee)
+
+	// A later change only applies to new lotteries
+	a.mustCall(t, set_config, "protocol_fee_percent=0", fakeOwner)
+	a.f.fund("hive:alice", 10_000)
+	a.f.fund("hive:bob", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("6.000"))
+	a.mustCall(t, join_lottery, "1", "hive:bob", transferAllow("4.000"))
+
+	res = a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	var executed *events.Executed
+	var paid []events.Amount
+	for _, ev := range v2Events(t, res.Logs) {
+		switch e := ev.(type) {
+		case *events.Executed:
+			executed = e
+		case *events.Payout:
+			paid = append(paid, e.Amount)
+		}
+	}
+	require.NotNil(t, executed)
+	assert.Equal(t, events.Amount(250), executed.ProtocolFee)
+	assert.Equal(t, events.Amount(1000), executed.Burned)
+	assert.Equal(t, events.Amount(2000), executed.Donated)
+	assert.Equal(t, []events.Amount{4050, 2700}, paid)
+	assert.Equal(t, Amount(250), loadTreasuryBalance(sdk.AssetHive))
+	assert.Equal(t, Amount(250), loadLotteryMetadata(1).ProtocolFeeAmount)
+
+	// Without a fee nothing is kept and le carries no fee field
+	a.f.at("2025-01-01T00:00:00").mustCall(t, create_lottery, "Free|24|10|100|1.000", "hive:creator")
+	a.mustCall(t, join_lottery, "2", "hive:alice", transferAllow("1.000"))
+	res = a.f.at(fakeFuture).mustCall(t, execute_lottery, "2", "hive:executor")
+	assert.NotContains(t, strings.Join(res.Logs, "\n"), "protocol_fee")
+	assert.Equal(t, Amount(250), loadTreasuryBalance(sdk.AssetHive))
+}
+
+// TestWithdrawTreasury tests partial and full withdrawals and the tw event
+func TestWithdrawTreasury(t *testing.T) {
+	a := newLedgerAudit(t)
+	a.mustCall(t, set_config, "protocol_fee_percent=10|max_burn_donation_percent=85", fakeOwner)
+	a.mustCall(t, create_lottery, "Fees|24|10|100|1.000", "hive:creator")
+	a.f.fund("hive:alice", 10_000)
+	a.mustCall(t, join_lottery, "1", "hive:alice", transferAllow("5.000"))
+	a.f.at(fakeFuture).mustCall(t, execute_lottery, "1", "hive:executor")
+	require.Equal(t, Amount(500), loadTreasuryBalance(sdk.AssetHive))
+
+	res := a.mustCall(t, withdraw_treasury, "hive|0.200|hive:ops", fakeOwner)
+	assert.Equal(t, "withdrew 0.200 hive, treasury balance: 0.300", res.Ret)
+	evs := v2Events(t, res.Logs)
+	require.Len(t, evs, 1)
+	assert.Equal(t, &events.TreasuryWithdrawn{Asset: "hive", Amount: 200, Recipient: "hive:ops", Balance: 300}, evs[0])
+	assert.Equal(t, int64(200), a.f.Balance("hive:ops", sdk.AssetHive))
+
+	res = a.mustCall(t, withdraw_treasury, `{"asset":"HIVE","amount":"0.300"}`, fakeOwner)
+	assert.Equal(t, "withdrew 0.300 hive, treasury balance: 0.000", res.Ret)
+	assert.Equal(t, int64(300), a.f.Balance(fakeOwner, sdk.AssetHive))
+	assert.NotContains(t, a.f.state, getTreasuryKey(sdk.AssetHive))
+	assert.Equal(t, "amount exceeds treasury balance of 0.000", a.call(t, withdraw_treasury, "hive|0.001", fakeOwner).Err)
+}
+
+// TestWithdrawTreasuryValidation tests owner and payload checks
+func TestWithdrawTreasuryValidation(t *testing.T) {
+	f := newFakeHost(t)
+	saveTreasuryBalance(sdk.AssetHive, 1000)
+
+	tests := []struct {
+		payload string
+		sender  string
+		err     string
+	}{
+		{"hive|1.000", "hive:creator", "only the contract owner can do this"},
+		{"", fakeOwner, "withdraw_treasury payload missing"},
+		{"hive", fakeOwner, "invalid withdraw_treasury payload format: expected asset|amount[|recipient]"},
+		{"hive|1.000|hive:ops|x", fakeOwner, "invalid withdraw_treasury payload format: expected asset|amount[|recipient]"},
+		{"doge|1.000", fakeOwner, "unsupported asset: doge"},
+		{"hive|0.0001", fakeOwner, "amount must have at most 3 decimals"},
+		{"hive|abc", fakeOwner, "invalid amount"},
+		{"hive|0", fakeOwner, "amount must be greater than 0"},
+		{"hive|1.000|ops", fakeOwner, "invalid recipient address"},
+		{"hive|1.001", fakeOwner, "amount exceeds treasury balance of 1.000"},
+		{"hbd|0.001", fakeOwner, "amount exceeds treasury balance of 0.000"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.err, f.call(t, withdraw_treasury, tt.payload, tt.sender).Err, tt.payload)
+	}
+	assert.True(t, strings.HasPrefix(f.call(t, withdraw_treasury, `{"amount":1}`, fakeOwner).Err, "invalid withdraw_treasury JSON payload: "))
+	assert.Equal(t, Amount(1000), loadTreasuryBalance(sdk.AssetHive))
+}
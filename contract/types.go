@@ -1,8 +1,10 @@
 package main
 
 import (
-	"math"
+	"errors"
+	"strings"
 
+	"okinoko_lottery/events"
 	"okinoko_lottery/sdk"
 )
 
@@ -10,14 +12,44 @@ const AmountScale = 1000
 
 type Amount int64
 
-// FloatToAmount scales human floats by AmountScale and rounds to int64 so storage stays precise.
-func FloatToAmount(v float64) Amount {
-	return Amount(math.Round(v * AmountScale))
+var (
+	errAmountSyntax   = errors.New("malformed amount")
+	errAmountDecimals = errors.New("too many decimals")
+	errAmountRange    = errors.New("amount out of range")
+	errAmountAsset    = errors.New("unsupported asset")
+)
+
+// assetDecimals returns how many decimals an asset is transferred with, 0 if it is unsupported.
+// Every supported asset must match AmountScale.
+func assetDecimals(asset sdk.Asset) int {
+	switch asset {
+	case sdk.AssetHive, sdk.AssetHbd:
+		return 3
+	}
+	return 0
 }
 
-// AmountToFloat converts back to float64 for reporting or events.
-func AmountToFloat(v Amount) float64 {
-	return float64(v) / AmountScale
+// ParseAmount converts a decimal string like "5", "5.5" or "5.000" into a scaled Amount
+// with events.ParseFixed, the parser the indexer uses for event amounts. Signs, exponents,
+// more decimals than the asset has and values beyond int64 are rejected rather than rounded.
+func ParseAmount(value string, asset sdk.Asset) (Amount, error) {
+	decimals := assetDecimals(asset)
+	if decimals == 0 {
+		return 0, errAmountAsset
+	}
+	if strings.HasPrefix(value, "-") {
+		return 0, errAmountSyntax
+	}
+	v, err := events.ParseFixed(value, decimals)
+	switch err {
+	case nil:
+		return Amount(v), nil
+	case events.ErrFixedPrecision:
+		return 0, errAmountDecimals
+	case events.ErrFixedRange:
+		return 0, errAmountRange
+	}
+	return 0, errAmountSyntax
 }
 
 // AmountToInt64 exposes the raw scaled int64 for Hive transfer functions.
@@ -25,6 +57,18 @@ func AmountToInt64(v Amount) int64 {
 	return int64(v)
 }
 
+// BasisPoints is a percentage in hundredths of a percent, e.g. 33.33% = 3333 and 100% = 10000.
+type BasisPoints int64
+
+const BasisPointsScale = 10000
+
+// ApplyBasisPoints returns the given share of an amount, rounded down to the smallest unit.
+// The amount is split into quotient and remainder first so the product cannot overflow int64.
+func ApplyBasisPoints(v Amount, bps BasisPoints) Amount {
+	q, r := v/BasisPointsScale, v%BasisPointsScale
+	return q*Amount(bps) + r*Amount(bps)/BasisPointsScale
+}
+
 // LotteryState captures a lottery's lifecycle.
 type LotteryState uint8
 
@@ -45,52 +89,132 @@ func (ls LotteryState) String() string {
 	}
 }
 
+// PayoutPreference is how a winner wants to receive prizes.
+type PayoutPreference uint8
+
+const (
+	PayoutPreferenceDefault  PayoutPreference = 0 // nothing set, falls back to the next level
+	PayoutPreferenceTransfer PayoutPreference = 1 // keep the prize on the Magi Network
+	PayoutPreferenceWithdraw PayoutPreference = 2 // withdraw the prize to the Hive L1 wallet
+)
+
+// String prints the payout preference as it is written in payloads and state.
+func (p PayoutPreference) String() string {
+	switch p {
+	case PayoutPreferenceDefault:
+		return "default"
+	case PayoutPreferenceTransfer:
+		return "transfer"
+	case PayoutPreferenceWithdraw:
+		return "withdraw"
+	default:
+		return "unknown"
+	}
+}
+
 // Lottery represents a lottery instance
 type Lottery struct {
-	ID              uint64
-	Creator         sdk.Address
-	Name            string
-	CreatedAt       int64
-	DeadlineHours   uint64
-	DeadlineUnix    int64
-	MaxTickets      uint64
-	BurnPercent     float64
-	TicketPrice     Amount
-	Asset           sdk.Asset
-	WinnerShares    []float64
-	Pool            Amount
-	Participants    map[string]uint64 // address -> ticket count
-	State           LotteryState
-	Winners         []Winner
-	ExecutedAt      int64
-	RandomSeed      uint64
-	TotalTickets    uint64
-	BurnedAmount    Amount
-	DonationAccount sdk.Address
-	DonationPercent float64
-	DonatedAmount   Amount
-	Metadata        string
+	ID            uint64
+	Creator       sdk.Address
+	Name          string
+	CreatedAt     int64
+	DeadlineHours uint64
+	DeadlineUnix  int64
+	MaxTickets    uint64
+	BurnPercent   BasisPoints
+	TicketPrice   Amount
+	Asset         sdk.Asset
+	WinnerShares  []BasisPoints
+	Pool          Amount
+	Participants  []ParticipantEntry // in first-join order, the order of the draw's ticket pool
+	State         LotteryState
+	Winners       []Winner
+	ExecutedAt    int64
+	RandomSeed    uint64
+	TotalTickets  uint64
+	BurnedAmount  Amount
+	Donations     []DonationRecipient
+	DonatedAmount Amount // Sum over all recipients
+	Metadata      string
+
+	// Participant archive, see archive_lottery. Participants is empty once archived.
+	ArchivedCount    uint64
+	ParticipantsHash string
+
+	TicketsRoot string // Merkle root over the participants' ticket ranges, see merkle.go
+
+	// Protocol fee, see treasury.go
+	ProtocolFeePercent BasisPoints
+	ProtocolFeeAmount  Amount
+
+	CreatorFeePercent BasisPoints
+	CreatorFeeAmount  Amount
 }
 
 // Winner represents a lottery winner
 type Winner struct {
 	Address sdk.Address
 	Amount  Amount
-	Share   float64
+	Share   BasisPoints
+}
+
+// DonationRecipient is one account receiving a share of the pool at execution
+type DonationRecipient struct {
+	Account   sdk.Address
+	Percent   BasisPoints
+	CharityID uint64 // Registered charity the account belonged to at creation, 0 if unregistered
+	Accepted  bool   // Unregistered accounts have to accept before the lottery sells tickets
+}
+
+// donationPercent returns the combined donation rate of all recipients
+func donationPercent(donations []DonationRecipient) BasisPoints {
+	total := BasisPoints(0)
+	for _, d := range donations {
+		total += d.Percent
+	}
+	return total
+}
+
+// verifiedCharity reports whether a lottery donates and every recipient is a registered charity
+func verifiedCharity(donations []DonationRecipient) bool {
+	for _, d := range donations {
+		if d.CharityID == 0 {
+			return false
+		}
+	}
+	return len(donations) > 0
+}
+
+// pendingDonation returns the first recipient that has not accepted its donation yet, nil if none
+func pendingDonation(donations []DonationRecipient) *DonationRecipient {
+	for i := range donations {
+		if !donations[i].Accepted {
+			return &donations[i]
+		}
+	}
+	return nil
+}
+
+// Charity is an entry of the owner-managed registry of verified donation accounts
+type Charity struct {
+	ID      uint64
+	Address sdk.Address
+	Name    string
+	URL     string
 }
 
 // CreateLotteryArgs represents arguments for creating a lottery
 type CreateLotteryArgs struct {
-	Name            string
-	DeadlineHours   uint64
-	MaxTickets      uint64
-	BurnPercent     float64
-	WinnerShares    []float64
-	TicketPrice     Amount
-	Asset           sdk.Asset
-	DonationAccount sdk.Address
-	DonationPercent float64
-	MetaData        string
+	Name          string
+	DeadlineHours uint64
+	MaxTickets    uint64
+	BurnPercent   BasisPoints
+	WinnerShares  []BasisPoints
+	TicketPrice   Amount
+	Asset         sdk.Asset
+	Donations     []DonationRecipient
+	MetaData      string
+	CreatorFee    BasisPoints
 }
 
 // JoinLotteryArgs represents arguments for joining a lottery
@@ -109,12 +233,71 @@ type ExecuteLotteryArgs struct {
 	LotteryID uint64
 }
 
+// ArchiveLotteryArgs represents arguments for archiving a lottery's participants
+type ArchiveLotteryArgs struct {
+	LotteryID uint64
+}
+
+// GetTicketProofArgs represents arguments for requesting a ticket ownership proof
+type GetTicketProofArgs struct {
+	LotteryID uint64
+	Address   sdk.Address
+}
+
 // VerifyLotteryArgs represents arguments for verifying a lottery
 type VerifyLotteryArgs struct {
 	LotteryID uint64
 	Seed      uint64
 }
 
+// GetCreatorLotteriesArgs represents arguments for listing a creator's lottery IDs
+type GetCreatorLotteriesArgs struct {
+	Creator sdk.Address
+	Offset  uint64
+	Limit   uint64
+}
+
+// MigrateArgs represents arguments for a migrate batch
+type MigrateArgs struct {
+	BatchSize uint64
+}
+
+// PauseArgs represents the actions a pause or unpause call toggles
+type PauseArgs struct {
+	Actions []string
+}
+
+// WithdrawTreasuryArgs represents arguments for withdrawing collected protocol fees
+type WithdrawTreasuryArgs struct {
+	Asset     sdk.Asset
+	Amount    Amount
+	Recipient sdk.Address // empty: the sender
+}
+
+// AcceptDonationArgs represents arguments for a donation account accepting a lottery's donation
+type AcceptDonationArgs struct {
+	LotteryID uint64
+}
+
+// SetPayoutPreferenceArgs represents arguments for setting the sender's payout preference
+type SetPayoutPreferenceArgs struct {
+	LotteryID  uint64 // 0 for the preference of all lotteries
+	Preference PayoutPreference
+}
+
+// GetPayoutPreferenceArgs represents arguments for reading an account's payout preference
+type GetPayoutPreferenceArgs struct {
+	Address   sdk.Address
+	LotteryID uint64 // 0 for the preference of all lotteries
+}
+
+// RegisterCharityArgs represents arguments for adding a charity to the registry
+type RegisterCharityArgs struct {
+	Address sdk.Address
+	Name    string
+	URL     string
+}
+
 // AddressFromString converts a human string to the platform-specific address wrapper.
 func AddressFromString(s string) sdk.Address { return sdk.Address(s) }
 
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Field is a single key:value pair of an event line.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Version is the version marker written into every v2 event line.
+const Version = "2"
+
+// versionField is the first field of every v2 line.
+const versionField = "v:" + Version
+
+// Escape percent-escapes the characters that would corrupt an event line:
+// '%' -> "%25", '|' -> "%7C", '\n' -> "%0A", '\r' -> "%0D". Nothing else is escaped.
+func Escape(v string) string {
+	if !strings.ContainsAny(v, "%|\n\r") {
+		return v
+	}
+	var b strings.Builder
+	b.Grow(len(v) + 8)
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '%':
+			b.WriteString("%25")
+		case '|':
+			b.WriteString("%7C")
+		case '\n':
+			b.WriteString("%0A")
+		case '\r':
+			b.WriteString("%0D")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Unescape reverses Escape. Any %XX sequence is decoded so values escaped by
+// other tools stay readable; a malformed sequence is an error.
+func Unescape(v string) (string, error) {
+	if !strings.Contains(v, "%") {
+		return v, nil
+	}
+	var b strings.Builder
+	b.Grow(len(v))
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(v) {
+			return "", errors.New("truncated escape in " + strconv.Quote(v))
+		}
+		x, err := strconv.ParseUint(v[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", errors.New("malformed escape in " + strconv.Quote(v))
+		}
+		b.WriteByte(byte(x))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// listEscaper escapes the entries of a list value
+var listEscaper = strings.NewReplacer("%", "%25", ",", "%2C")
+
+// joinList joins the entries of a list value with ',', escaping '%' -> "%25" and ',' -> "%2C"
+// inside each entry so an entry cannot split into two. The line escaping applies on top.
+func joinList(entries []string) string {
+	escaped := make([]string, len(entries))
+	for i, entry := range entries {
+		escaped[i] = listEscaper.Replace(entry)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// splitList reverses joinList.
+func splitList(v string) ([]string, error) {
+	entries := strings.Split(v, ",")
+	for i, entry := range entries {
+		var err error
+		if entries[i], err = Unescape(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// formatLine joins an event type and its fields, escaping values for v2 lines.
+func formatLine(eventType string, fields []Field, legacy bool) string {
+	var b strings.Builder
+	b.WriteString(eventType)
+	if !legacy {
+		b.WriteString("|")
+		b.WriteString(versionField)
+	}
+	for _, f := range fields {
+		b.WriteString("|")
+		b.WriteString(f.Key)
+		b.WriteString(":")
+		if legacy {
+			b.WriteString(f.Value)
+		} else {
+			b.WriteString(Escape(f.Value))
+		}
+	}
+	return b.String()
+}
+
+// splitLine splits an event line into its type and unescaped fields.
+// Legacy lines (no version marker) are split as-is, without unescaping.
+func splitLine(line string) (string, []Field, bool, error) {
+	parts := strings.Split(line, "|")
+	eventType := parts[0]
+	legacy := len(parts) < 2 || parts[1] != versionField
+	if !legacy {
+		parts = parts[1:]
+	}
+
+	fields := make([]Field, 0, len(parts)-1)
+	for i, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, ":")
+		if legacy && key == "metadata" {
+			// Legacy metadata is unescaped and always the last field, it takes the rest of the line
+			value = strings.Join(append([]string{value}, parts[i+2:]...), "|")
+			fields = append(fields, Field{Key: key, Value: value})
+			break
+		}
+		if !ok || key == "" {
+			return "", nil, legacy, errors.New("malformed field " + strconv.Quote(part))
+		}
+		if !legacy {
+			var err error
+			value, err = Unescape(value)
+			if err != nil {
+				return "", nil, legacy, err
+			}
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return eventType, fields, legacy, nil
+}
+
+// IsLegacy reports whether a line uses the legacy format (no version marker).
+func IsLegacy(line string) bool {
+	_, rest, _ := strings.Cut(line, "|")
+	return rest != versionField && !strings.HasPrefix(rest, versionField+"|")
+}
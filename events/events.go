@@ -0,0 +1,757 @@
+// Package events formats and parses the log lines emitted by the lottery contract.
+//
+// The contract builds every event through this package, so the emitted strings and
+// the parser cannot drift apart. Both the versioned v2 format and the legacy format
+// (no version marker, no escaping) are supported:
+//
+//	lc|v:2|id:1|creator:hive:alice|name:Weekly Draw|...   (v2)
+//	lc|id:1|creator:hive:alice|name:Weekly Draw|...       (legacy)
+//
+// The grammar is documented in the README under "Event Encoding".
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Event type prefixes.
+const (
+	TypeCreated           = "lc"
+	TypeMetadataChanged   = "lm"
+	TypeJoined            = "lj"
+	TypeExecuted          = "le"
+	TypePayout            = "lp"
+	TypeDonation          = "ld"
+	TypeUndistributed     = "lu"
+	TypeArchived          = "la"
+	TypePauseChanged      = "cp"
+	TypeConfigChanged     = "cc"
+	TypeTreasuryWithdrawn = "tw"
+	TypeCharityRegistered = "cr"
+	TypeCharityRemoved    = "cd"
+	TypeDonationAccepted  = "lo"
+)
+
+// Event is implemented by every typed event.
+type Event interface {
+	// Type returns the event prefix, e.g. "lc".
+	Type() string
+	// Fields returns the event's key:value pairs in emission order, unescaped.
+	Fields() []Field
+}
+
+// Format renders an event as a v2 line.
+func Format(ev Event) string {
+	return formatLine(ev.Type(), ev.Fields(), false)
+}
+
+// FormatLegacy renders an event as a legacy line (no version marker, no escaping).
+func FormatLegacy(ev Event) string {
+	return formatLine(ev.Type(), ev.Fields(), true)
+}
+
+// Parse parses a v2 or legacy event line into its typed event.
+// Unknown fields are ignored so newer emitters can append fields.
+func Parse(line string) (Event, error) {
+	eventType, fields, _, err := splitLine(line)
+	if err != nil {
+		return nil, err
+	}
+	r := &fieldReader{fields: fields}
+
+	var ev Event
+	switch eventType {
+	case TypeCreated:
+		ev = parseCreated(r)
+	case TypeMetadataChanged:
+		ev = &MetadataChanged{ID: r.uint("id"), Metadata: r.str("metadata")}
+	case TypeJoined:
+		ev = &Joined{
+			ID:          r.uint("id"),
+			Participant: r.str("participant"),
+			Tickets:     r.uint("tickets"),
+			Paid:        r.amount("paid"),
+			Asset:       r.str("asset"),
+			TicketStart: r.uint("ticket_start"),
+			TicketEnd:   r.uint("ticket_end"),
+		}
+	case TypeExecuted:
+		ev = parseExecuted(r)
+	case TypePayout:
+		ev = &Payout{
+			ID:       r.uint("id"),
+			Winner:   r.str("winner"),
+			Amount:   r.amount("amount"),
+			Share:    r.percent("share"),
+			Asset:    r.str("asset"),
+			Position: r.uint("position"),
+		}
+	case TypeDonation:
+		ev = &Donation{
+			ID:        r.uint("id"),
+			Recipient: r.str("recipient"),
+			Amount:    r.amount("amount"),
+			Percent:   r.percent("percent"),
+			Asset:     r.str("asset"),
+		}
+	case TypeUndistributed:
+		ev = &Undistributed{
+			ID:     r.uint("id"),
+			Amount: r.amount("amount"),
+			Asset:  r.str("asset"),
+		}
+	case TypeArchived:
+		ev = &Archived{
+			ID:           r.uint("id"),
+			Participants: r.uint("participants"),
+			Hash:         r.str("hash"),
+		}
+	case TypePauseChanged:
+		ev = parsePauseChanged(r)
+	case TypeConfigChanged:
+		ev = &ConfigChanged{
+			MinBurnPercent:         r.percent("min_burn_percent"),
+			MaxBurnPercent:         r.percent("max_burn_percent"),
+			MinDeadlineHours:       r.uint("min_deadline_hours"),
+			MaxDeadlineHours:       r.uint("max_deadline_hours"),
+			MaxDonationPercent:     r.percent("max_donation_percent"),
+			MaxBurnDonationPercent: r.percent("max_burn_donation_percent"),
+			MinTicketPrice:         r.amount("min_ticket_price"),
+			MaxNameLength:          r.uint("max_name_length"),
+			MaxMetadataLength:      r.uint("max_metadata_length"),
+			At:                     r.int("at"),
+		}
+		if fee, ok := r.optional("protocol_fee_percent"); ok {
+			ev.(*ConfigChanged).ProtocolFeePercent = r.parsePercent("protocol_fee_percent", fee)
+		}
+		if limit, ok := r.optional("max_creator_fee_percent"); ok {
+			ev.(*ConfigChanged).MaxCreatorFeePercent = r.parsePercent("max_creator_fee_percent", limit)
+		}
+	case TypeTreasuryWithdrawn:
+		ev = &TreasuryWithdrawn{
+			Asset:     r.str("asset"),
+			Amount:    r.amount("amount"),
+			Recipient: r.str("recipient"),
+			Balance:   r.amount("balance"),
+		}
+	case TypeCharityRegistered:
+		ev = &CharityRegistered{
+			ID:      r.uint("id"),
+			Address: r.str("address"),
+			Name:    r.str("name"),
+			URL:     r.str("url"),
+			At:      r.int("at"),
+		}
+	case TypeCharityRemoved:
+		ev = &CharityRemoved{
+			ID:      r.uint("id"),
+			Address: r.str("address"),
+			At:      r.int("at"),
+		}
+	case TypeDonationAccepted:
+		ev = &DonationAccepted{
+			ID:      r.uint("id"),
+			Account: r.str("account"),
+		}
+	default:
+		return nil, errors.New("events: unknown event type " + strconv.Quote(eventType))
+	}
+
+	if r.err != nil {
+		return nil, errors.New("events: " + eventType + ": " + r.err.Error())
+	}
+	return ev, nil
+}
+
+// Created is emitted when a lottery is created (lc).
+type Created struct {
+	ID        uint64
+	Creator   string
+	Name      string
+	CreatedAt int64
+	Deadline  int64
+	Burn      Percent
+	Ticket    Amount
+	Asset     string
+	Winners   uint64
+	Shares    []Percent
+	Donations []DonationShare // optional, empty if no donation is configured
+
+	ProtocolFee Percent // optional, emitted when the lottery pays a protocol fee
+	CreatorFee  Percent // optional, emitted when the creator takes a share of the pool
+
+	// OptIn is set when the recipients carry their charity IDs (charity_id). Recipients that
+	// are not registered charities then have to accept (lo) before tickets are sold; lotteries
+	// from before the charity registry have no charity_id and need no acceptance.
+	OptIn bool
+
+	// Verified is set when the lottery donates and every recipient is a registered charity (verified).
+	Verified bool
+}
+
+// DonationShare is one donation recipient announced in an lc event.
+type DonationShare struct {
+	Account   string  `json:"account"`
+	Percent   Percent `json:"percent"`
+	CharityID uint64  `json:"charity_id,omitempty"` // 0 if the account is not a registered charity
+}
+
+// Type implements Event.
+func (e *Created) Type() string { return TypeCreated }
+
+// Fields implements Event.
+func (e *Created) Fields() []Field {
+	shares := make([]string, len(e.Shares))
+	for i, share := range e.Shares {
+		shares[i] = share.String()
+	}
+	fields := []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"creator", e.Creator},
+		{"name", e.Name},
+		{"created_at", strconv.FormatInt(e.CreatedAt, 10)},
+		{"deadline", strconv.FormatInt(e.Deadline, 10)},
+		{"burn", e.Burn.String()},
+		{"ticket", e.Ticket.String()},
+		{"asset", e.Asset},
+		{"winners", strconv.FormatUint(e.Winners, 10)},
+		{"shares", strings.Join(shares, ",")},
+	}
+	if len(e.Donations) > 0 {
+		accounts := make([]string, len(e.Donations))
+		percents := make([]string, len(e.Donations))
+		for i, d := range e.Donations {
+			accounts[i] = d.Account
+			percents[i] = d.Percent.String()
+		}
+		fields = append(fields,
+			Field{"donation_account", joinList(accounts)},
+			Field{"donation_percent", joinList(percents)},
+		)
+	}
+	if e.ProtocolFee > 0 {
+		fields = append(fields, Field{"protocol_fee", e.ProtocolFee.String()})
+	}
+	if e.CreatorFee > 0 {
+		fields = append(fields, Field{"creator_fee", e.CreatorFee.String()})
+	}
+	if e.OptIn && len(e.Donations) > 0 {
+		ids := make([]string, len(e.Donations))
+		for i, d := range e.Donations {
+			ids[i] = strconv.FormatUint(d.CharityID, 10)
+		}
+		fields = append(fields, Field{"charity_id", joinList(ids)})
+	}
+	if e.Verified {
+		fields = append(fields, Field{"verified", strconv.FormatBool(e.Verified)})
+	}
+	return fields
+}
+
+// parseCreated reads an lc event, the share list is a comma separated percent list.
+// Lotteries with several donation recipients list them in donation_account and their
+// percentages and charity IDs in donation_percent and charity_id, in the same order;
+// these lists escape ',' inside an entry (see joinList).
+func parseCreated(r *fieldReader) *Created {
+	e := &Created{
+		ID:        r.uint("id"),
+		Creator:   r.str("creator"),
+		Name:      r.str("name"),
+		CreatedAt: r.int("created_at"),
+		Deadline:  r.int("deadline"),
+		Burn:      r.percent("burn"),
+		Ticket:    r.amount("ticket"),
+		Asset:     r.str("asset"),
+		Winners:   r.uint("winners"),
+	}
+	for _, share := range strings.Split(r.str("shares"), ",") {
+		e.Shares = append(e.Shares, r.parsePercent("shares", share))
+	}
+	if account, ok := r.optional("donation_account"); ok {
+		accounts := r.list("donation_account", account)
+		percents := r.list("donation_percent", r.str("donation_percent"))
+		if len(percents) != len(accounts) && r.err == nil {
+			r.fail(errors.New("donation_percent has " + strconv.Itoa(len(percents)) + " values for " + strconv.Itoa(len(accounts)) + " accounts"))
+		}
+		for i := 0; i < len(accounts) && i < len(percents); i++ {
+			e.Donations = append(e.Donations, DonationShare{Account: accounts[i], Percent: r.parsePercent("donation_percent", percents[i])})
+		}
+	}
+	if fee, ok := r.optional("protocol_fee"); ok {
+		e.ProtocolFee = r.parsePercent("protocol_fee", fee)
+	}
+	if fee, ok := r.optional("creator_fee"); ok {
+		e.CreatorFee = r.parsePercent("creator_fee", fee)
+	}
+	if list, ok := r.optional("charity_id"); ok {
+		ids := r.list("charity_id", list)
+		if len(ids) != len(e.Donations) && r.err == nil {
+			r.fail(errors.New("charity_id has " + strconv.Itoa(len(ids)) + " values for " + strconv.Itoa(len(e.Donations)) + " accounts"))
+		}
+		for i := 0; i < len(ids) && i < len(e.Donations); i++ {
+			id, err := strconv.ParseUint(ids[i], 10, 64)
+			if err != nil && r.err == nil {
+				r.fail(errors.New("invalid charity_id " + strconv.Quote(ids[i])))
+			}
+			e.Donations[i].CharityID = id
+		}
+		e.OptIn = true
+	}
+	if verified, ok := r.optional("verified"); ok {
+		v, err := strconv.ParseBool(verified)
+		if err != nil && r.err == nil {
+			r.fail(errors.New("invalid verified " + strconv.Quote(verified)))
+		}
+		e.Verified = v
+	}
+	return e
+}
+
+// MetadataChanged is emitted when a lottery's metadata is set or updated (lm).
+type MetadataChanged struct {
+	ID       uint64
+	Metadata string
+}
+
+// Type implements Event.
+func (e *MetadataChanged) Type() string { return TypeMetadataChanged }
+
+// Fields implements Event.
+func (e *MetadataChanged) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"metadata", e.Metadata},
+	}
+}
+
+// Joined is emitted for every ticket purchase (lj).
+type Joined struct {
+	ID          uint64
+	Participant string
+	Tickets     uint64
+	Paid        Amount
+	Asset       string
+	TicketStart uint64
+	TicketEnd   uint64
+}
+
+// Type implements Event.
+func (e *Joined) Type() string { return TypeJoined }
+
+// Fields implements Event.
+func (e *Joined) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"participant", e.Participant},
+		{"tickets", strconv.FormatUint(e.Tickets, 10)},
+		{"paid", e.Paid.String()},
+		{"asset", e.Asset},
+		{"ticket_start", strconv.FormatUint(e.TicketStart, 10)},
+		{"ticket_end", strconv.FormatUint(e.TicketEnd, 10)},
+	}
+}
+
+// Executed is emitted when a lottery is drawn (le).
+type Executed struct {
+	ID           uint64
+	Pool         Amount
+	Burned       Amount
+	Donated      Amount
+	Asset        string
+	Winners      uint64
+	Seed         uint64
+	Tickets      uint64
+	Participants uint64
+	ExecutedAt   int64
+	TicketsRoot  string // optional, lower-case hex Merkle root over the ticket ranges, see the README under "Ticket Proofs"
+	ProtocolFee  Amount // optional, the part of the pool kept in the contract's treasury
+	CreatorFee   Amount // optional, the part of the pool transferred to the creator
+}
+
+// Type implements Event.
+func (e *Executed) Type() string { return TypeExecuted }
+
+// Fields implements Event.
+func (e *Executed) Fields() []Field {
+	fields := []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"pool", e.Pool.String()},
+		{"burned", e.Burned.String()},
+		{"donated", e.Donated.String()},
+		{"asset", e.Asset},
+		{"winners", strconv.FormatUint(e.Winners, 10)},
+		{"seed", strconv.FormatUint(e.Seed, 10)},
+		{"tickets", strconv.FormatUint(e.Tickets, 10)},
+		{"participants", strconv.FormatUint(e.Participants, 10)},
+		{"executed_at", strconv.FormatInt(e.ExecutedAt, 10)},
+	}
+	if e.TicketsRoot != "" {
+		fields = append(fields, Field{"tickets_root", e.TicketsRoot})
+	}
+	if e.ProtocolFee > 0 {
+		fields = append(fields, Field{"protocol_fee", e.ProtocolFee.String()})
+	}
+	if e.CreatorFee > 0 {
+		fields = append(fields, Field{"creator_fee", e.CreatorFee.String()})
+	}
+	return fields
+}
+
+// parseExecuted reads an le event, lines from before ticket roots and fees carry none of them.
+func parseExecuted(r *fieldReader) *Executed {
+	e := &Executed{
+		ID:           r.uint("id"),
+		Pool:         r.amount("pool"),
+		Burned:       r.amount("burned"),
+		Donated:      r.amount("donated"),
+		Asset:        r.str("asset"),
+		Winners:      r.uint("winners"),
+		Seed:         r.uint("seed"),
+		Tickets:      r.uint("tickets"),
+		Participants: r.uint("participants"),
+		ExecutedAt:   r.int("executed_at"),
+	}
+	if root, ok := r.optional("tickets_root"); ok {
+		e.TicketsRoot = root
+	}
+	if fee, ok := r.optional("protocol_fee"); ok {
+		e.ProtocolFee = r.parseAmount("protocol_fee", fee)
+	}
+	if fee, ok := r.optional("creator_fee"); ok {
+		e.CreatorFee = r.parseAmount("creator_fee", fee)
+	}
+	return e
+}
+
+// Payout is emitted for every winner (lp).
+type Payout struct {
+	ID       uint64
+	Winner   string
+	Amount   Amount
+	Share    Percent
+	Asset    string
+	Position uint64
+}
+
+// Type implements Event.
+func (e *Payout) Type() string { return TypePayout }
+
+// Fields implements Event.
+func (e *Payout) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"winner", e.Winner},
+		{"amount", e.Amount.String()},
+		{"share", e.Share.String()},
+		{"asset", e.Asset},
+		{"position", strconv.FormatUint(e.Position, 10)},
+	}
+}
+
+// Donation is emitted when a donation is paid out (ld).
+type Donation struct {
+	ID        uint64
+	Recipient string
+	Amount    Amount
+	Percent   Percent
+	Asset     string
+}
+
+// Type implements Event.
+func (e *Donation) Type() string { return TypeDonation }
+
+// Fields implements Event.
+func (e *Donation) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"recipient", e.Recipient},
+		{"amount", e.Amount.String()},
+		{"percent", e.Percent.String()},
+		{"asset", e.Asset},
+	}
+}
+
+// Undistributed is emitted when unclaimed shares and rounding remainders are burned (lu).
+type Undistributed struct {
+	ID     uint64
+	Amount Amount
+	Asset  string
+}
+
+// Type implements Event.
+func (e *Undistributed) Type() string { return TypeUndistributed }
+
+// Fields implements Event.
+func (e *Undistributed) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"amount", e.Amount.String()},
+		{"asset", e.Asset},
+	}
+}
+
+// Archived is emitted when the participant entries of an executed lottery have been pruned (la).
+// Hash commits to the pruned participant list, see the README under "Archiving".
+type Archived struct {
+	ID           uint64
+	Participants uint64
+	Hash         string // lower-case hex SHA-256
+}
+
+// Type implements Event.
+func (e *Archived) Type() string { return TypeArchived }
+
+// Fields implements Event.
+func (e *Archived) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"participants", strconv.FormatUint(e.Participants, 10)},
+		{"hash", e.Hash},
+	}
+}
+
+// PauseChanged is emitted when the contract owner pauses or unpauses actions (cp).
+// Actions lists only the entrypoints whose state actually changed.
+type PauseChanged struct {
+	Actions []string
+	Paused  bool
+	At      int64
+}
+
+// Type implements Event.
+func (e *PauseChanged) Type() string { return TypePauseChanged }
+
+// Fields implements Event.
+func (e *PauseChanged) Fields() []Field {
+	return []Field{
+		{"actions", strings.Join(e.Actions, ",")},
+		{"paused", strconv.FormatBool(e.Paused)},
+		{"at", strconv.FormatInt(e.At, 10)},
+	}
+}
+
+func parsePauseChanged(r *fieldReader) *PauseChanged {
+	e := &PauseChanged{
+		Actions: strings.Split(r.str("actions"), ","),
+		At:      r.int("at"),
+	}
+	paused := r.str("paused")
+	v, err := strconv.ParseBool(paused)
+	if err != nil && r.err == nil {
+		r.fail(errors.New("invalid paused " + strconv.Quote(paused)))
+	}
+	e.Paused = v
+	return e
+}
+
+// ConfigChanged is emitted when the contract owner sets the protocol limits (cc).
+// It always carries the complete configuration in force after the change.
+type ConfigChanged struct {
+	MinBurnPercent         Percent
+	MaxBurnPercent         Percent
+	MinDeadlineHours       uint64
+	MaxDeadlineHours       uint64
+	MaxDonationPercent     Percent
+	MaxBurnDonationPercent Percent
+	MinTicketPrice         Amount
+	MaxNameLength          uint64
+	MaxMetadataLength      uint64
+	At                     int64
+
+	ProtocolFeePercent   Percent // optional in lines from before the protocol fee, which had none
+	MaxCreatorFeePercent Percent // optional in lines from before the creator fee, which had none
+}
+
+// Type implements Event.
+func (e *ConfigChanged) Type() string { return TypeConfigChanged }
+
+// Fields implements Event.
+func (e *ConfigChanged) Fields() []Field {
+	return []Field{
+		{"min_burn_percent", e.MinBurnPercent.String()},
+		{"max_burn_percent", e.MaxBurnPercent.String()},
+		{"min_deadline_hours", strconv.FormatUint(e.MinDeadlineHours, 10)},
+		{"max_deadline_hours", strconv.FormatUint(e.MaxDeadlineHours, 10)},
+		{"max_donation_percent", e.MaxDonationPercent.String()},
+		{"max_burn_donation_percent", e.MaxBurnDonationPercent.String()},
+		{"min_ticket_price", e.MinTicketPrice.String()},
+		{"max_name_length", strconv.FormatUint(e.MaxNameLength, 10)},
+		{"max_metadata_length", strconv.FormatUint(e.MaxMetadataLength, 10)},
+		{"at", strconv.FormatInt(e.At, 10)},
+		{"protocol_fee_percent", e.ProtocolFeePercent.String()},
+		{"max_creator_fee_percent", e.MaxCreatorFeePercent.String()},
+	}
+}
+
+// TreasuryWithdrawn is emitted when the contract owner withdraws collected protocol fees (tw).
+// Balance is what remains in the treasury for the asset.
+type TreasuryWithdrawn struct {
+	Asset     string
+	Amount    Amount
+	Recipient string
+	Balance   Amount
+}
+
+// Type implements Event.
+func (e *TreasuryWithdrawn) Type() string { return TypeTreasuryWithdrawn }
+
+// Fields implements Event.
+func (e *TreasuryWithdrawn) Fields() []Field {
+	return []Field{
+		{"asset", e.Asset},
+		{"amount", e.Amount.String()},
+		{"recipient", e.Recipient},
+		{"balance", e.Balance.String()},
+	}
+}
+
+// CharityRegistered is emitted when the contract owner adds a charity to the registry (cr).
+type CharityRegistered struct {
+	ID      uint64
+	Address string
+	Name    string
+	URL     string
+	At      int64
+}
+
+// Type implements Event.
+func (e *CharityRegistered) Type() string { return TypeCharityRegistered }
+
+// Fields implements Event.
+func (e *CharityRegistered) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"address", e.Address},
+		{"name", e.Name},
+		{"url", e.URL},
+		{"at", strconv.FormatInt(e.At, 10)},
+	}
+}
+
+// CharityRemoved is emitted when the contract owner removes a charity from the registry (cd).
+// Lotteries created before keep the charity ID they announced.
+type CharityRemoved struct {
+	ID      uint64
+	Address string
+	At      int64
+}
+
+// Type implements Event.
+func (e *CharityRemoved) Type() string { return TypeCharityRemoved }
+
+// Fields implements Event.
+func (e *CharityRemoved) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"address", e.Address},
+		{"at", strconv.FormatInt(e.At, 10)},
+	}
+}
+
+// DonationAccepted is emitted when a donation account that is not a registered charity
+// accepts a lottery's donation (lo). A creator naming itself is accepted at creation.
+type DonationAccepted struct {
+	ID      uint64
+	Account string
+}
+
+// Type implements Event.
+func (e *DonationAccepted) Type() string { return TypeDonationAccepted }
+
+// Fields implements Event.
+func (e *DonationAccepted) Fields() []Field {
+	return []Field{
+		{"id", strconv.FormatUint(e.ID, 10)},
+		{"account", e.Account},
+	}
+}
+
+// fieldReader looks up typed fields and keeps the first error.
+type fieldReader struct {
+	fields []Field
+	err    error
+}
+
+// optional returns the value of a field and whether it is present.
+func (r *fieldReader) optional(key string) (string, bool) {
+	for _, f := range r.fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// str returns the value of a required field.
+func (r *fieldReader) str(key string) string {
+	v, ok := r.optional(key)
+	if !ok {
+		r.fail(errors.New("missing field " + key))
+	}
+	return v
+}
+
+func (r *fieldReader) uint(key string) uint64 {
+	s := r.str(key)
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil && r.err == nil {
+		r.fail(errors.New("invalid " + key + " " + strconv.Quote(s)))
+	}
+	return v
+}
+
+func (r *fieldReader) int(key string) int64 {
+	s := r.str(key)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && r.err == nil {
+		r.fail(errors.New("invalid " + key + " " + strconv.Quote(s)))
+	}
+	return v
+}
+
+func (r *fieldReader) amount(key string) Amount {
+	return r.parseAmount(key, r.str(key))
+}
+
+func (r *fieldReader) parseAmount(key string, s string) Amount {
+	if r.err != nil {
+		return 0
+	}
+	v, err := ParseAmount(s)
+	if err != nil {
+		r.fail(errors.New(key + ": " + err.Error()))
+	}
+	return v
+}
+
+func (r *fieldReader) percent(key string) Percent {
+	return r.parsePercent(key, r.str(key))
+}
+
+func (r *fieldReader) parsePercent(key string, s string) Percent {
+	if r.err != nil {
+		return 0
+	}
+	v, err := ParsePercent(s)
+	if err != nil {
+		r.fail(errors.New(key + ": " + err.Error()))
+	}
+	return v
+}
+
+// list splits a list value written by joinList.
+func (r *fieldReader) list(key string, s string) []string {
+	entries, err := splitList(s)
+	if err != nil {
+		r.fail(errors.New(key + ": " + err.Error()))
+	}
+	return entries
+}
+
+func (r *fieldReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
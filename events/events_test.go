@@ -0,0 +1,249 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleEvents covers all fourteen event types including the optional donation, fee and charity fields
+func sampleEvents() []Event {
+	return []Event{
+		&Created{ID: 1, Creator: "hive:alice", Name: "Weekly Draw", CreatedAt: 1703001600, Deadline: 1703606400, Burn: 1000, Ticket: 5000, Asset: "HIVE", Winners: 3, Shares: []Percent{5000, 3000, 2000}},
+		&Created{ID: 2, Creator: "hive:bob", Name: "Help the Ocean", CreatedAt: 1703001600, Deadline: 1704211200, Burn: 1000, Ticket: 2000, Asset: "HIVE", Winners: 2, Shares: []Percent{6000, 4000}, Donations: []DonationShare{{Account: "hive:oceanDAO", Percent: 2000}}},
+		&MetadataChanged{ID: 1, Metadata: "ipfs://example"},
+		&Joined{ID: 1, Participant: "hive:bob", Tickets: 3, Paid: 15000, Asset: "HIVE", TicketStart: 0, TicketEnd: 2},
+		&Executed{ID: 1, Pool: 100000, Burned: 15500, Donated: 0, Asset: "HIVE", Winners: 3, Seed: 12345678901234567890, Tickets: 20, Participants: 5, ExecutedAt: 1703606500},
+		&Payout{ID: 1, Winner: "hive:charlie", Amount: 42250, Share: 5000, Asset: "HIVE", Position: 1},
+		&Donation{ID: 1, Recipient: "hive:oceanDAO", Amount: 10000, Percent: 1000, Asset: "HIVE"},
+		&Undistributed{ID: 1, Amount: 500, Asset: "HIVE"},
+		&Archived{ID: 1, Participants: 5, Hash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+		&PauseChanged{Actions: []string{"create_lottery", "join_lottery"}, Paused: true, At: 1703606500},
+		&ConfigChanged{MinBurnPercent: 500, MaxBurnPercent: 7500, MinDeadlineHours: 1, MaxDeadlineHours: 720, MaxDonationPercent: 5000, MaxBurnDonationPercent: 9000, MinTicketPrice: 100, MaxNameLength: 100, MaxMetadataLength: 500, At: 1703606500, ProtocolFeePercent: 250, MaxCreatorFeePercent: 1000},
+		&Created{ID: 3, Creator: "hive:carol", Name: "Fee Draw", CreatedAt: 1703001600, Deadline: 1703606400, Burn: 1000, Ticket: 1000, Asset: "HIVE", Winners: 1, Shares: []Percent{10000}, ProtocolFee: 250, CreatorFee: 500},
+		&Executed{ID: 3, Pool: 10000, Burned: 1000, Donated: 0, Asset: "HIVE", Winners: 1, Seed: 7, Tickets: 10, Participants: 2, ExecutedAt: 1703606500, TicketsRoot: "ab", ProtocolFee: 250, CreatorFee: 500},
+		&TreasuryWithdrawn{Asset: "HIVE", Amount: 200, Recipient: "hive:ops", Balance: 50},
+		&Created{ID: 4, Creator: "hive:dave", Name: "Split Causes", CreatedAt: 1703001600, Deadline: 1703606400, Burn: 500, Ticket: 1000, Asset: "HIVE", Winners: 1, Shares: []Percent{10000},
+			Donations: []DonationShare{{Account: "hive:oceanDAO", Percent: 1000}, {Account: "did:key:z6Mkf", Percent: 250}}},
+		&CharityRegistered{ID: 1, Address: "hive:oceanDAO", Name: "Ocean DAO", URL: "https://oceandao.org", At: 1703001600},
+		&Created{ID: 5, Creator: "hive:erin", Name: "Registered", CreatedAt: 1703001600, Deadline: 1703606400, Burn: 500, Ticket: 1000, Asset: "HIVE", Winners: 1, Shares: []Percent{10000},
+			Donations: []DonationShare{{Account: "hive:oceanDAO", Percent: 1000, CharityID: 1}, {Account: "hive:friend", Percent: 500}}, OptIn: true},
+		&DonationAccepted{ID: 5, Account: "hive:friend"},
+		&CharityRemoved{ID: 1, Address: "hive:oceanDAO", At: 1703606500},
+	}
+}
+
+// TestLegacyMatchesContractFormat pins FormatLegacy to the Sprintf formats the contract emitted before v2
+func TestLegacyMatchesContractFormat(t *testing.T) {
+	expected := []string{
+		fmt.Sprintf("lc|id:%d|creator:%s|name:%s|created_at:%d|deadline:%d|burn:%.2f|ticket:%.3f|asset:%s|winners:%d|shares:%s",
+			1, "hive:alice", "Weekly Draw", 1703001600, 1703606400, 10.0, 5.0, "HIVE", 3, "50.00,30.00,20.00"),
+		fmt.Sprintf("lc|id:%d|creator:%s|name:%s|created_at:%d|deadline:%d|burn:%.2f|ticket:%.3f|asset:%s|winners:%d|shares:%s",
+			2, "hive:bob", "Help the Ocean", 1703001600, 1704211200, 10.0, 2.0, "HIVE", 2, "60.00,40.00") +
+			fmt.Sprintf("|donation_account:%s|donation_percent:%.2f", "hive:oceanDAO", 20.0),
+		fmt.Sprintf("lm|id:%d|metadata:%s", 1, "ipfs://example"),
+		fmt.Sprintf("lj|id:%d|participant:%s|tickets:%d|paid:%.3f|asset:%s|ticket_start:%d|ticket_end:%d",
+			1, "hive:bob", 3, 15.0, "HIVE", 0, 2),
+		fmt.Sprintf("le|id:%d|pool:%.3f|burned:%.3f|donated:%.3f|asset:%s|winners:%d|seed:%d|tickets:%d|participants:%d|executed_at:%d",
+			1, 100.0, 15.5, 0.0, "HIVE", 3, uint64(12345678901234567890), 20, 5, 1703606500),
+		fmt.Sprintf("lp|id:%d|winner:%s|amount:%.3f|share:%.2f|asset:%s|position:%d", 1, "hive:charlie", 42.25, 50.0, "HIVE", 1),
+		fmt.Sprintf("ld|id:%d|recipient:%s|amount:%.3f|percent:%.2f|asset:%s", 1, "hive:oceanDAO", 10.0, 10.0, "HIVE"),
+		fmt.Sprintf("lu|id:%d|amount:%.3f|asset:%s", 1, 0.5, "HIVE"),
+		fmt.Sprintf("la|id:%d|participants:%d|hash:%s", 1, 5, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+		fmt.Sprintf("cp|actions:%s|paused:%t|at:%d", "create_lottery,join_lottery", true, 1703606500),
+		"cc|min_burn_percent:5.00|max_burn_percent:75.00|min_deadline_hours:1|max_deadline_hours:720|max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.100|max_name_length:100|max_metadata_length:500|at:1703606500|protocol_fee_percent:2.50|max_creator_fee_percent:10.00",
+		"lc|id:3|creator:hive:carol|name:Fee Draw|created_at:1703001600|deadline:1703606400|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|protocol_fee:2.50|creator_fee:5.00",
+		"le|id:3|pool:10.000|burned:1.000|donated:0.000|asset:HIVE|winners:1|seed:7|tickets:10|participants:2|executed_at:1703606500|tickets_root:ab|protocol_fee:0.250|creator_fee:0.500",
+		"tw|asset:HIVE|amount:0.200|recipient:hive:ops|balance:0.050",
+		"lc|id:4|creator:hive:dave|name:Split Causes|created_at:1703001600|deadline:1703606400|burn:5.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:oceanDAO,did:key:z6Mkf|donation_percent:10.00,2.50",
+		"cr|id:1|address:hive:oceanDAO|name:Ocean DAO|url:https://oceandao.org|at:1703001600",
+		"lc|id:5|creator:hive:erin|name:Registered|created_at:1703001600|deadline:1703606400|burn:5.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:oceanDAO,hive:friend|donation_percent:10.00,5.00|charity_id:1,0",
+		"lo|id:5|account:hive:friend",
+		"cd|id:1|address:hive:oceanDAO|at:1703606500",
+	}
+
+	for i, ev := range sampleEvents() {
+		assert.Equal(t, expected[i], FormatLegacy(ev))
+	}
+}
+
+// TestRoundTrip tests Parse(Format(ev)) == ev and Format(Parse(line)) == line for both formats
+func TestRoundTrip(t *testing.T) {
+	for _, ev := range sampleEvents() {
+		for _, line := range []string{Format(ev), FormatLegacy(ev)} {
+			parsed, err := Parse(line)
+			require.NoError(t, err, line)
+			assert.Equal(t, ev, parsed, line)
+		}
+
+		v2 := Format(ev)
+		parsed, _ := Parse(v2)
+		assert.Equal(t, v2, Format(parsed))
+		assert.False(t, IsLegacy(v2))
+		assert.True(t, IsLegacy(FormatLegacy(ev)))
+	}
+}
+
+// TestReadmeExamples tests that the documented example lines parse
+func TestReadmeExamples(t *testing.T) {
+	lines := []string{
+		"lc|id:1|creator:hive:alice|name:Weekly Draw|created_at:1703001600|deadline:1703606400|burn:10.00|ticket:5.000|asset:HIVE|winners:3|shares:50.00,30.00,20.00",
+		"lm|id:1|metadata:ipfs://example",
+		"lj|id:1|participant:hive:bob|tickets:3|paid:15.000|asset:HIVE|ticket_start:0|ticket_end:2",
+		"le|id:1|pool:100.000|burned:15.500|donated:0.000|asset:HIVE|winners:3|seed:12345678901234567890|tickets:20|participants:5|executed_at:1703606500",
+		"lp|id:1|winner:hive:charlie|amount:42.250|share:50.00|asset:HIVE|position:1",
+		"ld|id:1|recipient:hive:oceanDAO|amount:10.000|percent:10.00|asset:HIVE",
+		"lu|id:1|amount:0.500|asset:HIVE",
+		"la|id:1|participants:5|hash:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		"cp|actions:join_lottery|paused:false|at:1703606500",
+		"cc|min_burn_percent:5.00|max_burn_percent:75.00|min_deadline_hours:1|max_deadline_hours:2160|max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.001|max_name_length:100|max_metadata_length:500|at:1703606500|protocol_fee_percent:0.00|max_creator_fee_percent:10.00",
+		"tw|asset:HIVE|amount:12.500|recipient:hive:tibfox|balance:0.000",
+		"cr|id:1|address:hive:oceanDAO|name:Ocean DAO|url:https://oceandao.org|at:1703001600",
+		"cd|id:1|address:hive:oceanDAO|at:1703606500",
+		"lo|id:2|account:hive:friend",
+	}
+	types := []string{TypeCreated, TypeMetadataChanged, TypeJoined, TypeExecuted, TypePayout, TypeDonation, TypeUndistributed, TypeArchived, TypePauseChanged, TypeConfigChanged, TypeTreasuryWithdrawn,
+		TypeCharityRegistered, TypeCharityRemoved, TypeDonationAccepted}
+	for i, line := range lines {
+		ev, err := Parse(line)
+		require.NoError(t, err, line)
+		assert.Equal(t, types[i], ev.Type())
+		assert.Equal(t, line, FormatLegacy(ev))
+	}
+}
+
+// TestEscaping tests that free-form values cannot break a v2 line
+func TestEscaping(t *testing.T) {
+	ev := &MetadataChanged{ID: 7, Metadata: "a|b:c%d\nline2\r"}
+	line := Format(ev)
+	assert.Equal(t, "lm|v:2|id:7|metadata:a%7Cb:c%25d%0Aline2%0D", line)
+
+	parsed, err := Parse(line)
+	require.NoError(t, err)
+	assert.Equal(t, ev, parsed)
+
+	created := &Created{ID: 1, Creator: "did:pkh:eip155:1:0xabc", Name: "50% off", Asset: "HIVE", Winners: 1, Shares: []Percent{10000}}
+	parsed, err = Parse(Format(created))
+	require.NoError(t, err)
+	assert.Equal(t, created, parsed)
+
+	created.Donations = []DonationShare{{Account: "contract:a,b", Percent: 500, CharityID: 1}, {Account: "contract:c|burn:0%", Percent: 100, CharityID: 2}}
+	created.OptIn, created.Verified = true, true
+	line = Format(created)
+	assert.Contains(t, line, "|donation_account:contract:a%252Cb,contract:c%7Cburn:0%2525|donation_percent:5.00,1.00|")
+	parsed, err = Parse(line)
+	require.NoError(t, err)
+	assert.Equal(t, created, parsed)
+}
+
+// TestLegacyMetadataWithPipe tests that legacy metadata takes the rest of the line
+func TestLegacyMetadataWithPipe(t *testing.T) {
+	ev, err := Parse(`lm|id:1|metadata:meta|data: {"note":"do not parse"}`)
+	require.NoError(t, err)
+	assert.Equal(t, &MetadataChanged{ID: 1, Metadata: `meta|data: {"note":"do not parse"}`}, ev)
+}
+
+// TestParseErrors tests rejection of malformed lines
+func TestParseErrors(t *testing.T) {
+	bad := []string{
+		"",
+		"xx|v:2|id:1",
+		"lu|v:2|id:1|asset:HIVE",
+		"lu|v:2|id:x|amount:1.000|asset:HIVE",
+		"lu|v:2|id:1|amount:1.0001|asset:HIVE",
+		"lu|v:2|id:1|amount:1.000|asset:HI%7",
+		"lu|v:2|id:1|amount:1.000|asset:HI%ZZ",
+		"lu|v:2|id:1|amount|asset:HIVE",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:50.00,x",
+		"cp|v:2|actions:join_lottery|paused:maybe|at:0",
+		"cc|v:2|min_burn_percent:5.00|max_burn_percent:75.00|min_deadline_hours:1|max_deadline_hours:2160|max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.001|max_name_length:100|max_metadata_length:500|at:0|protocol_fee_percent:x",
+		"le|v:2|id:1|pool:1.000|burned:0.000|donated:0.000|asset:HIVE|winners:1|seed:1|tickets:1|participants:1|executed_at:0|protocol_fee:x",
+		"le|v:2|id:1|pool:1.000|burned:0.000|donated:0.000|asset:HIVE|winners:1|seed:1|tickets:1|participants:1|executed_at:0|creator_fee:1.0001",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|creator_fee:x",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a,hive:b|donation_percent:5.00",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a|donation_percent:5.00,1.00",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a,hive:b|donation_percent:5.00,x",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a,hive:b|donation_percent:5.00,1.00|charity_id:1",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a|donation_percent:5.00|charity_id:x",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|charity_id:1",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a|donation_percent:5.00|charity_id:1|verified:maybe",
+		"lc|v:2|id:1|creator:a|name:b|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00|donation_account:hive:a%252|donation_percent:5.00",
+		"cr|v:2|id:1|address:hive:a|name:b|at:0",
+		"lo|v:2|id:x|account:hive:a",
+	}
+	for _, line := range bad {
+		_, err := Parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+// TestParseIgnoresUnknownFields tests that appended fields do not break older parsers
+func TestParseIgnoresUnknownFields(t *testing.T) {
+	ev, err := Parse("lu|v:2|id:1|amount:0.500|asset:HIVE|future:field")
+	require.NoError(t, err)
+	assert.Equal(t, &Undistributed{ID: 1, Amount: 500, Asset: "HIVE"}, ev)
+}
+
+// TestExecutedTicketsRoot tests the optional Merkle root appended to le
+func TestExecutedTicketsRoot(t *testing.T) {
+	ev := &Executed{ID: 1, Pool: 100000, Burned: 15500, Asset: "HIVE", Winners: 3, Seed: 42, Tickets: 20, Participants: 5, ExecutedAt: 1703606500,
+		TicketsRoot: "5c3b1d7e0f8a9b2c4d6e8f0a1b3c5d7e9f1a2b4c6d8e0f1a3b5c7d9e1f2a4b6c"}
+	line := Format(ev)
+	assert.True(t, strings.HasSuffix(line, "|executed_at:1703606500|tickets_root:5c3b1d7e0f8a9b2c4d6e8f0a1b3c5d7e9f1a2b4c6d8e0f1a3b5c7d9e1f2a4b6c"))
+	parsed, err := Parse(line)
+	require.NoError(t, err)
+	assert.Equal(t, ev, parsed)
+
+	// Lines emitted before the root existed parse with an empty root
+	ev.TicketsRoot = ""
+	parsed, err = Parse("le|v:2|id:1|pool:100.000|burned:15.500|donated:0.000|asset:HIVE|winners:3|seed:42|tickets:20|participants:5|executed_at:1703606500")
+	require.NoError(t, err)
+	assert.Equal(t, ev, parsed)
+}
+
+// TestProtocolFeeFields tests that lines from before the protocol and creator fees parse without them
+func TestProtocolFeeFields(t *testing.T) {
+	ev, err := Parse("lc|v:2|id:1|creator:hive:alice|name:Draw|created_at:0|deadline:0|burn:10.00|ticket:1.000|asset:HIVE|winners:1|shares:100.00")
+	require.NoError(t, err)
+	assert.Equal(t, Percent(0), ev.(*Created).ProtocolFee)
+	assert.Equal(t, Percent(0), ev.(*Created).CreatorFee)
+
+	ev, err = Parse("cc|v:2|min_burn_percent:5.00|max_burn_percent:75.00|min_deadline_hours:1|max_deadline_hours:2160|max_donation_percent:50.00|max_burn_donation_percent:90.00|min_ticket_price:0.001|max_name_length:100|max_metadata_length:500|at:1703606500")
+	require.NoError(t, err)
+	assert.Equal(t, Percent(0), ev.(*ConfigChanged).ProtocolFeePercent)
+	assert.Equal(t, Percent(0), ev.(*ConfigChanged).MaxCreatorFeePercent)
+	assert.True(t, strings.HasSuffix(Format(ev), "|at:1703606500|protocol_fee_percent:0.00|max_creator_fee_percent:0.00"))
+}
+
+// TestNumbers tests fixed-point formatting and parsing
+func TestNumbers(t *testing.T) {
+	amounts := map[string]Amount{"0.000": 0, "0.001": 1, "5.000": 5000, "42.250": 42250, "-1.500": -1500, "9223372036854775.807": 9223372036854775807}
+	for s, v := range amounts {
+		assert.Equal(t, s, v.String())
+		parsed, err := ParseAmount(s)
+		require.NoError(t, err)
+		assert.Equal(t, v, parsed)
+	}
+
+	short, err := ParseAmount("5")
+	require.NoError(t, err)
+	assert.Equal(t, Amount(5000), short)
+
+	for _, s := range []string{"", ".5", "5.", "1e3", "1.0001", "abc", "9223372036854775.808"} {
+		_, err := ParseAmount(s)
+		assert.Error(t, err, s)
+	}
+
+	fixedErrors := map[string]error{"": ErrFixedEmpty, "5.": ErrFixedSyntax, "+1": ErrFixedSyntax, "1.0001": ErrFixedPrecision, "9223372036854775.808": ErrFixedRange}
+	for s, want := range fixedErrors {
+		_, err := ParseFixed(s, 3)
+		assert.Equal(t, want, err, s)
+	}
+
+	assert.Equal(t, "33.33", Percent(3333).String())
+	assert.Equal(t, Percent(1250), PercentFromFloat(12.5))
+	assert.Equal(t, Percent(-500), PercentFromFloat(-5))
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Amount is a token amount scaled by 1000 (3 decimals, as used for HIVE and HBD).
+// It matches the contract's Amount type so values survive a format/parse round trip exactly.
+type Amount int64
+
+// AmountScale is the number of Amount units per whole token.
+const AmountScale = 1000
+
+// String renders the amount with exactly 3 decimals (e.g. 5.000).
+func (a Amount) String() string {
+	return formatFixed(int64(a), 3)
+}
+
+// MarshalText renders the amount as its decimal string so JSON output stays exact.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText parses the decimal string written by MarshalText.
+func (a *Amount) UnmarshalText(text []byte) error {
+	v, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// ParseAmount parses a decimal amount with up to 3 decimals (e.g. "5.000" or "5").
+func ParseAmount(s string) (Amount, error) {
+	v, err := ParseFixed(s, 3)
+	if err != nil {
+		return 0, errors.New("invalid amount " + strconv.Quote(s) + ": " + err.Error())
+	}
+	return Amount(v), nil
+}
+
+// Percent is a percentage in hundredths of a percent (basis points), e.g. 10.00% = 1000.
+type Percent int64
+
+// String renders the percentage with exactly 2 decimals (e.g. 10.00).
+func (p Percent) String() string {
+	return formatFixed(int64(p), 2)
+}
+
+// MarshalText renders the percentage as its decimal string so JSON output stays exact.
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses the decimal string written by MarshalText.
+func (p *Percent) UnmarshalText(text []byte) error {
+	v, err := ParsePercent(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// ParsePercent parses a decimal percentage with up to 2 decimals (e.g. "10.00" or "10").
+func ParsePercent(s string) (Percent, error) {
+	v, err := ParseFixed(s, 2)
+	if err != nil {
+		return 0, errors.New("invalid percent " + strconv.Quote(s) + ": " + err.Error())
+	}
+	return Percent(v), nil
+}
+
+// PercentFromFloat rounds a float percentage (e.g. 12.5) to basis points.
+func PercentFromFloat(v float64) Percent {
+	if v < 0 {
+		return Percent(v*100 - 0.5)
+	}
+	return Percent(v*100 + 0.5)
+}
+
+// formatFixed renders v / 10^decimals with exactly the given number of decimals.
+func formatFixed(v int64, decimals int) string {
+	neg := v < 0
+	u := uint64(v)
+	if neg {
+		u = uint64(-v)
+	}
+	digits := strconv.FormatUint(u, 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	split := len(digits) - decimals
+	out := digits[:split] + "." + digits[split:]
+	if neg {
+		return "-" + out
+	}
+	return out
+}
+
+// Errors returned by ParseFixed, so callers can map them to their own messages.
+var (
+	ErrFixedEmpty     = errors.New("empty value")
+	ErrFixedSyntax    = errors.New("malformed decimal")
+	ErrFixedPrecision = errors.New("too many decimals")
+	ErrFixedRange     = errors.New("out of range")
+)
+
+// ParseFixed parses a decimal string into an integer scaled by 10^decimals without going
+// through a float. It is shared with the contract, which rejects the sign before calling it.
+// More decimals than allowed, exponents and overflow are rejected rather than rounded.
+func ParseFixed(s string, decimals int) (int64, error) {
+	if s == "" {
+		return 0, ErrFixedEmpty
+	}
+	neg := false
+	if s[0] == '-' {
+		neg = true
+		s = s[1:]
+	}
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" || (hasDot && frac == "") {
+		return 0, ErrFixedSyntax
+	}
+	if len(frac) > decimals {
+		return 0, ErrFixedPrecision
+	}
+	frac += strings.Repeat("0", decimals-len(frac))
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return 0, ErrFixedSyntax
+		}
+	}
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrFixedRange
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
@@ -0,0 +1,54 @@
+package indexer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler returns a read-only HTTP API over the indexer state:
+//
+//	GET /lotteries        all lotteries sorted by ID
+//	GET /lotteries/{id}   a single lottery
+//	GET /issues           accounting violations and unparsable events
+//	GET /snapshot         everything above plus line counters
+//
+// Responses are encoded under the read lock so they never observe a half-applied event.
+func (ix *Indexer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /lotteries", func(w http.ResponseWriter, r *http.Request) {
+		ix.mu.RLock()
+		defer ix.mu.RUnlock()
+		writeJSON(w, http.StatusOK, ix.snapshot().Lotteries)
+	})
+	mux.HandleFunc("GET /lotteries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid lottery ID"})
+			return
+		}
+		ix.mu.RLock()
+		defer ix.mu.RUnlock()
+		l := ix.lotteries[id]
+		if l == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "lottery not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	})
+	mux.HandleFunc("GET /issues", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, ix.Issues())
+	})
+	mux.HandleFunc("GET /snapshot", func(w http.ResponseWriter, r *http.Request) {
+		ix.mu.RLock()
+		defer ix.mu.RUnlock()
+		writeJSON(w, http.StatusOK, ix.snapshot())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,574 @@
+// Package indexer is the reference projection of the lottery contract's event log.
+//
+// It consumes the ordered stream of contract log lines, rebuilds every lottery with its
+// participants, ticket ranges, payouts, donations and burns, and cross-checks the accounting
+// of each execution (pool = burned + donated + paid to winners). Anything that does not add
+// up is recorded as an Issue instead of being silently accepted.
+package indexer
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"okinoko_lottery/events"
+)
+
+// Lottery states as reported by the indexer.
+const (
+	StateActive   = "active"
+	StateExecuted = "executed"
+)
+
+// Lottery is the reconstructed state of one lottery.
+type Lottery struct {
+	ID                 uint64                 `json:"id"`
+	Creator            string                 `json:"creator"`
+	Name               string                 `json:"name"`
+	CreatedAt          int64                  `json:"created_at"`
+	Deadline           int64                  `json:"deadline"`
+	Burn               events.Percent         `json:"burn"`
+	Ticket             events.Amount          `json:"ticket"`
+	Asset              string                 `json:"asset"`
+	Shares             []events.Percent       `json:"shares"`
+	DonationRecipients []events.DonationShare `json:"donation_recipients,omitempty"`
+	VerifiedCharity    bool                   `json:"verified_charity,omitempty"`
+	ProtocolFee        events.Percent         `json:"protocol_fee,omitempty"`
+	CreatorFee         events.Percent         `json:"creator_fee,omitempty"`
+	Metadata           string                 `json:"metadata"`
+	State              string                 `json:"state"`
+
+	Pool         events.Amount  `json:"pool"`
+	Tickets      uint64         `json:"tickets"`
+	Participants []*Participant `json:"participants"`
+
+	Payouts       []Payout      `json:"payouts"`
+	Donations     []Donation    `json:"donations"`
+	Undistributed events.Amount `json:"undistributed"`
+	Execution     *Execution    `json:"execution,omitempty"`
+
+	// ArchiveHash is the participants hash from the la event, set once the participants are pruned on-chain
+	ArchiveHash string `json:"archive_hash,omitempty"`
+
+	// PendingDonations lists the donation accounts that have not accepted yet (lo), no tickets may be sold until it is empty
+	PendingDonations []string `json:"pending_donations,omitempty"`
+
+	participantIndex map[string]*Participant
+}
+
+// Participant is a buyer with all of their ticket ranges in purchase order.
+type Participant struct {
+	Address string        `json:"address"`
+	Tickets uint64        `json:"tickets"`
+	Paid    events.Amount `json:"paid"`
+	Ranges  []TicketRange `json:"ranges"`
+}
+
+// TicketRange is an inclusive range of ticket numbers bought in one purchase.
+type TicketRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// Payout is a prize paid to a winner.
+type Payout struct {
+	Position uint64         `json:"position"`
+	Winner   string         `json:"winner"`
+	Amount   events.Amount  `json:"amount"`
+	Share    events.Percent `json:"share"`
+}
+
+// Donation is a donation paid out at execution.
+type Donation struct {
+	Recipient string         `json:"recipient"`
+	Amount    events.Amount  `json:"amount"`
+	Percent   events.Percent `json:"percent"`
+}
+
+// Execution holds the totals reported by the lottery's le event.
+type Execution struct {
+	Pool         events.Amount `json:"pool"`
+	Burned       events.Amount `json:"burned"`
+	Donated      events.Amount `json:"donated"`
+	Paid         events.Amount `json:"paid"`
+	Winners      uint64        `json:"winners"`
+	Seed         uint64        `json:"seed,string"`
+	Tickets      uint64        `json:"tickets"`
+	Participants uint64        `json:"participants"`
+	ExecutedAt   int64         `json:"executed_at"`
+	TicketsRoot  string        `json:"tickets_root,omitempty"`
+	ProtocolFee  events.Amount `json:"protocol_fee,omitempty"`
+	CreatorFee   events.Amount `json:"creator_fee,omitempty"`
+}
+
+// Charity is an entry of the contract owner's charity registry.
+type Charity struct {
+	ID      uint64 `json:"id"`
+	Address string `json:"address"`
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+}
+
+// Issue is an event that could not be applied or violates the accounting.
+type Issue struct {
+	Line      int    `json:"line"`
+	LotteryID uint64 `json:"lottery_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Snapshot is the JSON view of the indexer state.
+type Snapshot struct {
+	Lines     int        `json:"lines"`
+	Events    int        `json:"events"`
+	Skipped   int        `json:"skipped"`
+	Lotteries []*Lottery `json:"lotteries"`
+	Issues    []Issue    `json:"issues"`
+
+	// Paused lists the contract actions currently paused by the owner, sorted by name
+	Paused []string `json:"paused"`
+
+	// Treasury holds the protocol fees the contract keeps per asset, net of withdrawals
+	Treasury map[string]events.Amount `json:"treasury"`
+
+	// Charities is the current charity registry, sorted by ID
+	Charities []*Charity `json:"charities"`
+}
+
+// Indexer rebuilds lottery state from log lines. It is safe for concurrent reads while lines are applied.
+type Indexer struct {
+	mu        sync.RWMutex
+	lotteries map[uint64]*Lottery
+	issues    []Issue
+	lines     int
+	applied   int
+	skipped   int
+	paused    map[string]bool
+	treasury  map[string]events.Amount
+	charities map[uint64]*Charity
+
+	// pendingLegacy holds a legacy line until the next line shows whether it is the
+	// compatibility copy of a v2 event (emitted right before it) or a standalone legacy event.
+	pendingLegacy     events.Event
+	pendingLegacyLine string
+	pendingLegacyNo   int
+}
+
+// New returns an empty indexer.
+func New() *Indexer {
+	return &Indexer{
+		lotteries: make(map[uint64]*Lottery),
+		paused:    make(map[string]bool),
+		treasury:  make(map[string]events.Amount),
+		charities: make(map[uint64]*Charity),
+	}
+}
+
+// Consume applies every line from r in order and flushes any pending legacy line at the end.
+func (ix *Indexer) Consume(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ix.Apply(scanner.Text())
+	}
+	ix.Flush()
+	return scanner.Err()
+}
+
+// Apply processes one log line. Lines that are not lottery events are counted as skipped.
+func (ix *Indexer) Apply(line string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.lines++
+	ev, err := events.Parse(line)
+	if err != nil {
+		// Only lines claiming to be lottery events are worth an issue, other contract logs are skipped
+		if isLotteryEventType(line) {
+			ix.issue(ix.lines, 0, "unparsable event: "+err.Error())
+		}
+		ix.skipped++
+		return
+	}
+
+	if events.IsLegacy(line) {
+		ix.flushPending()
+		ix.pendingLegacy = ev
+		ix.pendingLegacyLine = line
+		ix.pendingLegacyNo = ix.lines
+		return
+	}
+
+	// A v2 line directly after its legacy copy replaces it
+	if ix.pendingLegacy != nil && events.FormatLegacy(ev) == ix.pendingLegacyLine {
+		ix.pendingLegacy = nil
+	}
+	ix.flushPending()
+	ix.apply(ix.lines, ev)
+}
+
+// Flush applies a legacy line still waiting for a possible v2 copy. Call it at the end of a stream.
+func (ix *Indexer) Flush() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.flushPending()
+}
+
+func (ix *Indexer) flushPending() {
+	if ix.pendingLegacy == nil {
+		return
+	}
+	ev, lineNo := ix.pendingLegacy, ix.pendingLegacyNo
+	ix.pendingLegacy = nil
+	ix.apply(lineNo, ev)
+}
+
+// apply dispatches a parsed event, the caller holds the write lock.
+func (ix *Indexer) apply(lineNo int, ev events.Event) {
+	ix.applied++
+	switch e := ev.(type) {
+	case *events.Created:
+		ix.applyCreated(lineNo, e)
+	case *events.MetadataChanged:
+		if l := ix.lottery(lineNo, e.ID, e); l != nil {
+			l.Metadata = e.Metadata
+		}
+	case *events.Joined:
+		ix.applyJoined(lineNo, e)
+	case *events.Payout:
+		if l := ix.activeLottery(lineNo, e.ID, e); l != nil {
+			if l.participantIndex[e.Winner] == nil {
+				ix.issue(lineNo, e.ID, "payout to "+e.Winner+" who holds no tickets")
+			}
+			l.Payouts = append(l.Payouts, Payout{Position: e.Position, Winner: e.Winner, Amount: e.Amount, Share: e.Share})
+		}
+	case *events.Donation:
+		if l := ix.activeLottery(lineNo, e.ID, e); l != nil {
+			if !l.announcesDonation(e.Recipient) {
+				ix.issue(lineNo, e.ID, "donation to "+e.Recipient+" which the lottery did not announce")
+			}
+			l.Donations = append(l.Donations, Donation{Recipient: e.Recipient, Amount: e.Amount, Percent: e.Percent})
+		}
+	case *events.Undistributed:
+		if l := ix.activeLottery(lineNo, e.ID, e); l != nil {
+			l.Undistributed += e.Amount
+		}
+	case *events.Executed:
+		ix.applyExecuted(lineNo, e)
+	case *events.Archived:
+		ix.applyArchived(lineNo, e)
+	case *events.PauseChanged:
+		for _, action := range e.Actions {
+			if e.Paused {
+				ix.paused[action] = true
+			} else {
+				delete(ix.paused, action)
+			}
+		}
+	case *events.TreasuryWithdrawn:
+		ix.applyTreasuryWithdrawn(lineNo, e)
+	case *events.CharityRegistered:
+		if ix.charities[e.ID] != nil {
+			ix.issue(lineNo, 0, "charity "+strconv.FormatUint(e.ID, 10)+" registered twice")
+		}
+		ix.charities[e.ID] = &Charity{ID: e.ID, Address: e.Address, Name: e.Name, URL: e.URL}
+	case *events.CharityRemoved:
+		if c := ix.charities[e.ID]; c == nil || c.Address != e.Address {
+			ix.issue(lineNo, 0, "removal of unknown charity "+strconv.FormatUint(e.ID, 10))
+		}
+		delete(ix.charities, e.ID)
+	case *events.DonationAccepted:
+		ix.applyDonationAccepted(lineNo, e)
+	}
+}
+
+// announcesDonation reports whether the lc event named the account as a donation recipient
+func (l *Lottery) announcesDonation(account string) bool {
+	for _, r := range l.DonationRecipients {
+		if r.Account == account {
+			return true
+		}
+	}
+	return false
+}
+
+func (ix *Indexer) applyCreated(lineNo int, e *events.Created) {
+	if _, exists := ix.lotteries[e.ID]; exists {
+		ix.issue(lineNo, e.ID, "lottery created twice")
+		return
+	}
+	if uint64(len(e.Shares)) != e.Winners {
+		ix.issue(lineNo, e.ID, "winner count does not match the number of shares")
+	}
+	ix.lotteries[e.ID] = &Lottery{
+		ID:                 e.ID,
+		Creator:            e.Creator,
+		Name:               e.Name,
+		CreatedAt:          e.CreatedAt,
+		Deadline:           e.Deadline,
+		Burn:               e.Burn,
+		Ticket:             e.Ticket,
+		Asset:              e.Asset,
+		Shares:             e.Shares,
+		DonationRecipients: e.Donations,
+		ProtocolFee:        e.ProtocolFee,
+		CreatorFee:         e.CreatorFee,
+		State:              StateActive,
+		Participants:       []*Participant{},
+		Payouts:            []Payout{},
+		Donations:          []Donation{},
+		participantIndex:   make(map[string]*Participant),
+	}
+
+	// Lotteries announcing charity IDs sell no tickets until the unregistered recipients accept
+	l := ix.lotteries[e.ID]
+	l.VerifiedCharity = e.Verified
+	if e.OptIn {
+		for _, d := range e.Donations {
+			if d.CharityID == 0 {
+				l.PendingDonations = append(l.PendingDonations, d.Account)
+				continue
+			}
+			if c := ix.charities[d.CharityID]; c == nil || c.Address != d.Account {
+				ix.issue(lineNo, e.ID, d.Account+" announced as unregistered charity "+strconv.FormatUint(d.CharityID, 10))
+			}
+		}
+	}
+}
+
+// applyDonationAccepted removes a donation account from the lottery's pending acceptances
+func (ix *Indexer) applyDonationAccepted(lineNo int, e *events.DonationAccepted) {
+	l := ix.activeLottery(lineNo, e.ID, e)
+	if l == nil {
+		return
+	}
+	for i, account := range l.PendingDonations {
+		if account == e.Account {
+			l.PendingDonations = append(l.PendingDonations[:i], l.PendingDonations[i+1:]...)
+			return
+		}
+	}
+	ix.issue(lineNo, e.ID, "donation acceptance from "+e.Account+" which the lottery was not waiting for")
+}
+
+func (ix *Indexer) applyJoined(lineNo int, e *events.Joined) {
+	l := ix.activeLottery(lineNo, e.ID, e)
+	if l == nil {
+		return
+	}
+	if e.Tickets == 0 {
+		ix.issue(lineNo, e.ID, "join without tickets")
+		return
+	}
+	if e.TicketStart != l.Tickets || e.TicketEnd != e.TicketStart+e.Tickets-1 {
+		ix.issue(lineNo, e.ID, "ticket range "+formatRange(e.TicketStart, e.TicketEnd)+" does not continue at ticket "+strconv.FormatUint(l.Tickets, 10))
+	}
+	if e.Paid != events.Amount(e.Tickets)*l.Ticket {
+		ix.issue(lineNo, e.ID, "paid "+e.Paid.String()+" for "+strconv.FormatUint(e.Tickets, 10)+" ticket(s) at "+l.Ticket.String())
+	}
+	if e.Asset != l.Asset {
+		ix.issue(lineNo, e.ID, "join paid in "+e.Asset+" instead of "+l.Asset)
+	}
+	if len(l.PendingDonations) > 0 {
+		ix.issue(lineNo, e.ID, "tickets sold before "+l.PendingDonations[0]+" accepted the donation")
+	}
+
+	p := l.participantIndex[e.Participant]
+	if p == nil {
+		p = &Participant{Address: e.Participant, Ranges: []TicketRange{}}
+		l.participantIndex[e.Participant] = p
+		l.Participants = append(l.Participants, p)
+	}
+	p.Tickets += e.Tickets
+	p.Paid += e.Paid
+	p.Ranges = append(p.Ranges, TicketRange{Start: e.TicketStart, End: e.TicketEnd})
+
+	l.Tickets += e.Tickets
+	l.Pool += e.Paid
+}
+
+func (ix *Indexer) applyExecuted(lineNo int, e *events.Executed) {
+	l := ix.activeLottery(lineNo, e.ID, e)
+	if l == nil {
+		return
+	}
+
+	paid := events.Amount(0)
+	for _, p := range l.Payouts {
+		paid += p.Amount
+	}
+	donated := events.Amount(0)
+	for _, d := range l.Donations {
+		donated += d.Amount
+	}
+
+	l.State = StateExecuted
+	l.Execution = &Execution{
+		Pool:         e.Pool,
+		Burned:       e.Burned,
+		Donated:      e.Donated,
+		Paid:         paid,
+		Winners:      e.Winners,
+		Seed:         e.Seed,
+		Tickets:      e.Tickets,
+		Participants: e.Participants,
+		ExecutedAt:   e.ExecutedAt,
+		TicketsRoot:  e.TicketsRoot,
+		ProtocolFee:  e.ProtocolFee,
+		CreatorFee:   e.CreatorFee,
+	}
+	ix.treasury[e.Asset] += e.ProtocolFee
+
+	// Cross-check the execution against what the join, payout and donation events add up to
+	if e.Pool != l.Pool {
+		ix.issue(lineNo, e.ID, "executed pool "+e.Pool.String()+" differs from joined total "+l.Pool.String())
+	}
+	if e.Burned+e.Donated+paid+e.ProtocolFee+e.CreatorFee != e.Pool {
+		ix.issue(lineNo, e.ID, "accounting mismatch: burned "+e.Burned.String()+" + donated "+e.Donated.String()+" + paid "+paid.String()+
+			" + protocol fee "+e.ProtocolFee.String()+" + creator fee "+e.CreatorFee.String()+" != pool "+e.Pool.String())
+	}
+	if e.ProtocolFee > 0 && l.ProtocolFee == 0 {
+		ix.issue(lineNo, e.ID, "protocol fee "+e.ProtocolFee.String()+" taken from a lottery created without one")
+	}
+	if e.CreatorFee > 0 && l.CreatorFee == 0 {
+		ix.issue(lineNo, e.ID, "creator fee "+e.CreatorFee.String()+" taken from a lottery created without one")
+	}
+	if e.Donated != donated {
+		ix.issue(lineNo, e.ID, "executed donation "+e.Donated.String()+" differs from donation events "+donated.String())
+	}
+	if e.Burned < l.Undistributed {
+		ix.issue(lineNo, e.ID, "burned "+e.Burned.String()+" is less than the undistributed amount "+l.Undistributed.String())
+	}
+	if e.Tickets != l.Tickets {
+		ix.issue(lineNo, e.ID, "executed ticket count "+strconv.FormatUint(e.Tickets, 10)+" differs from joined tickets "+strconv.FormatUint(l.Tickets, 10))
+	}
+	if e.Participants != uint64(len(l.Participants)) {
+		ix.issue(lineNo, e.ID, "executed participant count "+strconv.FormatUint(e.Participants, 10)+" differs from "+strconv.Itoa(len(l.Participants))+" joined")
+	}
+	if e.Winners != uint64(len(l.Payouts)) {
+		ix.issue(lineNo, e.ID, "executed winner count "+strconv.FormatUint(e.Winners, 10)+" differs from "+strconv.Itoa(len(l.Payouts))+" payout event(s)")
+	}
+}
+
+// applyArchived records the participants hash. The hash itself is checked by the verifier,
+// which recomputes it from the participant list.
+func (ix *Indexer) applyArchived(lineNo int, e *events.Archived) {
+	l := ix.lottery(lineNo, e.ID, e)
+	if l == nil {
+		return
+	}
+	if l.State != StateExecuted {
+		ix.issue(lineNo, e.ID, "la event before execution")
+		return
+	}
+	if e.Participants != uint64(len(l.Participants)) {
+		ix.issue(lineNo, e.ID, "archived participant count "+strconv.FormatUint(e.Participants, 10)+" differs from "+strconv.Itoa(len(l.Participants))+" joined")
+	}
+	l.ArchiveHash = e.Hash
+}
+
+// applyTreasuryWithdrawn books a withdrawal against the fees collected from le events
+func (ix *Indexer) applyTreasuryWithdrawn(lineNo int, e *events.TreasuryWithdrawn) {
+	balance := ix.treasury[e.Asset] - e.Amount
+	if balance != e.Balance {
+		ix.issue(lineNo, 0, "treasury withdrawal leaves "+e.Balance.String()+" "+e.Asset+" but collected fees leave "+balance.String())
+	}
+	ix.treasury[e.Asset] = e.Balance
+}
+
+// lottery returns the lottery an event refers to, recording an issue if it is unknown.
+func (ix *Indexer) lottery(lineNo int, id uint64, ev events.Event) *Lottery {
+	l := ix.lotteries[id]
+	if l == nil {
+		ix.issue(lineNo, id, ev.Type()+" event for unknown lottery")
+	}
+	return l
+}
+
+// activeLottery is lottery but also records an issue for events after execution.
+func (ix *Indexer) activeLottery(lineNo int, id uint64, ev events.Event) *Lottery {
+	l := ix.lottery(lineNo, id, ev)
+	if l != nil && l.State != StateActive {
+		ix.issue(lineNo, id, ev.Type()+" event after execution")
+		return nil
+	}
+	return l
+}
+
+func (ix *Indexer) issue(lineNo int, lotteryID uint64, msg string) {
+	ix.issues = append(ix.issues, Issue{Line: lineNo, LotteryID: lotteryID, Message: msg})
+}
+
+// Lottery returns a lottery by ID, or nil if it is unknown.
+// The returned value is live state, read it only while no lines are being applied.
+func (ix *Indexer) Lottery(id uint64) *Lottery {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.lotteries[id]
+}
+
+// Issues returns all recorded issues in line order.
+func (ix *Indexer) Issues() []Issue {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return append([]Issue{}, ix.issues...)
+}
+
+// Snapshot returns the full state with lotteries sorted by ID.
+// Like Lottery it references live state, the HTTP handler serializes it under the read lock instead.
+func (ix *Indexer) Snapshot() *Snapshot {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.snapshot()
+}
+
+func (ix *Indexer) snapshot() *Snapshot {
+	lotteries := make([]*Lottery, 0, len(ix.lotteries))
+	for _, l := range ix.lotteries {
+		lotteries = append(lotteries, l)
+	}
+	sort.Slice(lotteries, func(i, j int) bool { return lotteries[i].ID < lotteries[j].ID })
+	paused := make([]string, 0, len(ix.paused))
+	for action := range ix.paused {
+		paused = append(paused, action)
+	}
+	sort.Strings(paused)
+	treasury := make(map[string]events.Amount, len(ix.treasury))
+	for asset, balance := range ix.treasury {
+		treasury[asset] = balance
+	}
+	charities := make([]*Charity, 0, len(ix.charities))
+	for _, c := range ix.charities {
+		charities = append(charities, c)
+	}
+	sort.Slice(charities, func(i, j int) bool { return charities[i].ID < charities[j].ID })
+
+	return &Snapshot{
+		Lines:     ix.lines,
+		Events:    ix.applied,
+		Skipped:   ix.skipped,
+		Lotteries: lotteries,
+		Issues:    append([]Issue{}, ix.issues...),
+		Paused:    paused,
+		Treasury:  treasury,
+		Charities: charities,
+	}
+}
+
+// isLotteryEventType reports whether the line starts with a known event prefix.
+func isLotteryEventType(line string) bool {
+	prefix, _, _ := strings.Cut(line, "|")
+	switch prefix {
+	case events.TypeCreated, events.TypeMetadataChanged, events.TypeJoined, events.TypeExecuted,
+		events.TypePayout, events.TypeDonation, events.TypeUndistributed, events.TypeArchived, events.TypePauseChanged, events.TypeConfigChanged,
+		events.TypeTreasuryWithdrawn, events.TypeCharityRegistered, events.TypeCharityRemoved, events.TypeDonationAccepted:
+		return true
+	}
+	return false
+}
+
+func formatRange(start, end uint64) string {
+	return strconv.FormatUint(start, 10) + "-" + strconv.FormatUint(end, 10)
+}
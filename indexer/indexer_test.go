@@ -0,0 +1,357 @@
+package indexer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lotteryLog is one lottery as the contract emits it: created, two buyers (bob twice),
+// then execution with a 10% burn, a 20% donation and two winners at 60/40
+func lotteryLog() []events.Event {
+	return []events.Event{
+		&events.Created{ID: 1, Creator: "hive:alice", Name: "Weekly Draw", CreatedAt: 1000, Deadline: 2000, Burn: 1000, Ticket: 1000, Asset: "HIVE", Winners: 2, Shares: []events.Percent{6000, 4000}, Donations: []events.DonationShare{{Account: "hive:ocean", Percent: 2000}}},
+		&events.MetadataChanged{ID: 1, Metadata: "ipfs://a|b"},
+		&events.Joined{ID: 1, Participant: "hive:bob", Tickets: 3, Paid: 3000, Asset: "HIVE", TicketStart: 0, TicketEnd: 2},
+		&events.Joined{ID: 1, Participant: "hive:carol", Tickets: 5, Paid: 5000, Asset: "HIVE", TicketStart: 3, TicketEnd: 7},
+		&events.Joined{ID: 1, Participant: "hive:bob", Tickets: 2, Paid: 2000, Asset: "HIVE", TicketStart: 8, TicketEnd: 9},
+		&events.Donation{ID: 1, Recipient: "hive:ocean", Amount: 2000, Percent: 2000, Asset: "HIVE"},
+		&events.Payout{ID: 1, Winner: "hive:carol", Amount: 4200, Share: 6000, Asset: "HIVE", Position: 1},
+		&events.Payout{ID: 1, Winner: "hive:bob", Amount: 2800, Share: 4000, Asset: "HIVE", Position: 2},
+		&events.Executed{ID: 1, Pool: 10000, Burned: 1000, Donated: 2000, Asset: "HIVE", Winners: 2, Seed: 42, Tickets: 10, Participants: 2, ExecutedAt: 2500},
+	}
+}
+
+// render formats events the way the contract logs them while legacy events are enabled
+func render(evs []events.Event, legacy bool) string {
+	var b strings.Builder
+	for _, ev := range evs {
+		if legacy {
+			b.WriteString(events.FormatLegacy(ev) + "\n")
+		}
+		b.WriteString(events.Format(ev) + "\n")
+	}
+	return b.String()
+}
+
+func consume(t *testing.T, log string) *Indexer {
+	t.Helper()
+	ix := New()
+	require.NoError(t, ix.Consume(strings.NewReader(log)))
+	return ix
+}
+
+// TestReconstructLottery tests the full projection of a lottery from its v2 events
+func TestReconstructLottery(t *testing.T) {
+	ix := consume(t, render(lotteryLog(), false))
+	assert.Empty(t, ix.Issues())
+
+	l := ix.Lottery(1)
+	require.NotNil(t, l)
+	assert.Equal(t, StateExecuted, l.State)
+	assert.Equal(t, "ipfs://a|b", l.Metadata)
+	assert.Equal(t, events.Amount(10000), l.Pool)
+	assert.Equal(t, uint64(10), l.Tickets)
+
+	require.Len(t, l.Participants, 2)
+	bob := l.Participants[0]
+	assert.Equal(t, "hive:bob", bob.Address)
+	assert.Equal(t, uint64(5), bob.Tickets)
+	assert.Equal(t, events.Amount(5000), bob.Paid)
+	assert.Equal(t, []TicketRange{{0, 2}, {8, 9}}, bob.Ranges)
+	assert.Equal(t, "hive:carol", l.Participants[1].Address)
+
+	require.Len(t, l.Payouts, 2)
+	assert.Equal(t, "hive:carol", l.Payouts[0].Winner)
+	require.NotNil(t, l.Execution)
+	assert.Equal(t, events.Amount(7000), l.Execution.Paid)
+	assert.Equal(t, events.Amount(1000), l.Execution.Burned)
+}
+
+// TestLegacyCopiesAreDeduplicated tests that the legacy line emitted before each v2 line is not applied twice
+func TestLegacyCopiesAreDeduplicated(t *testing.T) {
+	withLegacy := consume(t, render(lotteryLog(), true))
+	v2Only := consume(t, render(lotteryLog(), false))
+
+	assert.Empty(t, withLegacy.Issues())
+	assert.Equal(t, v2Only.Lottery(1), withLegacy.Lottery(1))
+	assert.Equal(t, len(lotteryLog()), withLegacy.Snapshot().Events)
+}
+
+// TestLegacyOnlyLog tests logs written before v2 existed
+func TestLegacyOnlyLog(t *testing.T) {
+	var b strings.Builder
+	for _, ev := range lotteryLog() {
+		b.WriteString(events.FormatLegacy(ev) + "\n")
+	}
+	ix := consume(t, b.String())
+
+	assert.Empty(t, ix.Issues())
+	l := ix.Lottery(1)
+	require.NotNil(t, l)
+	assert.Equal(t, StateExecuted, l.State)
+	assert.Equal(t, uint64(10), l.Tickets)
+}
+
+// TestSkipsForeignLines tests that unrelated log output is counted but not reported
+func TestSkipsForeignLines(t *testing.T) {
+	ix := consume(t, "some other contract log\n"+render(lotteryLog(), false)+"\n")
+
+	snap := ix.Snapshot()
+	assert.Empty(t, snap.Issues)
+	assert.Equal(t, 2, snap.Skipped)
+}
+
+// TestAccountingViolations tests that tampered events are flagged instead of accepted
+func TestAccountingViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(evs []events.Event) []events.Event
+		issue  string
+	}{
+		{"payout too high", func(evs []events.Event) []events.Event {
+			evs[6].(*events.Payout).Amount = 5200
+			return evs
+		}, "accounting mismatch"},
+		{"pool differs from joins", func(evs []events.Event) []events.Event {
+			evs[8].(*events.Executed).Pool = 11000
+			return evs
+		}, "differs from joined total"},
+		{"donation event missing", func(evs []events.Event) []events.Event {
+			return append(evs[:5], evs[6:]...)
+		}, "differs from donation events"},
+		{"ticket range gap", func(evs []events.Event) []events.Event {
+			j := evs[3].(*events.Joined)
+			j.TicketStart, j.TicketEnd = 4, 8
+			return evs
+		}, "does not continue at ticket 3"},
+		{"paid below ticket price", func(evs []events.Event) []events.Event {
+			evs[2].(*events.Joined).Paid = 2000
+			return evs
+		}, "paid 2.000 for 3 ticket(s) at 1.000"},
+		{"winner without tickets", func(evs []events.Event) []events.Event {
+			evs[7].(*events.Payout).Winner = "hive:mallory"
+			return evs
+		}, "who holds no tickets"},
+		{"donation to an unannounced account", func(evs []events.Event) []events.Event {
+			evs[5].(*events.Donation).Recipient = "hive:mallory"
+			return evs
+		}, "donation to hive:mallory which the lottery did not announce"},
+		{"join after execution", func(evs []events.Event) []events.Event {
+			return append(evs, &events.Joined{ID: 1, Participant: "hive:dave", Tickets: 1, Paid: 1000, Asset: "HIVE", TicketStart: 10, TicketEnd: 10})
+		}, "lj event after execution"},
+		{"unknown lottery", func(evs []events.Event) []events.Event {
+			return append(evs, &events.Payout{ID: 7, Winner: "hive:bob", Amount: 1, Share: 10000, Asset: "HIVE", Position: 1})
+		}, "lp event for unknown lottery"},
+		{"created twice", func(evs []events.Event) []events.Event {
+			return append(evs, evs[0])
+		}, "lottery created twice"},
+		{"archived before execution", func(evs []events.Event) []events.Event {
+			return append(evs[:5], &events.Archived{ID: 1, Participants: 2, Hash: "00"})
+		}, "la event before execution"},
+		{"archive misses participants", func(evs []events.Event) []events.Event {
+			return append(evs, &events.Archived{ID: 1, Participants: 1, Hash: "00"})
+		}, "archived participant count 1 differs from 2 joined"},
+		{"tickets sold before acceptance", func(evs []events.Event) []events.Event {
+			evs[0].(*events.Created).OptIn = true
+			return evs
+		}, "tickets sold before hive:ocean accepted the donation"},
+		{"unregistered charity ID", func(evs []events.Event) []events.Event {
+			evs[0].(*events.Created).OptIn = true
+			evs[0].(*events.Created).Donations[0].CharityID = 3
+			return evs
+		}, "hive:ocean announced as unregistered charity 3"},
+		{"acceptance nobody asked for", func(evs []events.Event) []events.Event {
+			return slices.Insert(evs, 2, events.Event(&events.DonationAccepted{ID: 1, Account: "hive:ocean"}))
+		}, "donation acceptance from hive:ocean which the lottery was not waiting for"},
+		{"removal of an unknown charity", func(evs []events.Event) []events.Event {
+			return append(evs, &events.CharityRemoved{ID: 1, Address: "hive:ocean"})
+		}, "removal of unknown charity 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := consume(t, render(tt.tamper(lotteryLog()), false))
+			issues := ix.Issues()
+			require.NotEmpty(t, issues)
+
+			found := false
+			for _, issue := range issues {
+				if strings.Contains(issue.Message, tt.issue) {
+					found = true
+				}
+			}
+			assert.True(t, found, "expected issue containing %q, got %v", tt.issue, issues)
+		})
+	}
+}
+
+// TestUnparsableEvent tests that a malformed lottery event is reported with its line number
+func TestUnparsableEvent(t *testing.T) {
+	ix := consume(t, render(lotteryLog()[:1], false)+"lj|v:2|id:1|participant:hive:bob|tickets:x\n")
+
+	issues := ix.Issues()
+	require.Len(t, issues, 1)
+	assert.Equal(t, 2, issues[0].Line)
+	assert.Contains(t, issues[0].Message, "unparsable event")
+}
+
+// TestPausedActions tests that the snapshot follows the owner's pause toggles
+func TestPausedActions(t *testing.T) {
+	log := render([]events.Event{
+		&events.PauseChanged{Actions: []string{"join_lottery", "create_lottery"}, Paused: true, At: 1000},
+		&events.PauseChanged{Actions: []string{"execute_lottery"}, Paused: true, At: 1100},
+		&events.PauseChanged{Actions: []string{"join_lottery"}, Paused: false, At: 1200},
+	}, true)
+	ix := consume(t, log)
+
+	snap := ix.Snapshot()
+	assert.Empty(t, snap.Issues)
+	assert.Equal(t, []string{"create_lottery", "execute_lottery"}, snap.Paused)
+	assert.Equal(t, 3, snap.Events)
+}
+
+// TestProtocolFeeTreasury tests that fees are part of the accounting and the treasury follows fees and withdrawals
+func TestProtocolFeeTreasury(t *testing.T) {
+	log := lotteryLog()
+	log[0].(*events.Created).ProtocolFee = 500
+	log[6].(*events.Payout).Amount = 3900 // 60% of 6.500 after burn, donation and fee
+	log[7].(*events.Payout).Amount = 2600
+	log[8].(*events.Executed).ProtocolFee = 500
+	log = append(log,
+		&events.TreasuryWithdrawn{Asset: "HIVE", Amount: 200, Recipient: "hive:ops", Balance: 300},
+		&events.TreasuryWithdrawn{Asset: "HIVE", Amount: 100, Recipient: "hive:ops", Balance: 250},
+	)
+	ix := consume(t, render(log, true))
+
+	l := ix.Lottery(1)
+	assert.Equal(t, events.Percent(500), l.ProtocolFee)
+	assert.Equal(t, events.Amount(500), l.Execution.ProtocolFee)
+	issues := ix.Issues()
+	require.Len(t, issues, 1)
+	assert.Equal(t, "treasury withdrawal leaves 0.250 HIVE but collected fees leave 0.200", issues[0].Message)
+	assert.Equal(t, map[string]events.Amount{"HIVE": 250}, ix.Snapshot().Treasury)
+
+	// A fee the lottery did not announce is reported, and the pool must still add up
+	log = lotteryLog()
+	log[8].(*events.Executed).ProtocolFee = 1
+	ix = consume(t, render(log, false))
+	messages := make([]string, 0, 2)
+	for _, issue := range ix.Issues() {
+		messages = append(messages, issue.Message)
+	}
+	assert.Equal(t, []string{
+		"accounting mismatch: burned 1.000 + donated 2.000 + paid 7.000 + protocol fee 0.001 + creator fee 0.000 != pool 10.000",
+		"protocol fee 0.001 taken from a lottery created without one",
+	}, messages)
+}
+
+// TestCreatorFee tests that the creator's share is part of the accounting and must be announced
+func TestCreatorFee(t *testing.T) {
+	log := lotteryLog()
+	log[0].(*events.Created).CreatorFee = 500
+	log[6].(*events.Payout).Amount = 3900 // 60% of 6.500 after burn, donation and creator fee
+	log[7].(*events.Payout).Amount = 2600
+	log[8].(*events.Executed).CreatorFee = 500
+	ix := consume(t, render(log, true))
+
+	l := ix.Lottery(1)
+	assert.Equal(t, events.Percent(500), l.CreatorFee)
+	assert.Equal(t, events.Amount(500), l.Execution.CreatorFee)
+	assert.Empty(t, ix.Issues())
+	assert.Zero(t, ix.Snapshot().Treasury["HIVE"])
+
+	log = lotteryLog()
+	log[7].(*events.Payout).Amount = 2799
+	log[8].(*events.Executed).CreatorFee = 1
+	ix = consume(t, render(log, false))
+	messages := make([]string, 0, 1)
+	for _, issue := range ix.Issues() {
+		messages = append(messages, issue.Message)
+	}
+	assert.Equal(t, []string{"creator fee 0.001 taken from a lottery created without one"}, messages)
+}
+
+// TestDonationRecipients tests a lottery that splits its donation between several accounts
+func TestDonationRecipients(t *testing.T) {
+	log := lotteryLog()
+	log[0].(*events.Created).Donations = []events.DonationShare{{Account: "hive:ocean", Percent: 1500}, {Account: "hive:forest", Percent: 500}}
+	log[5].(*events.Donation).Amount, log[5].(*events.Donation).Percent = 1500, 1500
+	log = slices.Insert(log, 6, events.Event(&events.Donation{ID: 1, Recipient: "hive:forest", Amount: 500, Percent: 500, Asset: "HIVE"}))
+	ix := consume(t, render(log, true))
+
+	assert.Empty(t, ix.Issues())
+	l := ix.Lottery(1)
+	assert.Equal(t, []events.DonationShare{{Account: "hive:ocean", Percent: 1500}, {Account: "hive:forest", Percent: 500}}, l.DonationRecipients)
+	assert.Equal(t, []Donation{{Recipient: "hive:ocean", Amount: 1500, Percent: 1500}, {Recipient: "hive:forest", Amount: 500, Percent: 500}}, l.Donations)
+}
+
+// TestCharityRegistry tests the registry, verified charity lotteries and donation acceptance
+func TestCharityRegistry(t *testing.T) {
+	log := lotteryLog()
+	created := log[0].(*events.Created)
+	created.OptIn = true
+	created.Donations = []events.DonationShare{{Account: "hive:ocean", Percent: 1500, CharityID: 1}, {Account: "hive:forest", Percent: 500}}
+	log[5].(*events.Donation).Amount, log[5].(*events.Donation).Percent = 1500, 1500
+	log = slices.Insert(log, 6, events.Event(&events.Donation{ID: 1, Recipient: "hive:forest", Amount: 500, Percent: 500, Asset: "HIVE"}))
+	log = slices.Insert(log, 1, events.Event(&events.DonationAccepted{ID: 1, Account: "hive:forest"}))
+	log = slices.Insert(log, 0, events.Event(&events.CharityRegistered{ID: 1, Address: "hive:ocean", Name: "Ocean", URL: "https://ocean.org", At: 900}))
+	log = append(log, &events.CharityRegistered{ID: 2, Address: "hive:forest", Name: "Forest", URL: "https://forest.org", At: 3000})
+	ix := consume(t, render(log, true))
+
+	assert.Empty(t, ix.Issues())
+	l := ix.Lottery(1)
+	assert.False(t, l.VerifiedCharity)
+	assert.Empty(t, l.PendingDonations)
+	assert.Equal(t, []*Charity{{ID: 1, Address: "hive:ocean", Name: "Ocean", URL: "https://ocean.org"}, {ID: 2, Address: "hive:forest", Name: "Forest", URL: "https://forest.org"}}, ix.Snapshot().Charities)
+
+	ix.Apply(events.Format(&events.Created{ID: 2, Creator: "hive:alice", Name: "Verified", Burn: 1000, Ticket: 1000, Asset: "HIVE", Winners: 1, Shares: []events.Percent{10000},
+		Donations: []events.DonationShare{{Account: "hive:ocean", Percent: 1000, CharityID: 1}, {Account: "hive:forest", Percent: 1000, CharityID: 2}}, OptIn: true, Verified: true}))
+	ix.Apply(events.Format(&events.CharityRemoved{ID: 1, Address: "hive:ocean", At: 3100}))
+	ix.Flush()
+	assert.Empty(t, ix.Issues())
+	assert.True(t, ix.Lottery(2).VerifiedCharity)
+	assert.Len(t, ix.Snapshot().Charities, 1)
+}
+
+// TestHTTPHandler tests the read API endpoints and their JSON encoding
+func TestHTTPHandler(t *testing.T) {
+	ix := consume(t, render(lotteryLog(), false))
+	srv := httptest.NewServer(ix.Handler())
+	defer srv.Close()
+
+	get := func(path string) (*http.Response, map[string]any) {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	resp, body := get("/lotteries/1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Weekly Draw", body["name"])
+	assert.Equal(t, "10.000", body["pool"])
+	assert.Equal(t, "10.00", body["burn"])
+	assert.Equal(t, "42", body["execution"].(map[string]any)["seed"])
+
+	resp, _ = get("/lotteries/2")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get("/lotteries/abc")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body = get("/snapshot")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, body["lotteries"], 1)
+	assert.Empty(t, body["issues"])
+}
@@ -0,0 +1,27 @@
+//go:build !tinygo
+
+package sdk
+
+// Outside the wasm host there are no SDK imports. The types of this package build natively
+// so contract logic can run under plain `go test` against a fake host; aborts become panics.
+
+// Aborted is the panic value of Abort and Revert in native builds, tests recover it to tell
+// contract aborts apart from genuine panics
+type Aborted struct {
+	Msg    string
+	Symbol string
+}
+
+func (a Aborted) Error() string {
+	return a.Msg
+}
+
+// Aborts the contract execution
+func Abort(msg string) {
+	panic(Aborted{Msg: msg})
+}
+
+// Reverts the transaction and abort execution in the same way as Abort().
+func Revert(msg string, symbol string) {
+	panic(Aborted{Msg: msg, Symbol: symbol})
+}
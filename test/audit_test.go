@@ -0,0 +1,187 @@
+package contract_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/indexer"
+
+	"vsc-node/lib/test_utils"
+	"vsc-node/modules/db/vsc/contracts"
+	ledgerDb "vsc-node/modules/db/vsc/ledger"
+	stateEngine "vsc-node/modules/state-processing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// contractAccount is the ledger account the harness keeps the contract's funds under
+const contractAccount = "contract:" + ContractID
+
+// ledgerAudit runs contract calls against the harness and, after every call, compares the ledger
+// balances the harness keeps with the events the contract logged, which it also feeds to the indexer:
+//
+//   - the indexer reports no issues for the events logged so far
+//   - the contract account holds exactly the pools of its active lotteries and the treasury
+//   - every tracked account moved by exactly what the call's events say: joins pay for the tickets,
+//     prizes, the creator fee, non-hive donations and treasury withdrawals are transferred on the
+//     network, while the burn, hive: donations and prizes of winners preferring Hive L1 leave it
+//
+// Only tracked accounts can be checked, as the balance before the call has to be read up front.
+// The accounts funded by SetupContractTest are tracked from the start, creators and donation
+// accounts from the lc event that names them.
+type ledgerAudit struct {
+	ct       *test_utils.ContractTest
+	ix       *indexer.Indexer
+	accounts map[string]bool
+	assets   map[string]bool
+}
+
+// newLedgerAudit audits the calls made on ct, accounts are tracked on top of the setup accounts
+func newLedgerAudit(ct *test_utils.ContractTest, accounts ...string) *ledgerAudit {
+	a := &ledgerAudit{
+		ct:       ct,
+		ix:       indexer.New(),
+		accounts: make(map[string]bool),
+		assets:   map[string]bool{"hive": true},
+	}
+	for _, account := range []string{"hive:alice", "hive:bob", "hive:charlie", "hive:dave", "hive:eve", "hive:creator"} {
+		a.accounts[account] = true
+	}
+	for _, account := range accounts {
+		a.accounts[account] = true
+	}
+	return a
+}
+
+// call is CallContract with the audit
+func (a *ledgerAudit) call(t *testing.T, action string, payload string, intents []contracts.Intent, authUser string, expectedResult bool) (stateEngine.TxResult, uint, map[string][]string) {
+	t.Helper()
+	return a.callAt(t, action, payload, intents, authUser, expectedResult, "")
+}
+
+// callAt is CallContractAt with the audit
+func (a *ledgerAudit) callAt(t *testing.T, action string, payload string, intents []contracts.Intent, authUser string, expectedResult bool, timestamp string) (stateEngine.TxResult, uint, map[string][]string) {
+	t.Helper()
+	a.accounts[authUser] = true
+	before := a.balances()
+
+	result, gasUsed, logs := callContractWithTimestamp(t, a.ct, action, PayloadString(payload), intents, authUser, expectedResult, uint(700_000_000), timestamp)
+
+	var lines []events.Event
+	for _, line := range strings.Split(strings.TrimSuffix(logLines(logs), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		a.ix.Apply(line)
+		if ev, err := events.Parse(line); err == nil && !events.IsLegacy(line) {
+			lines = append(lines, ev)
+		}
+	}
+	a.ix.Flush()
+	assert.Empty(t, a.ix.Issues(), "indexer issues after %s", action)
+
+	expected := a.expectedMoves(t, lines)
+	after := a.balances()
+	for key, balance := range after {
+		if _, tracked := before[key]; !tracked || key.account == contractAccount {
+			continue
+		}
+		assert.Equal(t, expected[key], balance-before[key], "%s moved %s on the ledger after %s", key.account, key.asset, action)
+	}
+	a.checkContractBalance(t, action, after)
+	return result, gasUsed, logs
+}
+
+// ledgerKey identifies a balance on the harness ledger
+type ledgerKey struct {
+	account string
+	asset   string
+}
+
+// balances reads the harness ledger balance of every tracked account and the contract
+func (a *ledgerAudit) balances() map[ledgerKey]int64 {
+	balances := make(map[ledgerKey]int64)
+	for asset := range a.assets {
+		for account := range a.accounts {
+			balances[ledgerKey{account, asset}] = a.ct.GetBalance(account, ledgerDb.Asset(asset))
+		}
+		balances[ledgerKey{contractAccount, asset}] = a.ct.GetBalance(contractAccount, ledgerDb.Asset(asset))
+	}
+	return balances
+}
+
+// expectedMoves returns how much each account should have received (or paid, if negative) on the
+// network for the events of one call. Accounts named for the first time are tracked from now on.
+func (a *ledgerAudit) expectedMoves(t *testing.T, lines []events.Event) map[ledgerKey]int64 {
+	t.Helper()
+	expected := make(map[ledgerKey]int64)
+	credit := func(account string, amount events.Amount, asset string) {
+		a.requireTracked(t, account)
+		expected[ledgerKey{account, asset}] += int64(amount)
+	}
+
+	for _, ev := range lines {
+		switch e := ev.(type) {
+		case *events.Created:
+			a.assets[e.Asset] = true
+			a.accounts[e.Creator] = true
+			for _, d := range e.Donations {
+				a.accounts[d.Account] = true
+			}
+		case *events.Joined:
+			a.requireTracked(t, e.Participant)
+			expected[ledgerKey{e.Participant, e.Asset}] -= int64(e.Paid)
+		case *events.Payout:
+			if !a.prefersWithdraw(e.ID, e.Winner) {
+				credit(e.Winner, e.Amount, e.Asset)
+			}
+		case *events.Donation:
+			if !strings.HasPrefix(e.Recipient, "hive:") {
+				credit(e.Recipient, e.Amount, e.Asset)
+			}
+		case *events.Executed:
+			if l := a.ix.Lottery(e.ID); l != nil {
+				credit(l.Creator, e.CreatorFee, e.Asset)
+			}
+		case *events.TreasuryWithdrawn:
+			credit(e.Recipient, e.Amount, e.Asset)
+		}
+	}
+	return expected
+}
+
+// requireTracked fails the test if an account moved funds before its balance was read
+func (a *ledgerAudit) requireTracked(t *testing.T, account string) {
+	t.Helper()
+	if !a.accounts[account] {
+		t.Fatalf("%s is not tracked by the ledger audit, pass it to newLedgerAudit", account)
+	}
+}
+
+// prefersWithdraw reads a winner's payout preference from state, the lottery's first
+func (a *ledgerAudit) prefersWithdraw(lotteryID uint64, winner string) bool {
+	if !strings.HasPrefix(winner, "hive:") {
+		return false
+	}
+	if p := a.ct.StateGet(ContractID, "ppl:"+strconv.FormatUint(lotteryID, 10)+":"+winner); p != "" {
+		return p == "withdraw"
+	}
+	return a.ct.StateGet(ContractID, "pp:"+winner) == "withdraw"
+}
+
+// checkContractBalance compares the contract's ledger balance with what the events say it holds
+func (a *ledgerAudit) checkContractBalance(t *testing.T, action string, balances map[ledgerKey]int64) {
+	t.Helper()
+	snapshot := a.ix.Snapshot()
+	for asset := range a.assets {
+		held := int64(snapshot.Treasury[asset])
+		for _, l := range snapshot.Lotteries {
+			if l.State == indexer.StateActive && l.Asset == asset {
+				held += int64(l.Pool)
+			}
+		}
+		assert.Equal(t, held, balances[ledgerKey{contractAccount, asset}], "contract holds %s after %s", asset, action)
+	}
+}
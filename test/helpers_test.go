@@ -4,8 +4,11 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 
 	"vsc-node/lib/test_utils"
@@ -156,3 +159,49 @@ func transferIntent(amount string) []contracts.Intent {
 		},
 	}
 }
+
+// eventLines returns the v2 lines of the given event type (e.g. "lp").
+// Legacy lines emitted during the compatibility period are skipped so every event is counted once.
+func eventLines(logs map[string][]string, eventType string) []string {
+	var lines []string
+	for _, logValues := range logs {
+		for _, log := range logValues {
+			if strings.HasPrefix(log, eventType+"|v:2|") {
+				lines = append(lines, log)
+			}
+		}
+	}
+	return lines
+}
+
+// logLines flattens the logs of one call into lines for the indexer. Logs keep their emission
+// order per key; the keys are sorted so the output does not depend on map iteration order.
+func logLines(logs map[string][]string) string {
+	keys := make([]string, 0, len(logs))
+	for key := range logs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		for _, log := range logs[key] {
+			b.WriteString(log + "\n")
+		}
+	}
+	return b.String()
+}
+
+// eventValue returns the unescaped value of a field in a v2 event line, or "" if it is missing
+func eventValue(line string, key string) string {
+	for _, part := range strings.Split(line, "|") {
+		if strings.HasPrefix(part, key+":") {
+			value, err := url.PathUnescape(strings.TrimPrefix(part, key+":"))
+			if err != nil {
+				return ""
+			}
+			return value
+		}
+	}
+	return ""
+}
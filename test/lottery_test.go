@@ -5,6 +5,9 @@ import (
 	"strings"
 	"testing"
 
+	"okinoko_lottery/indexer"
+	"okinoko_lottery/verifier"
+	"vsc-node/modules/db/vsc/contracts"
 	ledgerDb "vsc-node/modules/db/vsc/ledger"
 
 	"github.com/stretchr/testify/assert"
@@ -288,16 +291,11 @@ func TestChangeLotteryMetadataStored(t *testing.T) {
 	assert.True(t, result.Success)
 	assert.Contains(t, result.Ret, "lottery metadata updated")
 
-	hasEvent := false
-	for _, logValues := range logs {
-		for _, log := range logValues {
-			if strings.HasPrefix(log, "lm|") && strings.Contains(log, "id:1") {
-				hasEvent = true
-				assert.Contains(t, log, metadata)
-			}
-		}
-	}
-	assert.True(t, hasEvent, "Expected metadata changed event")
+	// The v2 line escapes the pipe so indexers still see exactly two fields
+	lines := eventLines(logs, "lm")
+	assert.Equal(t, 1, len(lines), "Expected metadata changed event")
+	assert.Contains(t, lines[0], "metadata:meta%7Cdata: ")
+	assert.Equal(t, metadata, eventValue(lines[0], "metadata"))
 
 	stored := ct.StateGet(ContractID, "lmd:1")
 	assert.Equal(t, metadata, stored)
@@ -512,6 +510,27 @@ func TestJoinLotteryNoIntent(t *testing.T) {
 	assert.Contains(t, result.Ret, "transfer.allow intent required")
 }
 
+// TestJoinWithMultipleAllowances tests that the allowance matching the lottery's asset is used
+func TestJoinWithMultipleAllowances(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|168|10|100|5.000"), nil, "hive:creator", true, uint(700_000_000))
+
+	intents := []contracts.Intent{
+		{Type: "transfer.allow", Args: map[string]string{"limit": "100.000", "token": "hbd"}},
+		{Type: "transfer.allow", Args: map[string]string{"limit": "10.000", "token": "hive"}},
+	}
+	result, _, logs := CallContract(t, ct, "join_lottery", PayloadString("1"), intents, "hive:alice", true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "joined lottery with 2 ticket(s)")
+	joined := eventLines(logs, "lj")
+	assert.Len(t, joined, 1)
+	assert.Equal(t, "10.000", eventValue(joined[0], "paid"))
+
+	// Only an HBD allowance for a HIVE lottery
+	result, _, _ = CallContract(t, ct, "join_lottery", PayloadString("1"), intents[:1], "hive:bob", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "no transfer.allow intent for hive")
+}
+
 // TestJoinLotteryNotFound tests joining non-existent lottery
 func TestJoinLotteryNotFound(t *testing.T) {
 	ct := SetupContractTest()
@@ -659,15 +678,7 @@ func TestExecuteLotteryMultipleWinners(t *testing.T) {
 	assert.Contains(t, result.Ret, "lottery executed with 3 winner(s)")
 
 	// Count payout events (should be 3)
-	payoutCount := 0
-	for _, logValues := range logs {
-		for _, log := range logValues {
-			if strings.HasPrefix(log, "lp|") {
-				payoutCount++
-			}
-		}
-	}
-	assert.Equal(t, 3, payoutCount)
+	assert.Equal(t, 3, len(eventLines(logs, "lp")))
 }
 
 // TestExecuteLotteryWithMultipleTickets tests weighted random selection
@@ -903,16 +914,20 @@ func TestLongLotteryName(t *testing.T) {
 	assert.Contains(t, result.Ret, "100 characters or less")
 }
 
-// TestIntegerSharesOnly tests that only integer shares are accepted
-func TestIntegerSharesOnly(t *testing.T) {
+// TestFractionalShares tests that shares with up to two decimals are accepted and more are rejected
+func TestFractionalShares(t *testing.T) {
 	ct := SetupContractTest()
 
-	// Try with decimal shares
 	payload := "Test|168|10|33.33,33.33,33.34|5.000"
-	result, _, _ := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", false, uint(700_000_000))
+	_, _, logs := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", true, uint(700_000_000))
+	created := eventLines(logs, "lc")
+	assert.Len(t, created, 1)
+	assert.Equal(t, "33.33,33.33,33.34", eventValue(created[0], "shares"))
 
+	payload = "Test|168|10|33.333,33.333,33.334|5.000"
+	result, _, _ := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", false, uint(700_000_000))
 	assert.False(t, result.Success)
-	assert.Contains(t, result.Ret, "must be integer")
+	assert.Contains(t, result.Ret, "at most two decimals")
 }
 
 // TestNegativeWinnerShares tests that negative shares are rejected
@@ -923,7 +938,7 @@ func TestNegativeWinnerShares(t *testing.T) {
 	result, _, _ := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", false, uint(700_000_000))
 
 	assert.False(t, result.Success)
-	assert.Contains(t, result.Ret, "winner share must be between 1 and 100")
+	assert.Contains(t, result.Ret, "winner share must be between 0.01 and 100")
 }
 
 // TestMinTicketPrice tests minimum ticket price validation
@@ -973,21 +988,8 @@ func TestVerifyLotteryResults(t *testing.T) {
 
 	// Extract winners from first execution
 	var winners1 []string
-	for _, logValues := range logs1 {
-		for _, log := range logValues {
-			if strings.HasPrefix(log, "lp|") {
-				// Parse winner from payout event
-				// Format: lp|id:X|winner:ADDRESS|amount:Y.ZZZ
-				parts := strings.Split(log, "|")
-				for _, part := range parts {
-					if strings.HasPrefix(part, "winner:") {
-						winner := strings.TrimPrefix(part, "winner:")
-						winners1 = append(winners1, winner)
-						break
-					}
-				}
-			}
-		}
+	for _, line := range eventLines(logs1, "lp") {
+		winners1 = append(winners1, eventValue(line, "winner"))
 	}
 
 	assert.Equal(t, 3, len(winners1), "Should have 3 winners")
@@ -1009,19 +1011,8 @@ func TestVerifyLotteryResults(t *testing.T) {
 
 	// Extract winners from second execution
 	var winners2 []string
-	for _, logValues := range logs2 {
-		for _, log := range logValues {
-			if strings.HasPrefix(log, "lp|") {
-				parts := strings.Split(log, "|")
-				for _, part := range parts {
-					if strings.HasPrefix(part, "winner:") {
-						winner := strings.TrimPrefix(part, "winner:")
-						winners2 = append(winners2, winner)
-						break
-					}
-				}
-			}
-		}
+	for _, line := range eventLines(logs2, "lp") {
+		winners2 = append(winners2, eventValue(line, "winner"))
 	}
 
 	assert.Equal(t, 3, len(winners2), "Should have 3 winners in verification")
@@ -1141,13 +1132,58 @@ func TestVerifyLotteryNotExecuted(t *testing.T) {
 	assert.Contains(t, result.Ret, "lottery not executed yet")
 }
 
+// TestOfflineVerifierMatchesContract pins the offline verifier to the compiled contract:
+// the draw and payout split replayed from the event log must match what the contract paid out
+func TestOfflineVerifierMatchesContract(t *testing.T) {
+	scenarios := []struct {
+		create string
+		joins  []string // "address amount"
+	}{
+		{"Offline A|24|10|50,30,20|1.000", []string{"hive:alice 3.000", "hive:bob 5.000", "hive:charlie 1.000", "hive:alice 2.000", "hive:dave 4.000"}},
+		{"Offline B|24|25|100|2.000|hive:charity|15", []string{"hive:erin 2.000", "hive:frank 8.000", "hive:grace 4.000"}},
+		{"Offline C|24|5|40,30,20,10|0.500", []string{"hive:heidi 1.000", "hive:ivan 0.500"}},
+	}
+
+	for i, sc := range scenarios {
+		ct := SetupContractTest()
+		var log strings.Builder
+
+		_, _, logs := CallContract(t, ct, "create_lottery", PayloadString(sc.create), nil, "hive:creator", true, uint(700_000_000))
+		log.WriteString(logLines(logs))
+		if strings.Contains(sc.create, "hive:charity") {
+			_, _, logs = CallContract(t, ct, "accept_donation", PayloadString("1"), nil, "hive:charity", true, uint(700_000_000))
+			log.WriteString(logLines(logs))
+		}
+		for _, join := range sc.joins {
+			parts := strings.Fields(join)
+			_, _, logs = CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent(parts[1]), parts[0], true, uint(700_000_000))
+			log.WriteString(logLines(logs))
+		}
+		_, _, logs = CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:executor"+strconv.Itoa(i), true, uint(700_000_000), "2025-09-05T00:00:00")
+		log.WriteString(logLines(logs))
+
+		ix := indexer.New()
+		assert.NoError(t, ix.Consume(strings.NewReader(log.String())))
+		assert.Empty(t, ix.Issues(), "scenario %d", i)
+
+		l := ix.Lottery(1)
+		if !assert.NotNil(t, l) || !assert.NotNil(t, l.Execution) {
+			continue
+		}
+		res := verifier.Replay(l, l.Execution.Seed)
+		assert.Empty(t, res.Mismatches, "scenario %d: %v", i, res.Mismatches)
+		assert.Equal(t, len(l.Payouts), len(res.Winners))
+	}
+}
+
 // TestLotteryWithDonation tests lottery with optional donation feature
 func TestLotteryWithDonation(t *testing.T) {
 	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
 
 	// Create lottery with donation: 10% burn, 20% donation to hive:charity
 	// Format: name|hours|burn%|shares|price|donationAccount|donationPercent
-	createResult, _, createLogs := CallContract(t, ct, "create_lottery", PayloadString("Charity Lottery|24|10|100|5.000|hive:charity|20"), nil, "hive:creator", true, uint(700_000_000))
+	createResult, _, createLogs := audit.call(t, "create_lottery", "Charity Lottery|24|10|100|5.000|hive:charity|20", nil, "hive:creator", true)
 	assert.True(t, createResult.Success)
 	assert.Contains(t, createResult.Ret, "lottery created with ID: 1")
 
@@ -1162,12 +1198,13 @@ func TestLotteryWithDonation(t *testing.T) {
 		}
 	}
 	assert.True(t, foundDonationInfo, "Donation info should be in creation event")
+	audit.call(t, "accept_donation", "1", nil, "hive:charity", true)
 
 	// Add participants - 4 participants, each buying 1 ticket (5 HIVE)
 	participants := []string{"hive:alice", "hive:bob", "hive:charlie", "hive:dave"}
 	for _, participant := range participants {
 		ct.Deposit(participant, 10_000_000, ledgerDb.AssetHive) // 10 HIVE each
-		joinResult, _, _ := CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("5.000"), participant, true, uint(700_000_000))
+		joinResult, _, _ := audit.call(t, "join_lottery", "1", transferIntent("5.000"), participant, true)
 		assert.True(t, joinResult.Success)
 	}
 
@@ -1178,7 +1215,7 @@ func TestLotteryWithDonation(t *testing.T) {
 
 	// Execute lottery
 	futureTimestamp := "2025-09-05T00:00:00"
-	execResult, _, execLogs := CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:executor", true, uint(700_000_000), futureTimestamp)
+	execResult, _, execLogs := audit.callAt(t, "execute_lottery", "1", nil, "hive:executor", true, futureTimestamp)
 	assert.True(t, execResult.Success)
 
 	// Verify donation event was emitted
@@ -1198,9 +1235,9 @@ func TestLotteryWithDonation(t *testing.T) {
 	}
 	assert.True(t, foundDonationEvent, "Donation event should be emitted")
 
-	// Note: We cannot check balances of hive:null or hive:charity because sdk.HiveWithdraw
-	// sends funds to Layer 1 (out of the contract), not to Layer 2 accounts.
-	// We can only verify the contract balance decreased correctly.
+	// hive:null and hive:charity get their share withdrawn to Layer 1, so it never shows up on a
+	// Layer 2 balance. The ledger audit checks that the contract released the pool and the winner
+	// was credited the prize instead.
 
 	// Get winner to check their balance increased
 	var winnerAddress string
@@ -1289,6 +1326,182 @@ func TestLotteryDonationValidation(t *testing.T) {
 	assert.True(t, result.Success)
 }
 
+// ============================================================================
+// EVENT ENCODING
+// ============================================================================
+
+// TestEventsLegacyAndV2 tests that each event is emitted once per format during the compatibility period
+func TestEventsLegacyAndV2(t *testing.T) {
+	ct := SetupContractTest()
+
+	_, _, logs := CallContract(t, ct, "create_lottery", PayloadString(`{"name":"Line Test","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","metadata":"line1\nline2|x"}`), nil, "hive:creator", true, uint(700_000_000))
+
+	legacy := 0
+	for _, logValues := range logs {
+		for _, log := range logValues {
+			if strings.HasPrefix(log, "lc|id:") {
+				legacy++
+			}
+		}
+	}
+	assert.Equal(t, 1, legacy, "Expected one legacy creation line")
+
+	created := eventLines(logs, "lc")
+	assert.Equal(t, 1, len(created))
+	assert.True(t, strings.HasPrefix(created[0], "lc|v:2|id:1|creator:hive:creator|name:Line Test|"))
+
+	metadata := eventLines(logs, "lm")
+	assert.Equal(t, 1, len(metadata))
+	assert.Equal(t, "lm|v:2|id:1|metadata:line1%0Aline2%7Cx", metadata[0])
+	assert.Equal(t, "line1\nline2|x", eventValue(metadata[0], "metadata"))
+}
+
+// ============================================================================
+// JSON PAYLOADS
+// ============================================================================
+
+// TestCreateLotteryJSON tests creation with a JSON payload including metadata containing pipes
+func TestCreateLotteryJSON(t *testing.T) {
+	ct := SetupContractTest()
+
+	payload := `{"name":"JSON Draw","deadline_hours":24,"burn_percent":"10","winner_shares":["60","40"],"ticket_price":"5.000",` +
+		`"donation_account":"hive:charity","donation_percent":"20","metadata":"a|b|c","max_tickets":10}`
+	result, _, logs := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", true, uint(700_000_000))
+
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Ret, "lottery created with ID: 1")
+	assert.Equal(t, "a|b|c", ct.StateGet(ContractID, "lmd:1"))
+
+	hasEvent := false
+	for _, logValues := range logs {
+		for _, log := range logValues {
+			if strings.HasPrefix(log, "lc|") {
+				hasEvent = true
+				assert.Contains(t, log, "name:JSON Draw")
+				assert.Contains(t, log, "shares:60.00,40.00")
+				assert.Contains(t, log, "donation_account:hive:charity")
+			}
+		}
+	}
+	assert.True(t, hasEvent, "Expected lottery created event")
+}
+
+// TestCreateLotteryJSONValidation tests that JSON payloads follow the pipe format rules
+func TestCreateLotteryJSONValidation(t *testing.T) {
+	ct := SetupContractTest()
+
+	cases := map[string]string{
+		`{"name":"","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000"}`:                         "name is required",
+		`{"name":"X","deadline_hours":24,"burn_percent":"80","winner_shares":["100"],"ticket_price":"1.000"}`:                        "burn percent must be between 5 and 75",
+		`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["50","40"],"ticket_price":"1.000"}`:                    "winner shares must sum to 100",
+		`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","donation_percent":"5"}`: "donation account cannot be empty if provided",
+		`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","max_tickets":0}`:        "max tickets must be greater than 0",
+		`{"name":"X","deadline_hours":24,"burn_percent":"10","winner_shares":["100"],"ticket_price":"1.000","unknown":"field"}`:      "invalid create_lottery JSON payload",
+	}
+	for payload, expected := range cases {
+		result, _, _ := CallContract(t, ct, "create_lottery", PayloadString(payload), nil, "hive:creator", false, uint(700_000_000))
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Ret, expected)
+	}
+}
+
+// TestLotteryLifecycleJSON tests join, metadata change, execute and verify with JSON payloads
+func TestLotteryLifecycleJSON(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|24|10|100|5.000"), nil, "hive:creator", true, uint(700_000_000))
+
+	result, _, _ := CallContract(t, ct, "join_lottery", PayloadString(`{"lottery_id":1}`), transferIntent("10.000"), "hive:alice", true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "joined lottery with 2 ticket(s)")
+
+	result, _, _ = CallContract(t, ct, "change_lottery_metadata", PayloadString(`{"lottery_id":1,"metadata":"x|y"}`), nil, "hive:creator", true, uint(700_000_000))
+	assert.True(t, result.Success)
+	assert.Equal(t, "x|y", ct.StateGet(ContractID, "lmd:1"))
+
+	futureTimestamp := "2025-09-05T00:00:00"
+	_, _, logs := CallContractAt(t, ct, "execute_lottery", PayloadString(`{"lottery_id":1}`), nil, "hive:alice", true, uint(700_000_000), futureTimestamp)
+
+	var seed string
+	for _, logValues := range logs {
+		for _, log := range logValues {
+			if strings.HasPrefix(log, "le|") {
+				for _, part := range strings.Split(log, "|") {
+					if strings.HasPrefix(part, "seed:") {
+						seed = strings.TrimPrefix(part, "seed:")
+					}
+				}
+			}
+		}
+	}
+	assert.NotEmpty(t, seed)
+
+	result, _, _ = CallContract(t, ct, "verify_lottery", PayloadString(`{"lottery_id":1,"seed":"`+seed+`"}`), nil, "hive:bob", true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "verification successful")
+}
+
+// ============================================================================
+// CREATOR STATS
+// ============================================================================
+
+// TestCreatorStatsAggregates tests creator totals across several lotteries
+func TestCreatorStatsAggregates(t *testing.T) {
+	ct := SetupContractTest()
+
+	// Lottery 1: capped at 4 tickets with 20% donation, lottery 2: uncapped
+	CallContract(t, ct, "create_lottery", PayloadString("Capped|24|10|100|5.000|hive:charity|20|meta|max_tickets=4"), nil, "hive:creator", true, uint(700_000_000))
+	CallContract(t, ct, "create_lottery", PayloadString("Open|168|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+	CallContract(t, ct, "accept_donation", PayloadString("1"), nil, "hive:charity", true, uint(700_000_000))
+
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("10.000"), "hive:alice", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("5.000"), "hive:bob", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("2"), transferIntent("3.000"), "hive:charlie", true, uint(700_000_000))
+
+	futureTimestamp := "2025-09-05T00:00:00"
+	CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:alice", true, uint(700_000_000), futureTimestamp)
+
+	result, _, _ := CallContract(t, ct, "get_creator_stats", PayloadString("hive:creator"), nil, "hive:alice", true, uint(700_000_000))
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Ret, "creator:hive:creator")
+	assert.Contains(t, result.Ret, "lotteries:2")
+	assert.Contains(t, result.Ret, "active:1")
+	assert.Contains(t, result.Ret, "volume:18.000") // 15 HIVE + 3 HIVE
+	assert.Contains(t, result.Ret, "donated:3.000") // 20% of 15 HIVE
+	assert.Contains(t, result.Ret, "capped:1")
+	assert.Contains(t, result.Ret, "avg_fill:75.00") // 3 of 4 tickets
+}
+
+// TestCreatorStatsUnknownCreator tests that unknown creators return zero totals
+func TestCreatorStatsUnknownCreator(t *testing.T) {
+	ct := SetupContractTest()
+
+	result, _, _ := CallContract(t, ct, "get_creator_stats", PayloadString("hive:nobody"), nil, "hive:alice", true, uint(700_000_000))
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Ret, "lotteries:0")
+	assert.Contains(t, result.Ret, "avg_fill:0.00")
+}
+
+// TestCreatorLotteriesPagination tests paging through a creator's lottery IDs
+func TestCreatorLotteriesPagination(t *testing.T) {
+	ct := SetupContractTest()
+
+	for i := 0; i < 3; i++ {
+		CallContract(t, ct, "create_lottery", PayloadString("Draw "+strconv.Itoa(i)+"|24|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+	}
+	CallContract(t, ct, "create_lottery", PayloadString("Other|24|10|100|1.000"), nil, "hive:alice", true, uint(700_000_000))
+
+	result, _, _ := CallContract(t, ct, "get_creator_lotteries", PayloadString("hive:creator"), nil, "hive:alice", true, uint(700_000_000))
+	assert.Equal(t, "total:3|ids:1,2,3", result.Ret)
+
+	result, _, _ = CallContract(t, ct, "get_creator_lotteries", PayloadString("hive:creator|1|1"), nil, "hive:alice", true, uint(700_000_000))
+	assert.Equal(t, "total:3|ids:2", result.Ret)
+
+	result, _, _ = CallContract(t, ct, "get_creator_lotteries", PayloadString("hive:alice"), nil, "hive:alice", true, uint(700_000_000))
+	assert.Equal(t, "total:1|ids:4", result.Ret)
+
+	result, _, _ = CallContract(t, ct, "get_creator_lotteries", PayloadString("hive:creator|0|101"), nil, "hive:alice", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "limit must be between 1 and 100")
+}
+
 // TestLargeScaleLottery tests lottery with 1000 participants
 // uncommented as it takes a lot of time...
 // func TestLargeScaleLottery(t *testing.T) {
@@ -1350,3 +1563,307 @@ func TestLotteryDonationValidation(t *testing.T) {
 // 	}
 // 	assert.True(t, hasExecEvent)
 // }
+
+// ============================================================================
+// STATE MIGRATION
+// ============================================================================
+
+// TestMigrateOwnerOnly tests that only the contract owner may run migrate
+func TestMigrateOwnerOnly(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|24|10|100|5.000"), nil, "hive:creator", true, uint(700_000_000))
+	assert.Equal(t, "1", ct.StateGet(ContractID, "schema"))
+
+	result, _, _ := CallContract(t, ct, "migrate", PayloadString("100"), nil, "hive:creator", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "only the contract owner can do this")
+
+	result, _, _ = CallContract(t, ct, "migrate", PayloadString(`{"batch_size":100}`), nil, ownerAddress, true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "state already at schema 1")
+}
+
+// ============================================================================
+// ARCHIVING
+// ============================================================================
+
+// TestArchiveLottery tests pruning the participants of an executed lottery after the grace period
+func TestArchiveLottery(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|24|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("3.000"), "hive:alice", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("2.000"), "hive:bob", true, uint(700_000_000))
+	CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:alice", true, uint(700_000_000), "2025-09-05T00:00:00")
+
+	result, _, _ := CallContractAt(t, ct, "archive_lottery", PayloadString("1"), nil, "hive:anyone", false, uint(700_000_000), "2025-09-06T00:00:00")
+	assert.Contains(t, result.Ret, "archive grace period has not passed")
+
+	result, _, logs := CallContractAt(t, ct, "archive_lottery", PayloadString("1"), nil, "hive:anyone", true, uint(700_000_000), "2025-10-05T00:00:00")
+	hash := verifier.ParticipantsHash([]verifier.Participant{{Address: "hive:alice", Tickets: 3}, {Address: "hive:bob", Tickets: 2}})
+	assert.Contains(t, result.Ret, "lottery archived with participants hash "+hash)
+	archived := eventLines(logs, "la")
+	assert.Len(t, archived, 1)
+	for _, line := range archived {
+		assert.Equal(t, hash, eventValue(line, "hash"))
+	}
+	assert.Empty(t, ct.StateGet(ContractID, "lpi:1:1"))
+	assert.Empty(t, ct.StateGet(ContractID, "lpu:1:hive:bob"))
+
+	result, _, _ = CallContract(t, ct, "verify_lottery", PayloadString("1|1"), nil, "hive:anyone", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "lottery archived")
+}
+
+// TestGetTicketProof tests that the proof of every participant leads to the root in the le event
+func TestGetTicketProof(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|24|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("3.000"), "hive:alice", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("5.000"), "hive:bob", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("1.000"), "hive:carol", true, uint(700_000_000))
+
+	result, _, _ := CallContract(t, ct, "get_ticket_proof", PayloadString("1|hive:alice"), nil, "hive:anyone", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "lottery not executed yet")
+
+	_, _, logs := CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:alice", true, uint(700_000_000), "2025-09-05T00:00:00")
+	executed := eventLines(logs, "le")
+	assert.Len(t, executed, 1)
+	root := eventValue(executed[0], "tickets_root")
+	assert.Equal(t, "0ef355a9680fb6467c9ebb6f762b546c133a77fd723d33304ac5eefc9a471041", root)
+
+	for _, who := range []string{"hive:alice", "hive:bob", "hive:carol"} {
+		result, _, _ := CallContract(t, ct, "get_ticket_proof", PayloadString("1|"+who), nil, "hive:anyone", true, uint(700_000_000))
+		proof, err := verifier.ParseTicketProof(result.Ret)
+		assert.NoError(t, err)
+		assert.Equal(t, who, proof.Address)
+		assert.True(t, proof.Verify(root), who)
+	}
+
+	result, _, _ = CallContract(t, ct, "get_ticket_proof", PayloadString("1|hive:dave"), nil, "hive:anyone", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "address holds no tickets in this lottery")
+}
+
+// ============================================================================
+// EMERGENCY PAUSE
+// ============================================================================
+
+// TestPauseJoins tests that the owner can stop joins while running lotteries still execute
+func TestPauseJoins(t *testing.T) {
+	ct := SetupContractTest()
+
+	CallContract(t, ct, "create_lottery", PayloadString("Test|24|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("3.000"), "hive:alice", true, uint(700_000_000))
+
+	result, _, _ := CallContract(t, ct, "pause", PayloadString("join_lottery"), nil, "hive:creator", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "only the contract owner can do this")
+
+	result, _, logs := CallContract(t, ct, "pause", PayloadString("join_lottery"), nil, ownerAddress, true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "paused: join_lottery")
+	paused := eventLines(logs, "cp")
+	assert.Len(t, paused, 1)
+	for _, line := range paused {
+		assert.Equal(t, "join_lottery", eventValue(line, "actions"))
+		assert.Equal(t, "true", eventValue(line, "paused"))
+	}
+
+	result, _, _ = CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("1.000"), "hive:bob", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "join_lottery is paused")
+	CallContractAt(t, ct, "execute_lottery", PayloadString("1"), nil, "hive:alice", true, uint(700_000_000), "2025-09-05T00:00:00")
+
+	result, _, logs = CallContract(t, ct, "unpause", PayloadString(`{"actions":["all"]}`), nil, ownerAddress, true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "paused: none")
+	assert.Len(t, eventLines(logs, "cp"), 1)
+	assert.Empty(t, ct.StateGet(ContractID, "paused"))
+}
+
+// ============================================================================
+// PROTOCOL LIMITS
+// ============================================================================
+
+// TestSetConfig tests that the owner can tighten the limits for new lotteries
+func TestSetConfig(t *testing.T) {
+	ct := SetupContractTest()
+
+	result, _, _ := CallContract(t, ct, "set_config", PayloadString("max_deadline_hours=720"), nil, "hive:creator", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "only the contract owner can do this")
+
+	result, _, logs := CallContract(t, ct, "set_config", PayloadString(`{"max_deadline_hours":720}`), nil, ownerAddress, true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "max_deadline_hours:720")
+	changed := eventLines(logs, "cc")
+	assert.Len(t, changed, 1)
+	for _, line := range changed {
+		assert.Equal(t, "720", eventValue(line, "max_deadline_hours"))
+		assert.Equal(t, "75.00", eventValue(line, "max_burn_percent"))
+	}
+
+	result, _, _ = CallContract(t, ct, "get_config", PayloadString(""), nil, "hive:anyone", true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "max_deadline_hours:720")
+
+	result, _, _ = CallContract(t, ct, "create_lottery", PayloadString("Test|1000|10|100|1.000"), nil, "hive:creator", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "deadline must be 720 hours or less")
+	CallContract(t, ct, "create_lottery", PayloadString("Test|720|10|100|1.000"), nil, "hive:creator", true, uint(700_000_000))
+}
+
+// ============================================================================
+// PROTOCOL FEE
+// ============================================================================
+
+// TestProtocolFee tests that the fee is kept at execution and only the owner can withdraw it
+func TestProtocolFee(t *testing.T) {
+	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
+
+	audit.call(t, "set_config", "protocol_fee_percent=2.50", nil, ownerAddress, true)
+	_, _, logs := audit.call(t, "create_lottery", "Test|24|10|100|1.000", nil, "hive:creator", true)
+	for _, line := range eventLines(logs, "lc") {
+		assert.Equal(t, "2.50", eventValue(line, "protocol_fee"))
+	}
+	audit.call(t, "join_lottery", "1", transferIntent("10.000"), "hive:alice", true)
+
+	_, _, logs = audit.callAt(t, "execute_lottery", "1", nil, "hive:alice", true, "2025-09-05T00:00:00")
+	for _, line := range eventLines(logs, "le") {
+		assert.Equal(t, "0.250", eventValue(line, "protocol_fee"))
+	}
+	for _, line := range eventLines(logs, "lp") {
+		assert.Equal(t, "8.750", eventValue(line, "amount"))
+	}
+	assert.Equal(t, "250", ct.StateGet(ContractID, "treasury:hive"))
+
+	result, _, _ := audit.call(t, "withdraw_treasury", "hive|0.250", nil, "hive:alice", false)
+	assert.Contains(t, result.Ret, "only the contract owner can do this")
+
+	result, _, logs = audit.call(t, "withdraw_treasury", "hive|0.250|hive:bob", nil, ownerAddress, true)
+	assert.Contains(t, result.Ret, "treasury balance: 0.000")
+	assert.Len(t, eventLines(logs, "tw"), 1)
+	assert.Empty(t, ct.StateGet(ContractID, "treasury:hive"))
+}
+
+// TestCreatorFee tests that the creator fee is announced in lc and paid to the creator at execution
+func TestCreatorFee(t *testing.T) {
+	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
+
+	result, _, _ := audit.call(t, "create_lottery", "Test|24|10|100|1.000|creator_fee=10.01", nil, "hive:creator", false)
+	assert.Contains(t, result.Ret, "creator fee must be between 0 and 10")
+
+	_, _, logs := audit.call(t, "create_lottery", "Test|24|10|100|1.000|creator_fee=5|max_tickets=100", nil, "hive:creator", true)
+	for _, line := range eventLines(logs, "lc") {
+		assert.Equal(t, "5.00", eventValue(line, "creator_fee"))
+	}
+	audit.call(t, "join_lottery", "1", transferIntent("10.000"), "hive:alice", true)
+
+	_, _, logs = audit.callAt(t, "execute_lottery", "1", nil, "hive:alice", true, "2025-09-05T00:00:00")
+	for _, line := range eventLines(logs, "le") {
+		assert.Equal(t, "0.500", eventValue(line, "creator_fee"))
+	}
+	for _, line := range eventLines(logs, "lp") {
+		assert.Equal(t, "8.500", eventValue(line, "amount"))
+	}
+}
+
+func TestDonationRecipients(t *testing.T) {
+	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
+
+	result, _, _ := audit.call(t, "create_lottery", "Test|24|10|100|1.000|donations=hive:charity=5,charity=5", nil, "hive:creator", false)
+	assert.Contains(t, result.Ret, "invalid donation account: charity")
+
+	_, _, logs := audit.call(t, "create_lottery", "Test|24|10|100|1.000|donations=hive:charity=15,hive:shelter=5", nil, "hive:creator", true)
+	for _, line := range eventLines(logs, "lc") {
+		assert.Equal(t, "hive:charity,hive:shelter", eventValue(line, "donation_account"))
+		assert.Equal(t, "15.00,5.00", eventValue(line, "donation_percent"))
+	}
+	audit.call(t, "accept_donation", "1", nil, "hive:charity", true)
+	audit.call(t, "accept_donation", "1", nil, "hive:shelter", true)
+	audit.call(t, "join_lottery", "1", transferIntent("10.000"), "hive:alice", true)
+
+	_, _, logs = audit.callAt(t, "execute_lottery", "1", nil, "hive:alice", true, "2025-09-05T00:00:00")
+	var donated []string
+	for _, line := range eventLines(logs, "ld") {
+		donated = append(donated, eventValue(line, "recipient")+"="+eventValue(line, "amount"))
+	}
+	assert.ElementsMatch(t, []string{"hive:charity=1.500", "hive:shelter=0.500"}, donated)
+	for _, line := range eventLines(logs, "le") {
+		assert.Equal(t, "2.000", eventValue(line, "donated"))
+	}
+}
+
+// ============================================================================
+// CHARITY REGISTRY
+// ============================================================================
+
+// TestCharityRegistry tests that registered charities sell tickets right away and other accounts opt in first
+func TestCharityRegistry(t *testing.T) {
+	ct := SetupContractTest()
+
+	result, _, _ := CallContract(t, ct, "register_charity", PayloadString("hive:oceanDAO|Ocean DAO|https://oceandao.org"), nil, "hive:alice", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "only the contract owner can do this")
+	result, _, logs := CallContract(t, ct, "register_charity", PayloadString("hive:oceanDAO|Ocean DAO|https://oceandao.org"), nil, ownerAddress, true, uint(700_000_000))
+	assert.Contains(t, result.Ret, "charity registered with ID: 1")
+	assert.Len(t, eventLines(logs, "cr"), 1)
+
+	result, _, _ = CallContract(t, ct, "get_charity", PayloadString("hive:oceanDAO"), nil, "hive:alice", true, uint(700_000_000))
+	assert.Equal(t, "id:1|address:hive:oceanDAO|name:Ocean DAO|url:https://oceandao.org", result.Ret)
+
+	_, _, logs = CallContract(t, ct, "create_lottery", PayloadString("Ocean|24|10|100|1.000|hive:oceanDAO|10"), nil, "hive:creator", true, uint(700_000_000))
+	for _, line := range eventLines(logs, "lc") {
+		assert.Equal(t, "1", eventValue(line, "charity_id"))
+	}
+	CallContract(t, ct, "join_lottery", PayloadString("1"), transferIntent("1.000"), "hive:alice", true, uint(700_000_000))
+
+	_, _, logs = CallContract(t, ct, "create_lottery", PayloadString("Friend|24|10|100|1.000|hive:friend|10"), nil, "hive:creator", true, uint(700_000_000))
+	for _, line := range eventLines(logs, "lc") {
+		assert.Equal(t, "0", eventValue(line, "charity_id"))
+	}
+	result, _, _ = CallContract(t, ct, "join_lottery", PayloadString("2"), transferIntent("1.000"), "hive:alice", false, uint(700_000_000))
+	assert.Contains(t, result.Ret, "donation account hive:friend has not accepted the donation yet")
+
+	result, _, logs = CallContract(t, ct, "accept_donation", PayloadString("2"), nil, "hive:friend", true, uint(700_000_000))
+	assert.Equal(t, "donation accepted", result.Ret)
+	assert.Len(t, eventLines(logs, "lo"), 1)
+	CallContract(t, ct, "join_lottery", PayloadString("2"), transferIntent("1.000"), "hive:alice", true, uint(700_000_000))
+}
+
+// ============================================================================
+// PAYOUT ROUTING
+// ============================================================================
+
+// TestDonationToEVMAddress tests that a did:pkh donation is transferred on the network instead of withdrawn to L1
+func TestDonationToEVMAddress(t *testing.T) {
+	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
+
+	audit.call(t, "create_lottery", "EVM|24|10|100|1.000|did:pkh:eip155:1:0xabc|10", nil, "hive:creator", true)
+	audit.call(t, "accept_donation", "1", nil, "did:pkh:eip155:1:0xabc", true)
+	audit.call(t, "join_lottery", "1", transferIntent("10.000"), "hive:alice", true)
+
+	_, _, logs := audit.callAt(t, "execute_lottery", "1", nil, "hive:alice", true, "2025-09-05T00:00:00")
+	for _, line := range eventLines(logs, "ld") {
+		assert.Equal(t, "did:pkh:eip155:1:0xabc", eventValue(line, "recipient"))
+		assert.Equal(t, "1.000", eventValue(line, "amount"))
+	}
+}
+
+// TestPayoutPreference tests that a winner preferring Hive L1 gets the prize withdrawn
+func TestPayoutPreference(t *testing.T) {
+	ct := SetupContractTest()
+	audit := newLedgerAudit(ct)
+
+	audit.call(t, "create_lottery", "Test|24|10|100|1.000", nil, "hive:creator", true)
+	audit.call(t, "set_payout_preference", "1|withdraw", nil, "hive:alice", false)
+	audit.call(t, "join_lottery", "1", transferIntent("10.000"), "hive:alice", true)
+	result, _, _ := audit.call(t, "set_payout_preference", "1|withdraw", nil, "hive:alice", true)
+	assert.Equal(t, "payout preference set to withdraw for lottery 1", result.Ret)
+	assert.Equal(t, "withdraw", ct.StateGet(ContractID, "ppl:1:hive:alice"))
+
+	result, _, _ = audit.call(t, "get_payout_preference", "hive:alice|1", nil, "hive:bob", true)
+	assert.Equal(t, "preference:withdraw|source:lottery", result.Ret)
+
+	_, _, logs := audit.callAt(t, "execute_lottery", "1", nil, "hive:alice", true, "2025-09-05T00:00:00")
+	for _, line := range eventLines(logs, "lp") {
+		assert.Equal(t, "hive:alice", eventValue(line, "winner"))
+		assert.Equal(t, "9.000", eventValue(line, "amount"))
+	}
+	// The prize left the network, alice is down the ticket
+	assert.Equal(t, int64(190_000), ct.GetBalance("hive:alice", ledgerDb.AssetHive))
+}
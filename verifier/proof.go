@@ -0,0 +1,187 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Ticket ownership proofs. The contract commits to every participant's ticket range with a
+// Merkle tree and emits the root as tickets_root in the le event:
+//
+//	leaf = SHA-256(0x00 || index || start || end || address)
+//	node = SHA-256(0x01 || left || right)
+//
+// index is the 1-based participant index in first-join order, start and end the inclusive
+// ticket range in the draw's pool (the Winner.Ticket numbering), all three 8-byte big endian.
+// A node without a right sibling moves up a level unchanged.
+
+// TicketProof is an inclusion proof as returned by the contract's get_ticket_proof query.
+type TicketProof struct {
+	Index        uint64
+	Address      string
+	Start        uint64
+	End          uint64
+	Participants uint64
+	Root         string   // the root the contract claims, compare it with the le event
+	Siblings     []string // hex sibling hashes from the leaf up
+}
+
+// TicketsRoot computes the tickets root of a participant list in hex, "" for an empty list.
+func TicketsRoot(participants []Participant) string {
+	level := ticketLeaves(participants)
+	if len(level) == 0 {
+		return ""
+	}
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// ProveTicket builds the proof for the participant at a 1-based index, nil if it is out of range.
+func ProveTicket(participants []Participant, index uint64) *TicketProof {
+	if index == 0 || index > uint64(len(participants)) {
+		return nil
+	}
+	proof := &TicketProof{
+		Index:        index,
+		Address:      participants[index-1].Address,
+		Participants: uint64(len(participants)),
+		Root:         TicketsRoot(participants),
+	}
+	for _, p := range participants[:index-1] {
+		proof.Start += p.Tickets
+	}
+	proof.End = proof.Start + participants[index-1].Tickets - 1
+
+	level, pos := ticketLeaves(participants), int(index-1)
+	for len(level) > 1 {
+		if sibling := pos ^ 1; sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(level[sibling]))
+		}
+		level = merkleLevel(level)
+		pos /= 2
+	}
+	return proof
+}
+
+// Verify reports whether the proof leads from its leaf to root (hex, e.g. from the le event).
+// The participant count fixes the shape of the tree, so every sibling must be used exactly once.
+func (p *TicketProof) Verify(root string) bool {
+	if p.Index == 0 || p.Index > p.Participants || p.End < p.Start {
+		return false
+	}
+	want, err := hex.DecodeString(root)
+	if err != nil {
+		return false
+	}
+
+	node := ticketLeafHash(p.Index, p.Start, p.End, p.Address)
+	pos, width, used := p.Index-1, p.Participants, 0
+	for width > 1 {
+		sibling := pos ^ 1
+		if sibling < width {
+			if used == len(p.Siblings) {
+				return false
+			}
+			s, err := hex.DecodeString(p.Siblings[used])
+			if err != nil || len(s) != sha256.Size {
+				return false
+			}
+			used++
+			if pos%2 == 0 {
+				node = nodeHash(node, s)
+			} else {
+				node = nodeHash(s, node)
+			}
+		}
+		pos /= 2
+		width = (width + 1) / 2
+	}
+	return used == len(p.Siblings) && bytes.Equal(node, want)
+}
+
+// ParseTicketProof parses a get_ticket_proof result:
+// index:<i>|address:<address>|tickets:<start>-<end>|participants:<n>|root:<hex>|proof:<hex>,<hex>,...
+func ParseTicketProof(s string) (*TicketProof, error) {
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimSpace(s), "|") {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, errors.New("proof: malformed field " + strconv.Quote(part))
+		}
+		fields[key] = value
+	}
+
+	p := &TicketProof{Address: fields["address"], Root: fields["root"]}
+	var errs []error
+	parse := func(key, value string) uint64 {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			errs = append(errs, errors.New("proof: invalid "+key+" "+strconv.Quote(value)))
+		}
+		return v
+	}
+	p.Index = parse("index", fields["index"])
+	p.Participants = parse("participants", fields["participants"])
+	start, end, ok := strings.Cut(fields["tickets"], "-")
+	if !ok {
+		errs = append(errs, errors.New("proof: invalid tickets "+strconv.Quote(fields["tickets"])))
+	}
+	p.Start, p.End = parse("tickets", start), parse("tickets", end)
+	if p.Address == "" {
+		errs = append(errs, errors.New("proof: missing address"))
+	}
+	if siblings := fields["proof"]; siblings != "" {
+		p.Siblings = strings.Split(siblings, ",")
+	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return p, nil
+}
+
+// ticketLeafHash hashes one participant's ticket range
+func ticketLeafHash(index, start, end uint64, address string) []byte {
+	buf := []byte{0x00}
+	buf = binary.BigEndian.AppendUint64(buf, index)
+	buf = binary.BigEndian.AppendUint64(buf, start)
+	buf = binary.BigEndian.AppendUint64(buf, end)
+	buf = append(buf, address...)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+// ticketLeaves builds the leaves of a participant list in first-join order
+func ticketLeaves(participants []Participant) [][]byte {
+	leaves := make([][]byte, len(participants))
+	next := uint64(0)
+	for i, p := range participants {
+		leaves[i] = ticketLeafHash(uint64(i+1), next, next+p.Tickets-1, p.Address)
+		next += p.Tickets
+	}
+	return leaves
+}
+
+// merkleLevel hashes one level of the tree into the next
+func merkleLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, nodeHash(level[i], level[i+1]))
+	}
+	return next
+}
+
+func nodeHash(left, right []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+	return sum[:]
+}
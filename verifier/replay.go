@@ -0,0 +1,90 @@
+package verifier
+
+import (
+	"strconv"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/indexer"
+)
+
+// Result is a replayed draw and, for executed lotteries, how it compares to the on-chain outcome.
+type Result struct {
+	LotteryID uint64
+	Seed      uint64
+	Tickets   uint64
+	Winners   []Winner
+	Split     Split
+	// Mismatches lists differences to the lp, ld and le events, empty when the draw checks out.
+	Mismatches []string
+}
+
+// Participants converts an indexed lottery into the draw's participant list.
+func Participants(l *indexer.Lottery) []Participant {
+	list := make([]Participant, len(l.Participants))
+	for i, p := range l.Participants {
+		list[i] = Participant{Address: p.Address, Tickets: p.Tickets}
+	}
+	return list
+}
+
+// donationRates returns the rates of the lottery's donation recipients in lc order
+func donationRates(l *indexer.Lottery) []events.Percent {
+	rates := make([]events.Percent, len(l.DonationRecipients))
+	for i, r := range l.DonationRecipients {
+		rates[i] = r.Percent
+	}
+	return rates
+}
+
+// Replay redraws an indexed lottery with the given seed and compares the outcome with the
+// lottery's payout, donation and execution events (including the tickets root) if it was
+// executed, and the participant list with the hash of its la event if it was archived.
+func Replay(l *indexer.Lottery, seed uint64) *Result {
+	participants := Participants(l)
+	winners := SelectWinners(participants, len(l.Shares), seed)
+
+	res := &Result{
+		LotteryID: l.ID,
+		Seed:      seed,
+		Tickets:   l.Tickets,
+		Winners:   winners,
+		Split:     ComputeSplit(l.Pool, l.Burn, donationRates(l), l.ProtocolFee, l.CreatorFee, l.Shares, len(winners)),
+	}
+	if l.Execution == nil {
+		return res
+	}
+
+	if len(l.Payouts) != len(winners) {
+		res.mismatch("drew " + strconv.Itoa(len(winners)) + " winner(s), chain paid " + strconv.Itoa(len(l.Payouts)))
+	}
+	for i := 0; i < len(winners) && i < len(l.Payouts); i++ {
+		pos := strconv.Itoa(i + 1)
+		if l.Payouts[i].Winner != winners[i].Address {
+			res.mismatch("position " + pos + ": drew " + winners[i].Address + ", chain paid " + l.Payouts[i].Winner)
+		}
+		if l.Payouts[i].Amount != res.Split.Payouts[i] {
+			res.mismatch("position " + pos + ": computed " + res.Split.Payouts[i].String() + ", chain paid " + l.Payouts[i].Amount.String())
+		}
+	}
+	res.compare("burned", res.Split.Burned, l.Execution.Burned)
+	res.compare("donated", res.Split.Donated, l.Execution.Donated)
+	res.compare("protocol fee", res.Split.Fee, l.Execution.ProtocolFee)
+	res.compare("creator fee", res.Split.Creator, l.Execution.CreatorFee)
+	if root := l.Execution.TicketsRoot; root != "" && TicketsRoot(participants) != root {
+		res.mismatch("tickets root: computed " + TicketsRoot(participants) + ", chain reported " + root)
+	}
+	if l.ArchiveHash != "" && ParticipantsHash(participants) != l.ArchiveHash {
+		res.mismatch("participants hash: computed " + ParticipantsHash(participants) + ", chain archived " + l.ArchiveHash)
+	}
+	return res
+}
+
+func (r *Result) compare(what string, computed, onChain events.Amount) {
+	if computed != onChain {
+		r.mismatch(what + ": computed " + computed.String() + ", chain reported " + onChain.String())
+	}
+}
+
+func (r *Result) mismatch(msg string) {
+	r.Mismatches = append(r.Mismatches, msg)
+}
@@ -0,0 +1,178 @@
+// Package verifier replays a lottery draw from public data without calling the contract.
+//
+// It is an independent re-implementation of the contract's hashRandom PRNG, its
+// selectRandomWinners draw and its payout split. Given the participant list in first-join
+// order (as stored on-chain and visible from the lj events) and the seed from the le event,
+// it reproduces the winners, the ticket that won each position and the amounts paid.
+// The integration tests pin this package to the compiled contract.
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+
+	"okinoko_lottery/events"
+)
+
+// Participant is one entry of the draw's participant list.
+type Participant struct {
+	Address string
+	Tickets uint64
+}
+
+// Winner is a drawn winner.
+type Winner struct {
+	// Position is the 1-based prize position.
+	Position int
+	Address  string
+	// Ticket is the winning ticket's index in the draw pool: participants in first-join
+	// order, each with their tickets contiguous. For a participant who bought more than once
+	// this differs from the ticket numbers in their lj events.
+	Ticket uint64
+}
+
+// ParticipantsHash recomputes the commitment archive_lottery stores before it prunes a lottery's
+// participants: a SHA-256 chain h = SHA-256(h || address || " " || tickets || "\n") over the
+// participants in first-join order, starting from an empty h. It returns lower-case hex like the la event.
+func ParticipantsHash(participants []Participant) string {
+	var h []byte
+	for _, p := range participants {
+		d := sha256.New()
+		d.Write(h)
+		d.Write([]byte(p.Address + " " + strconv.FormatUint(p.Tickets, 10) + "\n"))
+		h = d.Sum(nil)
+	}
+	return hex.EncodeToString(h)
+}
+
+// Rand is the contract's SHA-256 counter PRNG: output n is the first 8 bytes (little endian)
+// of SHA-256(seed as uint64 LE || n as uint64 LE).
+type Rand struct {
+	seed    uint64
+	counter uint64
+}
+
+// NewRand returns the PRNG the contract uses for a given seed.
+func NewRand(seed uint64) *Rand {
+	return &Rand{seed: seed}
+}
+
+// Uint64 returns the next raw output.
+func (r *Rand) Uint64() uint64 {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], r.seed)
+	binary.LittleEndian.PutUint64(buf[8:], r.counter)
+	r.counter++
+	sum := sha256.Sum256(buf[:])
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
+// Intn returns a uniform number in [0, n) by rejection sampling, 0 if n <= 0.
+func (r *Rand) Intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	un := uint64(n)
+	limit := ^uint64(0) - (^uint64(0) % un)
+	for {
+		if v := r.Uint64(); v < limit {
+			return int(v % un)
+		}
+	}
+}
+
+// SelectWinners replays the contract's draw: it lays out one pool slot per ticket, shuffles
+// the pool with Fisher-Yates driven by Rand and walks it from the front, taking the first
+// winnerCount distinct addresses. Fewer winners are returned when there are fewer participants.
+func SelectWinners(participants []Participant, winnerCount int, seed uint64) []Winner {
+	total := uint64(0)
+	for _, p := range participants {
+		total += p.Tickets
+	}
+	if winnerCount == 0 || total == 0 {
+		return []Winner{}
+	}
+
+	// Shuffle ticket indices instead of addresses, it is the same permutation and keeps the winning ticket
+	owners := make([]int, 0, total)
+	for i, p := range participants {
+		for t := uint64(0); t < p.Tickets; t++ {
+			owners = append(owners, i)
+		}
+	}
+	pool := make([]uint64, total)
+	for i := range pool {
+		pool[i] = uint64(i)
+	}
+
+	rng := NewRand(seed)
+	for i := len(pool) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+
+	winners := make([]Winner, 0, winnerCount)
+	seen := make(map[string]bool)
+	for _, ticket := range pool {
+		addr := participants[owners[ticket]].Address
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		winners = append(winners, Winner{Position: len(winners) + 1, Address: addr, Ticket: ticket})
+		if len(winners) == winnerCount {
+			break
+		}
+	}
+	return winners
+}
+
+// Split is the distribution of a lottery pool at execution.
+type Split struct {
+	Pool    events.Amount
+	Burned  events.Amount // including the undistributed remainder
+	Donated events.Amount
+	Fee     events.Amount // protocol fee kept in the contract's treasury
+	Creator events.Amount // creator fee paid to the lottery's creator
+	// Payouts holds the amount for each drawn winner, in position order.
+	Payouts       []events.Amount
+	Undistributed events.Amount
+}
+
+// ComputeSplit reproduces the contract's payout arithmetic for a pool. Burn, each donation, protocol
+// fee and creator fee are their basis points of the pool, each payout its basis points of what is left after them,
+// all rounded down to the smallest unit; the rounding remainder and unclaimed shares are burned.
+// Lotteries executed before the contract switched to basis points used float64 arithmetic and
+// may differ by one unit per part.
+func ComputeSplit(pool events.Amount, burn events.Percent, donations []events.Percent, fee, creatorFee events.Percent, shares []events.Percent, winners int) Split {
+	s := Split{Pool: pool, Payouts: make([]events.Amount, 0, winners)}
+
+	s.Burned = applyPercent(pool, burn)
+	for _, donation := range donations {
+		s.Donated += applyPercent(pool, donation)
+	}
+	s.Fee = applyPercent(pool, fee)
+	s.Creator = applyPercent(pool, creatorFee)
+
+	remaining := pool - s.Burned - s.Donated - s.Fee - s.Creator
+	distributed := events.Amount(0)
+	for i := 0; i < winners && i < len(shares); i++ {
+		amount := applyPercent(remaining, shares[i])
+		s.Payouts = append(s.Payouts, amount)
+		distributed += amount
+	}
+
+	if distributed < remaining {
+		s.Undistributed = remaining - distributed
+		s.Burned += s.Undistributed
+	}
+	return s
+}
+
+// applyPercent returns p basis points of v rounded down, without overflowing for large pools
+func applyPercent(v events.Amount, p events.Percent) events.Amount {
+	q, r := v/10000, v%10000
+	return q*events.Amount(p) + r*events.Amount(p)/10000
+}
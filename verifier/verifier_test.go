@@ -0,0 +1,239 @@
+package verifier
+
+import (
+	"strings"
+	"testing"
+
+	"okinoko_lottery/events"
+	"okinoko_lottery/indexer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The golden values below were produced by hashRandom and selectRandomWinners from
+// contract/random.go. The contract is also checked against this package directly by
+// TestDrawMatchesOfflineVerifier (native) and TestOfflineVerifierMatchesContract (wasm).
+
+func sampleParticipants() []Participant {
+	return []Participant{{"hive:alice", 3}, {"hive:bob", 5}, {"hive:carol", 1}, {"hive:dave", 2}}
+}
+
+// TestRandGolden pins the PRNG output to the contract's hashRandom
+func TestRandGolden(t *testing.T) {
+	r := NewRand(42)
+	assert.Equal(t, uint64(18325140140735790510), r.Uint64())
+	assert.Equal(t, uint64(936818002525049801), r.Uint64())
+	assert.Equal(t, uint64(17713641361893983915), r.Uint64())
+
+	r = NewRand(12345678901234567890)
+	assert.Equal(t, 3, r.Intn(10))
+	assert.Equal(t, 562, r.Intn(1000))
+	assert.Equal(t, 6, r.Intn(7))
+	assert.Equal(t, 0, r.Intn(0))
+}
+
+// TestSelectWinnersGolden pins the draw to the contract's selectRandomWinners
+func TestSelectWinnersGolden(t *testing.T) {
+	tests := []struct {
+		seed    uint64
+		count   int
+		winners []string
+	}{
+		{1, 3, []string{"hive:bob", "hive:carol", "hive:alice"}},
+		{42, 3, []string{"hive:alice", "hive:carol", "hive:bob"}},
+		{987654321, 3, []string{"hive:dave", "hive:bob", "hive:alice"}},
+		{7, 5, []string{"hive:bob", "hive:dave", "hive:alice", "hive:carol"}},
+	}
+
+	for _, tt := range tests {
+		winners := SelectWinners(sampleParticipants(), tt.count, tt.seed)
+		addrs := make([]string, len(winners))
+		for i, w := range winners {
+			addrs[i] = w.Address
+			assert.Equal(t, i+1, w.Position)
+		}
+		assert.Equal(t, tt.winners, addrs, "seed %d", tt.seed)
+	}
+}
+
+// TestWinningTicketsBelongToWinners tests that each winning ticket lies in its winner's pool range
+func TestWinningTicketsBelongToWinners(t *testing.T) {
+	participants := sampleParticipants()
+	ranges := map[string][2]uint64{}
+	start := uint64(0)
+	for _, p := range participants {
+		ranges[p.Address] = [2]uint64{start, start + p.Tickets - 1}
+		start += p.Tickets
+	}
+
+	for seed := uint64(0); seed < 200; seed++ {
+		for _, w := range SelectWinners(participants, 4, seed) {
+			r := ranges[w.Address]
+			assert.True(t, w.Ticket >= r[0] && w.Ticket <= r[1], "seed %d: ticket %d outside %v", seed, w.Ticket, r)
+		}
+	}
+}
+
+// TestSelectWinnersEdgeCases tests empty pools and zero winners
+func TestSelectWinnersEdgeCases(t *testing.T) {
+	assert.Empty(t, SelectWinners(nil, 3, 1))
+	assert.Empty(t, SelectWinners(sampleParticipants(), 0, 1))
+	assert.Len(t, SelectWinners([]Participant{{"hive:solo", 4}}, 3, 1), 1)
+}
+
+// TestComputeSplit tests the pool split including unclaimed shares
+func TestComputeSplit(t *testing.T) {
+	// 100 HIVE, 10% burn, 20% donation, 60/40 between winners
+	s := ComputeSplit(100000, 1000, []events.Percent{2000}, 0, 0, []events.Percent{6000, 4000}, 2)
+	assert.Equal(t, events.Amount(10000), s.Burned)
+	assert.Equal(t, events.Amount(20000), s.Donated)
+	assert.Equal(t, []events.Amount{42000, 28000}, s.Payouts)
+	assert.Equal(t, events.Amount(0), s.Undistributed)
+
+	// Only one winner drawn for two shares, the second share is burned
+	s = ComputeSplit(100000, 1000, nil, 0, 0, []events.Percent{6000, 4000}, 1)
+	assert.Equal(t, []events.Amount{54000}, s.Payouts)
+	assert.Equal(t, events.Amount(36000), s.Undistributed)
+	assert.Equal(t, events.Amount(46000), s.Burned)
+
+	// Rounding remainder: 33/33/34 of 10.001 after a 5% burn
+	s = ComputeSplit(10001, 500, nil, 0, 0, []events.Percent{3300, 3300, 3400}, 3)
+	total := s.Burned + s.Donated
+	for _, p := range s.Payouts {
+		total += p
+	}
+	assert.Equal(t, s.Pool, total)
+
+	// Pools beyond float64 precision split exactly: 2^53+1 units, 10% burn, 33.33/66.67
+	s = ComputeSplit(9007199254740993, 1000, nil, 0, 0, []events.Percent{3333, 6667}, 2)
+	assert.Equal(t, events.Amount(900719925474099), s.Burned-s.Undistributed)
+	assert.Equal(t, []events.Amount{2701889560444655, 5404589768822238}, s.Payouts)
+	assert.Equal(t, events.Amount(1), s.Undistributed)
+
+	// A 2.5% protocol fee comes off the pool like burn and donation
+	s = ComputeSplit(100000, 1000, []events.Percent{2000}, 250, 0, []events.Percent{6000, 4000}, 2)
+	assert.Equal(t, events.Amount(2500), s.Fee)
+	assert.Equal(t, []events.Amount{40500, 27000}, s.Payouts)
+	assert.Equal(t, events.Amount(10000), s.Burned)
+
+	// So does a 5% creator fee, next to the protocol fee
+	s = ComputeSplit(100000, 1000, []events.Percent{2000}, 250, 500, []events.Percent{6000, 4000}, 2)
+	assert.Equal(t, events.Amount(5000), s.Creator)
+	assert.Equal(t, []events.Amount{37500, 25000}, s.Payouts)
+	assert.Equal(t, events.Amount(10000), s.Burned)
+
+	// Every donation recipient's share is rounded down on its own: 2 × 0.250 rather than 25% of 1.002
+	s = ComputeSplit(1002, 1000, []events.Percent{2500, 2500}, 0, 0, []events.Percent{10000}, 1)
+	assert.Equal(t, events.Amount(500), s.Donated)
+	assert.Equal(t, []events.Amount{402}, s.Payouts)
+}
+
+// TestReplay tests replaying an indexed lottery against its own on-chain events
+func TestReplay(t *testing.T) {
+	participants := sampleParticipants()
+	seed := uint64(42)
+	winners := SelectWinners(participants, 3, seed)
+	split := ComputeSplit(11000, 1000, nil, 0, 0, []events.Percent{5000, 3000, 2000}, len(winners))
+
+	log := []events.Event{
+		&events.Created{ID: 1, Creator: "hive:owner", Name: "Replay", Burn: 1000, Ticket: 1000, Asset: "HIVE", Winners: 3, Shares: []events.Percent{5000, 3000, 2000}},
+	}
+	next := uint64(0)
+	for _, p := range participants {
+		log = append(log, &events.Joined{ID: 1, Participant: p.Address, Tickets: p.Tickets, Paid: events.Amount(p.Tickets) * 1000, Asset: "HIVE", TicketStart: next, TicketEnd: next + p.Tickets - 1})
+		next += p.Tickets
+	}
+	for i, w := range winners {
+		log = append(log, &events.Payout{ID: 1, Winner: w.Address, Amount: split.Payouts[i], Asset: "HIVE", Position: uint64(i + 1)})
+	}
+	log = append(log, &events.Executed{ID: 1, Pool: 11000, Burned: split.Burned, Asset: "HIVE", Winners: 3, Seed: seed, Tickets: 11, Participants: 4})
+
+	var b strings.Builder
+	for _, ev := range log {
+		b.WriteString(events.Format(ev) + "\n")
+	}
+	ix := indexer.New()
+	require.NoError(t, ix.Consume(strings.NewReader(b.String())))
+	l := ix.Lottery(1)
+	require.NotNil(t, l)
+
+	res := Replay(l, seed)
+	assert.Empty(t, res.Mismatches)
+	assert.Equal(t, winners, res.Winners)
+
+	// A wrong seed draws a different order
+	res = Replay(l, seed+1)
+	assert.NotEmpty(t, res.Mismatches)
+
+	// The archive commitment has to match the indexed participant list
+	l.ArchiveHash = ParticipantsHash(participants)
+	assert.Empty(t, Replay(l, seed).Mismatches)
+	l.ArchiveHash = ParticipantsHash(participants[1:])
+	assert.Equal(t, []string{"participants hash: computed " + ParticipantsHash(participants) + ", chain archived " + l.ArchiveHash}, Replay(l, seed).Mismatches)
+}
+
+// TestParticipantsHash pins the hash chain to a value computed independently
+func TestParticipantsHash(t *testing.T) {
+	assert.Equal(t, "", ParticipantsHash(nil))
+	// sha256("hive:alice 3\n"), then chained with sha256(h || "hive:bob 12\n")
+	assert.Equal(t, "9f35dfc3efa0f7b8fd33b4534de1fa9c61e11bab13e5bf5a1ca3d72c02d3042c", ParticipantsHash([]Participant{{"hive:alice", 3}}))
+	assert.Equal(t, "9cbf4b3d7dee6e2ca7b8b9db41a8c6e575002ea3e0f916598099b94a5a0f8a0f", ParticipantsHash([]Participant{{"hive:alice", 3}, {"hive:bob", 12}}))
+}
+
+// TestTicketsRootGolden pins the Merkle layout to a value computed independently
+func TestTicketsRootGolden(t *testing.T) {
+	participants := []Participant{{"hive:alice", 3}, {"hive:bob", 5}, {"hive:carol", 1}}
+	assert.Equal(t, "0ef355a9680fb6467c9ebb6f762b546c133a77fd723d33304ac5eefc9a471041", TicketsRoot(participants))
+	assert.Equal(t, "", TicketsRoot(nil))
+
+	// Carol's leaf has no sibling on the first level and moves up unchanged
+	proof := ProveTicket(participants, 3)
+	assert.Equal(t, uint64(8), proof.Start)
+	assert.Equal(t, uint64(8), proof.End)
+	assert.Len(t, proof.Siblings, 1)
+}
+
+// TestTicketProofs tests that every participant of trees of different shapes can prove its
+// range and that tampered proofs fail
+func TestTicketProofs(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var participants []Participant
+		for i := range n {
+			participants = append(participants, Participant{"hive:user" + string(rune('a'+i)), uint64(i%4 + 1)})
+		}
+		root := TicketsRoot(participants)
+		for index := uint64(1); index <= uint64(n); index++ {
+			proof := ProveTicket(participants, index)
+			require.True(t, proof.Verify(root), "n=%d index=%d", n, index)
+
+			tampered := *proof
+			tampered.Address = "hive:mallory"
+			assert.False(t, tampered.Verify(root))
+			tampered = *proof
+			tampered.End++
+			assert.False(t, tampered.Verify(root))
+			tampered = *proof
+			tampered.Siblings = append(append([]string{}, proof.Siblings...), root)
+			assert.False(t, tampered.Verify(root))
+		}
+		assert.Nil(t, ProveTicket(participants, uint64(n+1)))
+	}
+}
+
+// TestParseTicketProof tests reading a get_ticket_proof result
+func TestParseTicketProof(t *testing.T) {
+	participants := sampleParticipants()
+	want := ProveTicket(participants, 2)
+	result := "index:2|address:hive:bob|tickets:3-7|participants:4|root:" + want.Root + "|proof:" + strings.Join(want.Siblings, ",")
+
+	proof, err := ParseTicketProof(result)
+	require.NoError(t, err)
+	assert.Equal(t, want, proof)
+	assert.True(t, proof.Verify(TicketsRoot(participants)))
+
+	for _, bad := range []string{"", "index:x|address:a|tickets:0-1|participants:1|root:|proof:", "index:1|address:a|tickets:3|participants:1|root:|proof:", "index:1|tickets:0-1|participants:1|root:|proof:"} {
+		_, err := ParseTicketProof(bad)
+		assert.Error(t, err, bad)
+	}
+}